@@ -1,11 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime/debug"
+	"slices"
 	"strings"
 
 	"github.com/jansmrcka/differ/internal/config"
@@ -15,15 +16,31 @@ import (
 	"github.com/spf13/cobra"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
 var version = "dev"
 
 var (
-	flagStaged bool
-	flagRef    string
-	flagTheme  string
-	flagCommit bool
+	flagStaged  bool
+	flagRef     string
+	flagBase    string
+	flagTheme   string
+	flagCommit  bool
+	flagNoColor bool
+	flagView    string
+	flagReview  bool
+	flagCheck   bool
+
+	flagLogMax    int
+	flagLogSince  string
+	flagLogUntil  string
+	flagLogAuthor string
+
+	flagLogFirstParent bool
+	flagLogMerges      bool
+	flagLogNoMerges    bool
 )
 
 var rootCmd = &cobra.Command{
@@ -39,6 +56,12 @@ var logCmd = &cobra.Command{
 	RunE:  runLog,
 }
 
+var addCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Stage and unstage working tree changes",
+	RunE:  runAdd,
+}
+
 var commitCmd = &cobra.Command{
 	Use:   "commit",
 	Short: "Review staged changes and commit",
@@ -52,15 +75,31 @@ func init() {
 		}
 	}
 	rootCmd.Flags().BoolVarP(&flagStaged, "staged", "s", false, "show only staged changes")
-	rootCmd.Flags().StringVarP(&flagRef, "ref", "r", "", "compare against branch/tag/commit")
+	rootCmd.Flags().StringVarP(&flagRef, "ref", "r", "", "compare against branch/tag/commit (with --staged: index vs ref)")
+	rootCmd.Flags().StringVar(&flagBase, "base", "", "show changes on this branch since it forked from base (base...HEAD)")
 	rootCmd.Flags().BoolVarP(&flagCommit, "commit", "c", false, "enter commit mode after review")
-	rootCmd.Flags().StringVar(&flagTheme, "theme", "", "color theme (dark, light)")
-	rootCmd.AddCommand(logCmd, commitCmd)
+	rootCmd.Flags().StringVar(&flagTheme, "theme", "", "color theme (dark, light, solarized-dark, solarized-light)")
+	rootCmd.Flags().BoolVar(&flagReview, "review", false, "read-only review: disable staging, commit, push/pull and branch switching")
+	rootCmd.Flags().StringVar(&flagView, "view", "", "initial view (files, commit, log)")
+	rootCmd.Flags().BoolVar(&flagCheck, "check", false, "exit 0 if clean, 1 with unstaged or untracked changes, 2 if everything is staged, 3 on error; no UI")
+	logCmd.Flags().IntVarP(&flagLogMax, "max", "n", git.DefaultLogMax, "maximum number of commits")
+	logCmd.Flags().StringVar(&flagLogSince, "since", "", "show commits after date (e.g. \"1 week ago\")")
+	logCmd.Flags().StringVar(&flagLogUntil, "until", "", "show commits before date")
+	logCmd.Flags().StringVar(&flagLogAuthor, "author", "", "show commits by author (regex)")
+	logCmd.Flags().BoolVar(&flagLogFirstParent, "first-parent", false, "follow only the first parent of merge commits")
+	logCmd.Flags().BoolVar(&flagLogMerges, "merges", false, "show only merge commits")
+	logCmd.Flags().BoolVar(&flagLogNoMerges, "no-merges", false, "hide merge commits")
+	logCmd.MarkFlagsMutuallyExclusive("merges", "no-merges")
+	rootCmd.PersistentFlags().BoolVar(&flagNoColor, "no-color", false, "disable colors (also honors NO_COLOR)")
+	rootCmd.AddCommand(logCmd, commitCmd, addCmd)
 }
 
 // Execute runs the root CLI command.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		if flagCheck {
+			os.Exit(checkError)
+		}
 		os.Exit(1)
 	}
 }
@@ -76,46 +115,244 @@ func resolveTheme(cfg config.Config) theme.Theme {
 	return theme.DarkTheme()
 }
 
+// buildStyles creates UI styles, switching to monochrome output when
+// --no-color or NO_COLOR (https://no-color.org) is set.
+func buildStyles(t theme.Theme) ui.Styles {
+	styles := ui.NewStyles(t)
+	if flagNoColor || os.Getenv("NO_COLOR") != "" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+		styles.Monochrome = true
+	}
+	return styles
+}
+
+// pickRecentRepo offers the recently opened repos when differ is started
+// outside a git repo. It returns "" when there are none or the user quits.
+func pickRecentRepo(cfg config.Config) (string, error) {
+	var repos []string
+	for _, dir := range config.LoadRecent() {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			repos = append(repos, dir)
+		}
+	}
+	if len(repos) == 0 {
+		fmt.Println("Not a git repository, and no recently opened repos to pick from.")
+		return "", nil
+	}
+	t := resolveTheme(cfg)
+	picker := ui.NewRepoPickerModel(repos, buildStyles(t), t)
+	finalModel, err := tea.NewProgram(picker, tea.WithAltScreen()).Run()
+	if err != nil {
+		return "", err
+	}
+	m, ok := finalModel.(ui.RepoPickerModel)
+	if !ok {
+		return "", nil
+	}
+	return m.Selected(), nil
+}
+
+// resolveView picks the initial view: --commit, then --view, then config.
+// Unknown config values fall back to "files"; an unknown --view is an error.
+// --staged and --ref ask for a file list, so they beat the configured view.
+func resolveView(cfg config.Config) (string, error) {
+	if flagCommit {
+		return "commit", nil
+	}
+	if flagView != "" {
+		if !slices.Contains(config.Views(), flagView) {
+			return "", fmt.Errorf("unknown view %q (want one of: %s)", flagView, strings.Join(config.Views(), ", "))
+		}
+		return flagView, nil
+	}
+	if flagStaged || flagRef != "" {
+		return "files", nil
+	}
+	if slices.Contains(config.Views(), cfg.DefaultView) {
+		return cfg.DefaultView, nil
+	}
+	return "files", nil
+}
+
+// resolveBase picks the base for a branch comparison: --base, then
+// compare_base from the per-repo config (.git/differ.json) or the global
+// one. --ref, --staged and the commit view skip the configured base, and so
+// does a repo without it (a global "main" in a repo on "master"): differ
+// then opens the working tree as usual.
+func resolveBase(repo *git.Repo, cfg config.Config, view string) (string, error) {
+	if flagBase != "" {
+		if flagRef != "" || flagStaged {
+			return "", fmt.Errorf("--base cannot be combined with --ref or --staged")
+		}
+		return flagBase, nil
+	}
+	if flagRef != "" || flagStaged || view == "commit" {
+		return "", nil
+	}
+	base := config.LoadRepo(cfg, repo.LocalConfigPath()).CompareBase
+	if base == "" {
+		return "", nil
+	}
+	if _, err := repo.ResolveRef(base); err != nil {
+		return "", nil
+	}
+	return base, nil
+}
+
+// resolveRef is --ref, compared from its merge base with HEAD when
+// ref_merge_base is set. A trailing "..." (--ref main...) asks for that
+// explicitly; other ranges pass through as given.
+func resolveRef(cfg config.Config) string {
+	if flagRef == "" || strings.Contains(flagRef, "..") || !cfg.RefMergeBase {
+		return flagRef
+	}
+	return git.MergeBaseRef(flagRef)
+}
+
+// openRepo opens the repo in the current directory using the git binary
+// from $GIT, then config git_path, then plain "git", and the configured
+// diff algorithm.
+func openRepo(cfg config.Config) (*git.Repo, error) {
+	gitBin := os.Getenv("GIT")
+	if gitBin == "" {
+		gitBin = cfg.GitPath
+	}
+	if gitBin == "" {
+		gitBin = "git"
+	}
+	repo, err := git.NewRepoWithGit(".", gitBin)
+	if err != nil {
+		return nil, err
+	}
+	if !flagCheck {
+		_ = config.AddRecent(repo.Dir()) // best effort; only feeds the picker
+	}
+	return repo.WithDiffAlgorithm(cfg.DiffAlgorithm), nil
+}
+
 func runDiff(cmd *cobra.Command, args []string) error {
-	repo, err := git.NewRepo(".")
+	cfg := config.Load()
+	repo, err := openRepo(cfg)
+	if errors.Is(err, git.ErrNotRepository) && !flagCheck {
+		dir, pickErr := pickRecentRepo(cfg)
+		if pickErr != nil || dir == "" {
+			return pickErr
+		}
+		if err := os.Chdir(dir); err != nil {
+			return err
+		}
+		return runDiff(cmd, args)
+	}
 	if err != nil {
 		return err
 	}
+	if flagCheck {
+		code, err := runCheck(repo)
+		if err != nil {
+			return err
+		}
+		os.Exit(code)
+	}
 
-	files, err := repo.ChangedFiles(flagStaged, flagRef)
+	view, err := resolveView(cfg)
+	if err != nil {
+		return err
+	}
+	if flagReview {
+		if flagCommit {
+			return fmt.Errorf("--review cannot be combined with --commit")
+		}
+		view = "files" // the commit and log views can change the repo
+	}
+	if view == "log" {
+		return runLogModel(repo, cfg, git.LogOptions{})
+	}
+
+	base, err := resolveBase(repo, cfg, view)
+	if err != nil {
+		return err
+	}
+	ref := resolveRef(cfg)
+	var files []git.FileChange
+	if base != "" {
+		files, err = repo.ChangedFilesRange(base, "HEAD")
+	} else {
+		files, err = repo.ChangedFiles(flagStaged, ref)
+	}
 	if err != nil {
 		return err
 	}
 
 	var untracked []string
-	if !flagStaged && flagRef == "" {
+	if !flagStaged && flagRef == "" && base == "" {
 		untracked, err = repo.UntrackedFiles()
 		if err != nil {
 			return err
 		}
 	}
 
-	cfg := config.Load()
 	t := resolveTheme(cfg)
-	styles := ui.NewStyles(t)
+	styles := buildStyles(t)
 
-	model := ui.NewModel(repo, cfg, files, untracked, styles, t, flagStaged, flagRef)
-	if flagCommit {
+	model := ui.NewModel(repo, cfg, files, untracked, styles, t, flagStaged, ref)
+	if base != "" {
+		model.SetCompareBase(base)
+	}
+	if flagReview {
+		model.SetReviewOnly()
+	}
+	if view == "commit" {
 		model.StartInCommitMode()
 	}
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	finalModel, err := p.Run()
+	finalModel, err := runModel(model)
 	if err != nil {
 		return err
 	}
 	if m, ok := finalModel.(ui.Model); ok && m.SelectedFile != "" {
-		return openInEditor(cfg.EditorCmd, m.SelectedFile, repo.Dir())
+		return openInEditor(cfg.EditorCmd, repo.AbsPath(m.SelectedFile), repo.Dir())
 	}
 	return nil
 }
 
-func openInEditor(editorCmd, file, repoRoot string) error {
-	absPath := filepath.Join(repoRoot, file)
+// Exit codes of differ --check.
+const (
+	checkClean    = 0
+	checkUnstaged = 1
+	checkStaged   = 2
+	checkError    = 3 // differ couldn't tell, e.g. not a git repository
+)
+
+// runCheck computes the --check exit code for the working tree.
+func runCheck(repo *git.Repo) (int, error) {
+	files, err := repo.ChangedFiles(false, "")
+	if err != nil {
+		return 0, err
+	}
+	untracked, err := repo.UntrackedFiles()
+	if err != nil {
+		return 0, err
+	}
+	return checkCode(files, untracked), nil
+}
+
+// checkCode maps the working tree state to an exit code. Unstaged and
+// untracked changes win over staged ones: the tree is only "ready to
+// commit" (2) when nothing is left unstaged.
+func checkCode(files []git.FileChange, untracked []string) int {
+	if len(untracked) > 0 {
+		return checkUnstaged
+	}
+	code := checkClean
+	for _, f := range files {
+		if !f.Staged {
+			return checkUnstaged
+		}
+		code = checkStaged
+	}
+	return code
+}
+
+func openInEditor(editorCmd, absPath, repoRoot string) error {
 	if editorCmd == "" {
 		editor := os.Getenv("EDITOR")
 		if editor == "" {
@@ -135,7 +372,8 @@ func openInEditor(editorCmd, file, repoRoot string) error {
 }
 
 func runCommit(cmd *cobra.Command, args []string) error {
-	repo, err := git.NewRepo(".")
+	cfg := config.Load()
+	repo, err := openRepo(cfg)
 	if err != nil {
 		return err
 	}
@@ -149,33 +387,133 @@ func runCommit(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	cfg := config.Load()
 	t := resolveTheme(cfg)
-	styles := ui.NewStyles(t)
+	styles := buildStyles(t)
 
 	model := ui.NewModel(repo, cfg, files, nil, styles, t, true, "")
 	model.StartInCommitMode()
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	_, err = p.Run()
+	_, err = runModel(model)
 	return err
 }
 
+func runAdd(cmd *cobra.Command, args []string) error {
+	cfg := config.Load()
+	repo, err := openRepo(cfg)
+	if err != nil {
+		return err
+	}
+
+	files, err := repo.ChangedFiles(false, "")
+	if err != nil {
+		return err
+	}
+	untracked, err := repo.UntrackedFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 && len(untracked) == 0 {
+		fmt.Println("No changes to stage.")
+		return nil
+	}
+
+	t := resolveTheme(cfg)
+	styles := buildStyles(t)
+
+	model := ui.NewModel(repo, cfg, files, untracked, styles, t, false, "")
+	model.SetStagingOnly()
+	finalModel, err := runModel(model)
+	if err != nil {
+		return err
+	}
+	if m, ok := finalModel.(ui.Model); ok && m.SelectedFile != "" {
+		return openInEditor(cfg.EditorCmd, repo.AbsPath(m.SelectedFile), repo.Dir())
+	}
+	return nil
+}
+
 func runLog(cmd *cobra.Command, args []string) error {
-	repo, err := git.NewRepo(".")
+	cfg := config.Load()
+	repo, err := openRepo(cfg)
 	if err != nil {
 		return err
 	}
+	opts := git.LogOptions{
+		Max: flagLogMax, Since: flagLogSince, Until: flagLogUntil, Author: flagLogAuthor,
+		FirstParent: flagLogFirstParent, Merges: flagLogMerges, NoMerges: flagLogNoMerges,
+	}
+	return runLogModel(repo, cfg, opts)
+}
+
+func runLogModel(repo *git.Repo, cfg config.Config, opts git.LogOptions) error {
 	if !repo.HasCommits() {
 		fmt.Println("No commits yet.")
 		return nil
 	}
 
-	cfg := config.Load()
 	t := resolveTheme(cfg)
-	styles := ui.NewStyles(t)
+	styles := buildStyles(t)
 
-	model := ui.NewLogModel(repo, styles, t)
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	_, err = p.Run()
-	return err
+	// o in the log reviews a commit in the file list; quitting that
+	// returns to the log on the same commit.
+	selected := ""
+	var reviewErr error
+	for {
+		model := ui.NewLogModel(repo, styles, t)
+		model.SetLogOptions(opts)
+		model.SelectCommit(selected)
+		if reviewErr != nil {
+			model.ReviewFailed(reviewErr)
+		}
+		finalModel, err := tea.NewProgram(model, tea.WithAltScreen()).Run()
+		if err != nil {
+			return err
+		}
+		lm, ok := finalModel.(ui.LogModel)
+		if !ok || lm.OpenCommit == "" {
+			return nil
+		}
+		selected = lm.OpenCommit
+		reviewErr = runCommitReview(repo, cfg, selected, styles, t)
+	}
+}
+
+// runCommitReview opens one commit's files in the read-only file list, and
+// the file picked with e in the editor after it. runLogModel shows its
+// errors, such as a shallow clone's boundary commit, in the log.
+func runCommitReview(repo *git.Repo, cfg config.Config, hash string, styles ui.Styles, t theme.Theme) error {
+	files, err := repo.CommitDiffFiles(hash)
+	if err != nil {
+		return err
+	}
+	model := ui.NewModel(repo, cfg, files, nil, styles, t, false, "")
+	if err := model.SetCommit(hash); err != nil {
+		return err
+	}
+	finalModel, err := runModel(model)
+	if err != nil {
+		return err
+	}
+	if m, ok := finalModel.(ui.Model); ok && m.SelectedFile != "" {
+		return openInEditor(cfg.EditorCmd, repo.AbsPath(m.SelectedFile), repo.Dir())
+	}
+	return nil
+}
+
+// runModel runs the main view full-screen. The model sets the terminal
+// title, so the previous one is pushed onto xterm's title stack first and
+// popped on exit; terminals without a title stack ignore both sequences.
+// Nothing is written when stdout is redirected.
+func runModel(model ui.Model) (tea.Model, error) {
+	if isTerminal(os.Stdout) {
+		fmt.Fprint(os.Stdout, "\x1b[22;0t")
+		defer fmt.Fprint(os.Stdout, "\x1b[23;0t")
+	}
+	return tea.NewProgram(model, tea.WithAltScreen()).Run()
+}
+
+// isTerminal reports whether f is a character device rather than a pipe
+// or a regular file.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jansmrcka/differ/internal/config"
+	"github.com/jansmrcka/differ/internal/git"
+	"github.com/jansmrcka/differ/internal/theme"
+	"github.com/muesli/termenv"
+)
+
+func TestCheckCode(t *testing.T) {
+	t.Parallel()
+	staged := git.FileChange{Path: "a.go", Staged: true}
+	unstaged := git.FileChange{Path: "b.go"}
+	tests := []struct {
+		name      string
+		files     []git.FileChange
+		untracked []string
+		want      int
+	}{
+		{"clean", nil, nil, checkClean},
+		{"unstaged", []git.FileChange{unstaged}, nil, checkUnstaged},
+		{"untracked", nil, []string{"new.go"}, checkUnstaged},
+		{"staged", []git.FileChange{staged}, nil, checkStaged},
+		{"staged_and_unstaged", []git.FileChange{staged, unstaged}, nil, checkUnstaged},
+		{"staged_and_untracked", []git.FileChange{staged}, []string{"new.go"}, checkUnstaged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := checkCode(tt.files, tt.untracked); got != tt.want {
+				t.Errorf("checkCode = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = []string{
+			"HOME=" + t.TempDir(),
+			"GIT_CONFIG_NOSYSTEM=1",
+			"GIT_CONFIG_GLOBAL=/dev/null",
+			"GIT_AUTHOR_NAME=test",
+			"GIT_AUTHOR_EMAIL=test@test.com",
+			"GIT_COMMITTER_NAME=test",
+			"GIT_COMMITTER_EMAIL=test@test.com",
+			"PATH=" + os.Getenv("PATH"),
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("init", "-q")
+	write("v1\n")
+	run("add", "f.txt")
+	run("commit", "-q", "-m", "init")
+
+	repo, err := git.NewRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name string
+		do   func()
+		want int
+	}{
+		{"clean", func() {}, checkClean},
+		{"modified", func() { write("v2\n") }, checkUnstaged},
+		{"staged", func() { run("add", "f.txt") }, checkStaged},
+	}
+	for _, s := range steps {
+		s.do()
+		code, err := runCheck(repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != s.want {
+			t.Errorf("%s: code = %d, want %d", s.name, code, s.want)
+		}
+	}
+}
+
+// gitIn runs git in dir with an isolated config and fixed identity.
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"HOME=" + t.TempDir(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL=/dev/null",
+		"GIT_AUTHOR_NAME=test",
+		"GIT_AUTHOR_EMAIL=test@test.com",
+		"GIT_COMMITTER_NAME=test",
+		"GIT_COMMITTER_EMAIL=test@test.com",
+		"PATH=" + os.Getenv("PATH"),
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestBuildStyles_NoColor(t *testing.T) {
+	prev := lipgloss.ColorProfile()
+	t.Cleanup(func() { lipgloss.SetColorProfile(prev) })
+	lipgloss.SetColorProfile(termenv.TrueColor)
+
+	t.Setenv("NO_COLOR", "")
+	if styles := buildStyles(theme.DarkTheme()); styles.Monochrome || !strings.Contains(styles.DiffAdded.Render("x"), "\x1b[") {
+		t.Fatal("without NO_COLOR styles should be colored")
+	}
+	t.Setenv("NO_COLOR", "1")
+	styles := buildStyles(theme.DarkTheme())
+	if out := styles.DiffAdded.Render("x"); !styles.Monochrome || strings.Contains(out, "\x1b[") {
+		t.Errorf("NO_COLOR: monochrome=%v output=%q, want no escapes", styles.Monochrome, out)
+	}
+}
+
+func TestIsTerminal_Redirected(t *testing.T) {
+	t.Parallel()
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if isTerminal(f) || isTerminal(w) {
+		t.Error("a file or pipe should not count as a terminal")
+	}
+}
+
+func TestResolveView_FlagsBeatConfig(t *testing.T) {
+	t.Cleanup(func() { flagStaged, flagRef = false, "" })
+	cfg := config.Default()
+	cfg.DefaultView = "log"
+	tests := []struct {
+		name   string
+		staged bool
+		ref    string
+		want   string
+	}{
+		{"config", false, "", "log"},
+		{"staged", true, "", "files"},
+		{"ref", false, "main", "files"},
+	}
+	for _, tt := range tests {
+		flagStaged, flagRef = tt.staged, tt.ref
+		if got, err := resolveView(cfg); err != nil || got != tt.want {
+			t.Errorf("%s: view = %q (err %v), want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestResolveBase_MissingConfiguredBase(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	gitIn(t, dir, "init", "-q", "-b", "main")
+	gitIn(t, dir, "commit", "-q", "--allow-empty", "-m", "init")
+	repo, err := git.NewRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	for branch, want := range map[string]string{"main": "main", "develop": ""} {
+		cfg.CompareBase = branch
+		if got, err := resolveBase(repo, cfg, "files"); err != nil || got != want {
+			t.Errorf("compare_base %q: base = %q (err %v), want %q", branch, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jansmrcka/differ/internal/config"
+	"github.com/jansmrcka/differ/internal/git"
+	"github.com/jansmrcka/differ/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var flagServeStdinJSON bool
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Answer line-delimited JSON requests for editor integrations",
+	Long: `Reads one JSON request per line on stdin and writes one JSON response
+per line on stdout, until stdin closes.
+
+Requests:
+  {"id": 1, "op": "changed_files"}
+  {"id": 2, "op": "changed_files", "staged": true, "ref": "main"}
+  {"id": 3, "op": "diff", "path": "x.go", "staged": true}
+  {"id": 4, "op": "diff", "path": "new.go", "untracked": true}
+
+Responses echo the id and carry either a result or an error:
+  {"id": 1, "result": {"files": [{"path": "x.go", "status": "M", "staged": false, "added": 3, "deleted": 1}], "untracked": ["new.go"]}}
+  {"id": 3, "result": {"binary": false, "truncated": false, "lines": [{"type": "added", "content": "...", "old": -1, "new": 12}]}}
+  {"id": 5, "error": "unknown op \"bogus\""}
+
+Line types are "context", "added", "removed" and "hunk"; -1 marks a line
+number that does not apply.`,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().BoolVar(&flagServeStdinJSON, "stdin-json", false, "read line-delimited JSON requests from stdin (required)")
+	rootCmd.AddCommand(serveCmd)
+}
+
+type serveRequest struct {
+	ID        json.RawMessage `json:"id,omitempty"`
+	Op        string          `json:"op"`
+	Path      string          `json:"path,omitempty"`
+	Staged    bool            `json:"staged,omitempty"`
+	Ref       string          `json:"ref,omitempty"`
+	Untracked bool            `json:"untracked,omitempty"`
+}
+
+type serveResponse struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Result any             `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type serveFiles struct {
+	Files     []serveFile `json:"files"`
+	Untracked []string    `json:"untracked"`
+}
+
+type serveFile struct {
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"`
+	Status  string `json:"status"`
+	Staged  bool   `json:"staged"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+}
+
+type serveDiff struct {
+	Binary    bool        `json:"binary"`
+	Truncated bool        `json:"truncated"`
+	Lines     []serveLine `json:"lines"`
+}
+
+type serveLine struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Old     int    `json:"old"`
+	New     int    `json:"new"`
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if !flagServeStdinJSON {
+		return fmt.Errorf("serve needs --stdin-json (the only transport so far)")
+	}
+	repo, err := openRepo(config.Load())
+	if err != nil {
+		return err
+	}
+	return serve(repo, os.Stdin, os.Stdout)
+}
+
+// serve answers each request line from r with one response line on w.
+// Malformed requests get an error response; only I/O errors stop it.
+func serve(repo *git.Repo, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req serveRequest
+		resp := serveResponse{}
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			resp.Error = "invalid request: " + err.Error()
+		} else {
+			resp = handleServeRequest(repo, req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func handleServeRequest(repo *git.Repo, req serveRequest) serveResponse {
+	var result any
+	var err error
+	switch req.Op {
+	case "changed_files":
+		result, err = serveChangedFiles(repo, req)
+	case "diff":
+		result, err = serveFileDiff(repo, req)
+	default:
+		err = fmt.Errorf("unknown op %q", req.Op)
+	}
+	if err != nil {
+		return serveResponse{ID: req.ID, Error: err.Error()}
+	}
+	return serveResponse{ID: req.ID, Result: result}
+}
+
+func serveChangedFiles(repo *git.Repo, req serveRequest) (*serveFiles, error) {
+	changes, err := repo.ChangedFiles(req.Staged, req.Ref)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]serveFile, 0, len(changes))
+	for _, c := range changes {
+		files = append(files, serveFile{
+			Path:    c.Path,
+			OldPath: c.OldPath,
+			Status:  string(c.Status),
+			Staged:  c.Staged,
+			Added:   c.AddedLines,
+			Deleted: c.DeletedLines,
+		})
+	}
+	untracked := []string{}
+	if !req.Staged && req.Ref == "" {
+		if untracked, err = repo.UntrackedFiles(); err != nil {
+			return nil, err
+		}
+	}
+	if untracked == nil {
+		untracked = []string{} // keep the schema's array, never null
+	}
+	return &serveFiles{Files: files, Untracked: untracked}, nil
+}
+
+func serveFileDiff(repo *git.Repo, req serveRequest) (*serveDiff, error) {
+	if req.Path == "" {
+		return nil, fmt.Errorf("diff needs a path")
+	}
+	var parsed ui.ParsedDiff
+	if req.Untracked {
+		// Only files git lists as untracked are read, so a request can't
+		// reach tracked files or paths outside the repo.
+		untracked, err := repo.UntrackedFiles()
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(untracked, req.Path) {
+			return nil, fmt.Errorf("%s is not an untracked file", req.Path)
+		}
+		content, err := repo.ReadFileContent(req.Path)
+		if err != nil {
+			return nil, err
+		}
+		parsed = ui.ParseNewFile(content)
+	} else {
+		raw, err := repo.DiffFile(req.Path, req.Staged, req.Ref)
+		if err != nil {
+			return nil, err
+		}
+		parsed = ui.ParseDiff(raw)
+	}
+	d := &serveDiff{Binary: parsed.Binary, Truncated: parsed.Truncated, Lines: []serveLine{}}
+	for _, dl := range parsed.Lines {
+		d.Lines = append(d.Lines, serveLine{Type: lineTypeName(dl.Type), Content: dl.Content, Old: dl.OldNum, New: dl.NewNum})
+	}
+	return d, nil
+}
+
+// lineTypeName is the schema name for a parsed diff line type.
+func lineTypeName(t ui.DiffLineType) string {
+	switch t {
+	case ui.LineAdded:
+		return "added"
+	case ui.LineRemoved:
+		return "removed"
+	case ui.LineHunkHeader:
+		return "hunk"
+	default:
+		return "context"
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitIn(t, dir, "init", "-q")
+	write("f.txt", "a\nb\n")
+	gitIn(t, dir, "add", "f.txt")
+	gitIn(t, dir, "commit", "-q", "-m", "init")
+	write("f.txt", "a\nc\n")
+	write("new.txt", "x\n")
+	write("blob.bin", "\x00\x01\x02")
+
+	repo, err := git.NewRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := strings.Join([]string{
+		`{"id": 1, "op": "changed_files"}`,
+		`{"id": 2, "op": "diff", "path": "f.txt"}`,
+		`{"id": 3, "op": "diff", "path": "new.txt", "untracked": true}`,
+		`{"id": 4, "op": "bogus"}`,
+		`not json`,
+		`{"id": 6, "op": "diff", "path": "../outside", "untracked": true}`,
+		`{"id": 7, "op": "diff", "path": "f.txt", "untracked": true}`,
+		`{"id": 8, "op": "diff", "path": "blob.bin", "untracked": true}`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := serve(repo, strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("got %d responses, want 8:\n%s", len(lines), out.String())
+	}
+
+	var files struct {
+		ID     int
+		Result serveFiles
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &files); err != nil {
+		t.Fatal(err)
+	}
+	if files.ID != 1 || len(files.Result.Files) != 1 || files.Result.Files[0].Status != "M" ||
+		strings.Join(files.Result.Untracked, ",") != "blob.bin,new.txt" {
+		t.Errorf("changed_files = %s", lines[0])
+	}
+
+	var diff struct{ Result serveDiff }
+	if err := json.Unmarshal([]byte(lines[1]), &diff); err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, l := range diff.Result.Lines {
+		types = append(types, l.Type)
+	}
+	if got := strings.Join(types, ","); got != "hunk,context,removed,added" {
+		t.Errorf("diff line types = %s", got)
+	}
+	if last := diff.Result.Lines[len(diff.Result.Lines)-1]; last.Content != "c" || last.Old != -1 || last.New != 2 {
+		t.Errorf("added line = %+v, want c at new line 2", last)
+	}
+
+	if !strings.Contains(lines[2], `"type":"added","content":"x","old":-1,"new":1`) {
+		t.Errorf("untracked diff = %s", lines[2])
+	}
+	if !strings.Contains(lines[3], `"id":4`) || !strings.Contains(lines[3], `unknown op`) {
+		t.Errorf("bogus op = %s", lines[3])
+	}
+	if !strings.Contains(lines[4], `"error":"invalid request`) {
+		t.Errorf("malformed request = %s", lines[4])
+	}
+	for _, l := range lines[5:7] {
+		if !strings.Contains(l, `is not an untracked file`) {
+			t.Errorf("untracked outside the list should be refused: %s", l)
+		}
+	}
+	if !strings.Contains(lines[7], `"binary":true`) || !strings.Contains(lines[7], `"lines":[]`) {
+		t.Errorf("binary untracked = %s", lines[7])
+	}
+}
@@ -8,22 +8,86 @@ import (
 
 // Config holds user preferences.
 type Config struct {
-	Theme           string `json:"theme"`
-	TabWidth        int    `json:"tab_width"`
-	CommitMsgCmd    string `json:"commit_msg_cmd"`
-	CommitMsgPrompt string `json:"commit_msg_prompt"`
-	SplitDiff       bool   `json:"split_diff"`
-	EditorCmd       string `json:"editor_cmd"`
+	Theme                string   `json:"theme"`
+	TabWidth             int      `json:"tab_width"`
+	CommitMsgCmd         string   `json:"commit_msg_cmd"`
+	CommitMsgPrompt      string   `json:"commit_msg_prompt"`
+	SplitDiff            bool     `json:"split_diff"`
+	CompactDiff          bool     `json:"compact_diff"`
+	ContextOnly          bool     `json:"context_only"` // hide unchanged context lines, showing only the changes
+	WordDiff             bool     `json:"word_diff"`    // git --word-diff=plain: changes marked inside lines
+	EditorCmd            string   `json:"editor_cmd"`
+	DiffTool             string   `json:"diff_tool"`       // git difftool --tool; empty uses git's diff.tool
+	MergeTool            string   `json:"merge_tool"`      // git mergetool --tool; empty uses git's merge.tool
+	FileListRatio        float64  `json:"file_list_ratio"` // 0 = fixed width
+	RelativeLineNums     bool     `json:"relative_line_nums"`
+	Gutter               string   `json:"gutter"`  // "both", "old", "new" or "none"
+	Borders              bool     `json:"borders"` // false drops the card frames for a flat layout
+	Tail                 bool     `json:"tail"`    // select the most recently modified file on each change
+	CommitMsgCount       int      `json:"commit_msg_count"`
+	CommitMsgTimeoutSec  int      `json:"commit_msg_timeout_sec"` // kill commit_msg_cmd after this long; 0 = no limit
+	CommitSubjectMax     int      `json:"commit_subject_max"`     // subject length counter in the commit bar; 0 hides it
+	DefaultView          string   `json:"default_view"`           // "files", "commit" or "log"
+	HexdumpMaxBytes      int      `json:"hexdump_max_bytes"`
+	ShowFullPath         bool     `json:"show_full_path"`
+	StartOnTextFile      bool     `json:"start_on_text_file"`   // open on the first non-binary file rather than the first listed
+	MaxNameWidth         int      `json:"max_name_width"`       // file list name column cap; 0 = panel width
+	AutoStageOnCommit    bool     `json:"auto_stage_on_commit"` // git add -u when committing with nothing staged
+	FetchOnStart         bool     `json:"fetch_on_start"`       // fetch origin in the background on launch
+	ShowWhitespaceErrors bool     `json:"show_whitespace_errors"`
+	GitPath              string   `json:"git_path"`       // $GIT overrides; empty means "git"
+	MaxDiffLines         int      `json:"max_diff_lines"` // 0 = no limit
+	ExplainCmd           string   `json:"explain_cmd"`    // empty = commit_msg_cmd
+	ExplainPrompt        string   `json:"explain_prompt"`
+	DiffAlgorithm        string   `json:"diff_algorithm"`   // myers, patience, histogram or minimal
+	Icons                string   `json:"icons"`            // "ascii" or "nerdfont"
+	HidePatterns         []string `json:"hide_patterns"`    // globs kept out of the file list, e.g. "*.pb.go"
+	CompareBase          string   `json:"compare_base"`     // usually set per repo; shows base...HEAD
+	RefMergeBase         bool     `json:"ref_merge_base"`   // --ref compares from the merge base with HEAD, not the ref's tip
+	StatusBarItems       []string `json:"status_bar_items"` // branch, staged, files, ahead_behind, last_fetch, split, upstream_url, time
 }
 
 // Default returns the default configuration.
 func Default() Config {
 	return Config{
-		Theme:    "dark",
-		TabWidth: 4,
+		Theme:               "dark",
+		GitPath:             "git",
+		TabWidth:            4,
+		CommitMsgCount:      1,
+		CommitMsgTimeoutSec: 30,
+		CommitSubjectMax:    50,
+		StartOnTextFile:     true,
+		DefaultView:         "files",
+		Borders:             true,
+		Gutter:              "both",
+		Icons:               "ascii",
+		DiffAlgorithm:       "myers",
+		HexdumpMaxBytes:     8192,
+		MaxDiffLines:        10000,
+		StatusBarItems:      []string{"staged", "files", "ahead_behind", "last_fetch", "split"},
 	}
 }
 
+// Views returns the accepted DefaultView values.
+func Views() []string {
+	return []string{"files", "commit", "log"}
+}
+
+// DiffAlgorithms returns the accepted DiffAlgorithm values.
+func DiffAlgorithms() []string {
+	return []string{"myers", "patience", "histogram", "minimal"}
+}
+
+// Gutters returns the accepted Gutter values.
+func Gutters() []string {
+	return []string{"both", "old", "new", "none"}
+}
+
+// IconSets returns the accepted Icons values.
+func IconSets() []string {
+	return []string{"ascii", "nerdfont"}
+}
+
 // Load reads config from ~/.config/differ/config.json.
 // Returns defaults if file doesn't exist.
 func Load() Config {
@@ -45,6 +109,20 @@ func LoadFrom(path string) Config {
 	return cfg
 }
 
+// LoadRepo overlays the per-repo config file at path onto cfg. A missing
+// or invalid file leaves cfg unchanged.
+func LoadRepo(cfg Config, path string) Config {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg
+	}
+	repoCfg := cfg
+	if err := json.Unmarshal(data, &repoCfg); err != nil {
+		return cfg
+	}
+	return repoCfg
+}
+
 // Save writes config to ~/.config/differ/config.json.
 func Save(cfg Config) error {
 	path, err := configPath()
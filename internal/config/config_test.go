@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -21,6 +23,30 @@ func TestDefault(t *testing.T) {
 	if cfg.CommitMsgCmd != "" {
 		t.Errorf("CommitMsgCmd should be empty, got %q", cfg.CommitMsgCmd)
 	}
+	if cfg.CommitMsgCount != 1 {
+		t.Errorf("CommitMsgCount=%d, want 1", cfg.CommitMsgCount)
+	}
+	if cfg.FileListRatio != 0 {
+		t.Errorf("FileListRatio=%v, want 0 (fixed width)", cfg.FileListRatio)
+	}
+	if cfg.DefaultView != "files" {
+		t.Errorf("DefaultView=%q, want files", cfg.DefaultView)
+	}
+	if cfg.MaxDiffLines != 10000 {
+		t.Errorf("MaxDiffLines=%d, want 10000", cfg.MaxDiffLines)
+	}
+	if !cfg.Borders {
+		t.Error("Borders should default to true")
+	}
+	if cfg.Gutter != "both" {
+		t.Errorf("Gutter=%q, want both", cfg.Gutter)
+	}
+	if cfg.GitPath != "git" {
+		t.Errorf("GitPath=%q, want git", cfg.GitPath)
+	}
+	if len(cfg.StatusBarItems) == 0 {
+		t.Error("StatusBarItems should default to the standard layout")
+	}
 }
 
 func TestSaveAndLoad(t *testing.T) {
@@ -88,3 +114,48 @@ func TestSave_CreatesDir(t *testing.T) {
 		t.Errorf("config file should exist: %v", err)
 	}
 }
+
+func TestLoadRepo_Overlay(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "differ.json")
+	global := Default()
+	global.Theme = "light"
+
+	if got := LoadRepo(global, path); got.CompareBase != "" || got.Theme != "light" {
+		t.Errorf("missing repo file should keep cfg, got %+v", got)
+	}
+	if err := os.WriteFile(path, []byte(`{"compare_base": "main"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := LoadRepo(global, path)
+	if got.CompareBase != "main" || got.Theme != "light" {
+		t.Errorf("CompareBase=%q Theme=%q, want main/light", got.CompareBase, got.Theme)
+	}
+}
+
+func TestAddRecentTo(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "nested", "recent.json")
+	if got := LoadRecentFrom(path); got != nil {
+		t.Errorf("missing file should give nil, got %v", got)
+	}
+	for _, dir := range []string{"/a", "/b", "/a", "/c"} {
+		if err := AddRecentTo(path, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"/c", "/a", "/b"}
+	if got := LoadRecentFrom(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("recent = %v, want %v", got, want)
+	}
+
+	for i := range maxRecent + 5 {
+		if err := AddRecentTo(path, fmt.Sprintf("/r%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := LoadRecentFrom(path)
+	if len(got) != maxRecent || got[0] != fmt.Sprintf("/r%d", maxRecent+4) {
+		t.Errorf("len=%d first=%q, want capped at %d newest first", len(got), got[0], maxRecent)
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// maxRecent caps the number of remembered repositories.
+const maxRecent = 20
+
+// LoadRecent reads the recently opened repo roots from
+// ~/.config/differ/recent.json, most recent first.
+func LoadRecent() []string {
+	path, err := recentPath()
+	if err != nil {
+		return nil
+	}
+	return LoadRecentFrom(path)
+}
+
+// LoadRecentFrom reads the recent repo list at path. Returns nil on error.
+func LoadRecentFrom(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	if err := json.Unmarshal(data, &dirs); err != nil {
+		return nil
+	}
+	return dirs
+}
+
+// AddRecent records dir as the most recently opened repo.
+func AddRecent(dir string) error {
+	path, err := recentPath()
+	if err != nil {
+		return err
+	}
+	return AddRecentTo(path, dir)
+}
+
+// AddRecentTo moves dir to the front of the recent list at path, dropping
+// duplicates and anything past maxRecent.
+func AddRecentTo(path, dir string) error {
+	dirs := []string{dir}
+	for _, d := range LoadRecentFrom(path) {
+		if d != dir && len(dirs) < maxRecent {
+			dirs = append(dirs, d)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(dirs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func recentPath() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "recent.json"), nil
+}
@@ -2,12 +2,16 @@ package git
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // FileStatus represents the type of change for a file.
@@ -30,13 +34,41 @@ type FileChange struct {
 	Staged       bool
 	AddedLines   int
 	DeletedLines int
+	Binary       bool // numstat has no line counts for it
 }
 
+// ErrShallowBoundary is returned when diffing a commit whose parent was cut
+// off by a shallow clone (git clone --depth).
+var ErrShallowBoundary = errors.New("shallow clone boundary")
+
+// ErrNoRemote is returned by Fetch when the remote is not configured.
+var ErrNoRemote = errors.New("no such remote")
+
+// ErrNotRepository is returned by NewRepo when the directory is not inside
+// any git repository.
+var ErrNotRepository = errors.New("not a git repository")
+
 // UpstreamInfo holds ahead/behind counts relative to the upstream branch.
 type UpstreamInfo struct {
-	Upstream string // e.g. "origin/main", empty if none
-	Ahead    int
-	Behind   int
+	Upstream  string // e.g. "origin/main", empty if none
+	URL       string // fetch URL of the upstream's remote
+	Ahead     int
+	Behind    int
+	LastFetch time.Time // zero if never fetched
+	Shallow   bool      // ahead/behind skipped: the history is truncated
+}
+
+// Divergence describes how HEAD and its upstream have moved apart since
+// their merge base.
+type Divergence struct {
+	Upstream  string
+	MergeBase string   // short hash
+	Ahead     []Commit // on HEAD only: will be pushed
+	Behind    []Commit // on the upstream only: need pulling
+
+	// AheadCount and BehindCount count all the commits on each side;
+	// Ahead and Behind stop at DefaultLogMax.
+	AheadCount, BehindCount int
 }
 
 // Commit represents a git commit entry.
@@ -48,29 +80,156 @@ type Commit struct {
 	Subject string
 }
 
+// BranchInfo is a local branch with its last commit summary.
+type BranchInfo struct {
+	Name    string
+	Subject string
+	Date    string // relative, e.g. "2 days ago"
+}
+
 // Repo wraps git operations for a repository.
 type Repo struct {
-	dir string
+	dir string // repository root; git reports paths relative to it
+	cwd string // directory differ was started in, possibly below dir
+	git string // git binary, "git" unless configured
+
+	diffAlgorithm string // --diff-algorithm for content diffs; empty = git's default
+	wordDiff      bool   // file diffs use --word-diff=plain
 }
 
 // NewRepo validates the path is inside a git repo and returns a Repo.
 func NewRepo(path string) (*Repo, error) {
+	return NewRepoWithGit(path, "git")
+}
+
+// NewRepoWithGit is like NewRepo but runs gitBin (a name on PATH or a full
+// path) for all git operations. The binary must report a git version.
+func NewRepoWithGit(path, gitBin string) (*Repo, error) {
 	abs, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
 	}
-	r := &Repo{dir: abs}
+	if err := checkGitBinary(gitBin); err != nil {
+		return nil, err
+	}
+	r := &Repo{dir: abs, git: gitBin}
+	// --show-toplevel honors GIT_DIR/GIT_WORK_TREE and core.worktree, and
+	// resolves linked worktrees to their own checkout.
 	root, err := r.run("rev-parse", "--show-toplevel")
 	if err != nil {
-		return nil, fmt.Errorf("not a git repository: %s", abs)
+		return nil, r.noWorkTreeError(abs)
 	}
 	r.dir = strings.TrimSpace(root)
+	// git resolves symlinks in the toplevel; do the same so RelPath works
+	// from e.g. a symlinked /tmp.
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	r.cwd = abs
 	return r, nil
 }
 
+// noWorkTreeError explains why abs has no working tree: a bare repository
+// (including a bare GIT_DIR without GIT_WORK_TREE), the inside of a .git
+// directory, or no repository at all.
+func (r *Repo) noWorkTreeError(abs string) error {
+	if out, err := r.run("rev-parse", "--is-bare-repository"); err == nil && strings.TrimSpace(out) == "true" {
+		return fmt.Errorf("bare repository has no working tree to diff: %s (use git worktree add, or set GIT_WORK_TREE)", abs)
+	}
+	if out, err := r.run("rev-parse", "--is-inside-git-dir"); err == nil && strings.TrimSpace(out) == "true" {
+		return fmt.Errorf("inside a git directory, not a working tree: %s", abs)
+	}
+	return fmt.Errorf("%w: %s", ErrNotRepository, abs)
+}
+
+// WithDiffAlgorithm returns a copy of r whose content diffs use algo
+// (myers, patience, histogram or minimal). Unknown values fall back to
+// myers. Returning a copy keeps commands already running on r unaffected.
+func (r *Repo) WithDiffAlgorithm(algo string) *Repo {
+	c := *r
+	switch algo {
+	case "patience", "histogram", "minimal":
+		c.diffAlgorithm = algo
+	default:
+		c.diffAlgorithm = ""
+	}
+	return &c
+}
+
+// DiffAlgorithm is the algorithm content diffs use, "myers" by default.
+func (r *Repo) DiffAlgorithm() string {
+	if r.diffAlgorithm == "" {
+		return "myers"
+	}
+	return r.diffAlgorithm
+}
+
+// WithWordDiff returns a copy of r whose DiffFile and DiffFileFull diffs are
+// git word diffs (--word-diff=plain) when on, for display. StagedDiff and
+// commit diffs in the log stay line-based; callers that parse or apply a
+// file diff take a copy with it off.
+func (r *Repo) WithWordDiff(on bool) *Repo {
+	c := *r
+	c.wordDiff = on
+	return &c
+}
+
+// WordDiff reports whether file diffs are word diffs.
+func (r *Repo) WordDiff() bool {
+	return r.wordDiff
+}
+
+// fileDiffArgs is diffArgs for the working tree and index diffs that
+// honor WithWordDiff.
+func (r *Repo) fileDiffArgs() []string {
+	args := r.diffArgs("diff")
+	if r.wordDiff {
+		args = append(args, "--word-diff=plain")
+	}
+	return args
+}
+
+// diffArgs starts a content diff command with the configured algorithm.
+func (r *Repo) diffArgs(cmd string) []string {
+	args := []string{cmd, "--no-ext-diff", "--color=never"}
+	if r.diffAlgorithm != "" {
+		args = append(args, "--diff-algorithm="+r.diffAlgorithm)
+	}
+	return args
+}
+
+// checkGitBinary verifies gitBin exists and behaves like git. Wrappers such
+// as hub pass; unrelated binaries are rejected before any repo command runs.
+func checkGitBinary(gitBin string) error {
+	if _, err := exec.LookPath(gitBin); err != nil {
+		return fmt.Errorf("git binary %q not found (set git_path or GIT)", gitBin)
+	}
+	out, err := exec.Command(gitBin, "version").Output()
+	if err != nil || !strings.HasPrefix(string(out), "git version ") {
+		return fmt.Errorf("%q does not look like git: `%s version` failed", gitBin, gitBin)
+	}
+	return nil
+}
+
 // Dir returns the repository root directory.
 func (r *Repo) Dir() string { return r.dir }
 
+// AbsPath returns the absolute path of a repo-root-relative path, the form
+// git reports in diffs and status.
+func (r *Repo) AbsPath(path string) string {
+	return filepath.Join(r.dir, path)
+}
+
+// RelPath returns a repo-root-relative path relative to the directory
+// differ was started in, e.g. "../pkg/a.go" when run from a sibling.
+func (r *Repo) RelPath(path string) string {
+	rel, err := filepath.Rel(r.cwd, r.AbsPath(path))
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
 // HasCommits returns true if the repo has at least one commit.
 func (r *Repo) HasCommits() bool {
 	_, err := r.run("rev-parse", "HEAD")
@@ -79,20 +238,26 @@ func (r *Repo) HasCommits() bool {
 
 // BranchName returns the current branch name, or short hash if detached.
 func (r *Repo) BranchName() string {
+	name, _ := r.HeadName()
+	return name
+}
+
+// HeadName is BranchName that also reports whether HEAD is detached.
+func (r *Repo) HeadName() (string, bool) {
 	out, err := r.run("rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
-		return "unknown"
+		return "unknown", false
 	}
 	name := strings.TrimSpace(out)
 	if name == "HEAD" {
 		// detached HEAD — return short hash
 		hash, err := r.run("rev-parse", "--short", "HEAD")
 		if err != nil {
-			return "HEAD"
+			return "HEAD", true
 		}
-		return strings.TrimSpace(hash)
+		return strings.TrimSpace(hash), true
 	}
-	return name
+	return name, false
 }
 
 // ListBranches returns local branch names.
@@ -108,6 +273,34 @@ func (r *Repo) ListBranches() ([]string, error) {
 	return strings.Split(out, "\n"), nil
 }
 
+// ListBranchesDetailed returns local branches with their last commit subject
+// and relative date.
+func (r *Repo) ListBranchesDetailed() ([]BranchInfo, error) {
+	format := "%(refname:short)%00%(contents:subject)%00%(committerdate:relative)"
+	out, err := r.run("for-each-ref", "--format="+format, "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	return parseBranchInfo(out), nil
+}
+
+// MergedBranches returns local branch names fully merged into the given ref.
+// An empty ref means HEAD.
+func (r *Repo) MergedBranches(into string) ([]string, error) {
+	if into == "" {
+		into = "HEAD"
+	}
+	out, err := r.run("branch", "--merged", into, "--format=%(refname:short)")
+	if err != nil {
+		return nil, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
 // CreateBranch creates a new branch at the current HEAD.
 func (r *Repo) CreateBranch(name string) error {
 	_, err := r.run("branch", name)
@@ -120,6 +313,28 @@ func (r *Repo) CheckoutBranch(name string) error {
 	return err
 }
 
+// CheckoutDetached checks out ref (a commit, tag or branch) with a detached
+// HEAD, like git switch --detach.
+func (r *Repo) CheckoutDetached(ref string) error {
+	_, err := r.runWithStderr("switch", "--detach", ref)
+	return err
+}
+
+// IsDirty reports whether tracked files have staged or unstaged changes,
+// the ones that can make a switch, pull or rebase refuse to run. Untracked
+// files don't count. A failing status counts as dirty.
+func (r *Repo) IsDirty() bool {
+	out, err := r.run("status", "--porcelain", "--untracked-files=no")
+	return err != nil || strings.TrimSpace(out) != ""
+}
+
+// Stash saves staged and unstaged changes of tracked files with git stash
+// push, leaving a clean tree. message labels the entry in git stash list.
+func (r *Repo) Stash(message string) error {
+	_, err := r.runWithStderr("stash", "push", "-m", message)
+	return err
+}
+
 // UpstreamStatus returns ahead/behind counts relative to the upstream branch.
 // Returns zero-value UpstreamInfo if no upstream is configured.
 func (r *Repo) UpstreamStatus() UpstreamInfo {
@@ -128,8 +343,16 @@ func (r *Repo) UpstreamStatus() UpstreamInfo {
 		return UpstreamInfo{}
 	}
 	upstream = strings.TrimSpace(upstream)
-	info := UpstreamInfo{Upstream: upstream}
+	info := UpstreamInfo{Upstream: upstream, LastFetch: r.LastFetchTime()}
+	remote, _, _ := strings.Cut(upstream, "/")
+	if url, err := r.run("remote", "get-url", remote); err == nil {
+		info.URL = strings.TrimSpace(url)
+	}
 
+	if r.IsShallow() {
+		info.Shallow = true
+		return info
+	}
 	out, err := r.run("rev-list", "--left-right", "--count", "HEAD...@{u}")
 	if err != nil {
 		return info
@@ -142,6 +365,64 @@ func (r *Repo) UpstreamStatus() UpstreamInfo {
 	return info
 }
 
+// LastFetchTime returns when the repo last fetched (the mtime of
+// FETCH_HEAD), or the zero time if it never has.
+func (r *Repo) LastFetchTime() time.Time {
+	fi, err := os.Stat(r.gitPath("FETCH_HEAD"))
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// gitPath returns the absolute path of a file inside the git directory,
+// or "" if git can't resolve it.
+func (r *Repo) gitPath(name string) string {
+	out, err := r.run("rev-parse", "--git-path", name)
+	if err != nil {
+		return ""
+	}
+	path := strings.TrimSpace(out)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.dir, path)
+	}
+	return path
+}
+
+// IsShallow reports whether the repo is a shallow clone.
+func (r *Repo) IsShallow() bool {
+	out, err := r.run("rev-parse", "--is-shallow-repository")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// isShallowBoundary reports whether hash is a grafted commit of a shallow
+// clone, i.e. listed in .git/shallow: its parents exist but were not fetched.
+func (r *Repo) isShallowBoundary(hash string) bool {
+	full, err := r.run("rev-parse", hash)
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(r.gitPath("shallow"))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(data), strings.TrimSpace(full))
+}
+
+// LocalConfigPath is the per-repo differ config file. It lives in the
+// common git dir so every linked worktree of a repo shares it.
+func (r *Repo) LocalConfigPath() string {
+	out, err := r.run("rev-parse", "--git-common-dir")
+	if err != nil {
+		return ""
+	}
+	dir := strings.TrimSpace(out)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.dir, dir)
+	}
+	return filepath.Join(dir, "differ.json")
+}
+
 // Push pushes to the upstream branch.
 func (r *Repo) Push() error {
 	_, err := r.runWithStderr("push")
@@ -154,6 +435,46 @@ func (r *Repo) PushSetUpstream(remote, branch string) error {
 	return err
 }
 
+// fetchTimeout bounds a background fetch, so a remote that stops
+// answering doesn't leave it running for the rest of the session.
+const fetchTimeout = time.Minute
+
+// Fetch fetches remote in the background: credential and ssh prompts are
+// disabled so a remote that needs them fails instead of waiting on the
+// terminal, and the fetch gives up after fetchTimeout. A user's own ssh
+// command is left alone.
+// Returns ErrNoRemote when remote is not configured.
+func (r *Repo) Fetch(remote string) error {
+	return r.fetch(remote, fetchTimeout)
+}
+
+func (r *Repo) fetch(remote string, timeout time.Duration) error {
+	if _, err := r.run("remote", "get-url", remote); err != nil {
+		return ErrNoRemote
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err := r.runWithEnvContext(ctx, r.fetchEnv(), "fetch", "--quiet", remote)
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("fetch from %s timed out after %s", remote, timeout)
+	}
+	return err
+}
+
+// fetchEnv disables prompts for a background fetch. BatchMode is only
+// added when neither GIT_SSH_COMMAND, GIT_SSH nor core.sshCommand picks
+// the ssh command, since setting GIT_SSH_COMMAND would override them.
+func (r *Repo) fetchEnv() []string {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	if os.Getenv("GIT_SSH_COMMAND") != "" || os.Getenv("GIT_SSH") != "" {
+		return env
+	}
+	if _, err := r.run("config", "core.sshCommand"); err == nil {
+		return env
+	}
+	return append(env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+}
+
 // Pull pulls from the upstream branch using fast-forward only.
 func (r *Repo) Pull() error {
 	_, err := r.runWithStderr("pull", "--ff-only")
@@ -162,12 +483,13 @@ func (r *Repo) Pull() error {
 
 // ChangedFiles returns files changed in the working tree or index.
 // If staged is true, only returns staged changes.
-// If ref is non-empty, compares against that ref.
+// If ref is non-empty, compares against that ref; combined with staged it
+// compares the index against ref.
 func (r *Repo) ChangedFiles(staged bool, ref string) ([]FileChange, error) {
 	var files []FileChange
 
 	if ref != "" {
-		return r.changedFilesRef(ref)
+		return r.changedFilesRef(ref, staged)
 	}
 
 	// Staged changes
@@ -211,6 +533,27 @@ func (r *Repo) ChangedFiles(staged bool, ref string) ([]FileChange, error) {
 	return files, nil
 }
 
+// ChangedFilesRange lists changes on head since it forked from base
+// (git diff base...head). The result is committed history only, so
+// nothing in it is stageable.
+func (r *Repo) ChangedFilesRange(base, head string) ([]FileChange, error) {
+	return r.changedFilesRef(RangeRef(base, head), false)
+}
+
+// RangeRef is the three-dot spec comparing head against its merge base
+// with base. It can be passed anywhere a ref is accepted by DiffFile.
+func RangeRef(base, head string) string {
+	return base + "..." + head
+}
+
+// MergeBaseRef marks ref for comparison from its merge base with HEAD
+// instead of its tip, so the diff leaves out whatever ref gained since the
+// branch forked. Unlike RangeRef it still includes the index and working
+// tree.
+func MergeBaseRef(ref string) string {
+	return ref + "..."
+}
+
 // UntrackedFiles returns paths of untracked files.
 func (r *Repo) UntrackedFiles() ([]string, error) {
 	out, err := r.run("ls-files", "--others", "--exclude-standard")
@@ -224,14 +567,65 @@ func (r *Repo) UntrackedFiles() ([]string, error) {
 	return strings.Split(out, "\n"), nil
 }
 
-// DiffFile returns the raw diff for a single file.
+// DiffFile returns the raw diff for a single file. With both staged and ref
+// set it diffs the index against ref (git diff --cached <ref>).
 func (r *Repo) DiffFile(path string, staged bool, ref string) (string, error) {
-	args := []string{"diff", "--no-ext-diff", "--color=never"}
+	args := r.fileDiffArgs()
+	if staged {
+		args = append(args, "--cached")
+	}
+	if ref != "" {
+		args = append(args, r.diffBase(ref))
+	}
+	args = append(args, "--", path)
+	return r.run(args...)
+}
+
+// DiffToolCmd builds (without running) git difftool for one file, with the
+// same staged/ref selection as DiffFile. An empty tool uses git's diff.tool.
+func (r *Repo) DiffToolCmd(path string, staged bool, ref, tool string) *exec.Cmd {
+	args := []string{"difftool", "--no-prompt"}
+	if tool != "" {
+		args = append(args, "--tool="+tool)
+	}
+	if staged {
+		args = append(args, "--cached")
+	}
+	if ref != "" {
+		args = append(args, r.diffBase(ref))
+	}
+	args = append(args, "--", path)
+	cmd := exec.Command(r.git, args...)
+	cmd.Dir = r.dir
+	return cmd
+}
+
+// MergeToolCmd builds (without running) git mergetool for one conflicted
+// file. An empty tool uses git's merge.tool.
+func (r *Repo) MergeToolCmd(path, tool string) *exec.Cmd {
+	args := []string{"mergetool", "--no-prompt"}
+	if tool != "" {
+		args = append(args, "--tool="+tool)
+	}
+	args = append(args, "--", path)
+	cmd := exec.Command(r.git, args...)
+	cmd.Dir = r.dir
+	return cmd
+}
+
+// fullContext is the -U value that makes a diff include every line of the
+// file, so unchanged regions appear between the changes.
+const fullContext = "--unified=1000000"
+
+// DiffFileFull is DiffFile with the whole file as context: the complete
+// old and new versions, aligned around the changes.
+func (r *Repo) DiffFileFull(path string, staged bool, ref string) (string, error) {
+	args := append(r.fileDiffArgs(), fullContext)
 	if staged {
 		args = append(args, "--cached")
 	}
 	if ref != "" {
-		args = append(args, ref)
+		args = append(args, r.diffBase(ref))
 	}
 	args = append(args, "--", path)
 	return r.run(args...)
@@ -239,8 +633,7 @@ func (r *Repo) DiffFile(path string, staged bool, ref string) (string, error) {
 
 // ReadFileContent reads a file from the working tree.
 func (r *Repo) ReadFileContent(path string) (string, error) {
-	full := filepath.Join(r.dir, path)
-	data, err := os.ReadFile(full)
+	data, err := os.ReadFile(r.AbsPath(path))
 	if err != nil {
 		return "", err
 	}
@@ -249,70 +642,614 @@ func (r *Repo) ReadFileContent(path string) (string, error) {
 
 // StageFile stages a file.
 func (r *Repo) StageFile(path string) error {
-	_, err := r.run("add", "--", path)
+	return r.StageFiles(path)
+}
+
+// StageFiles stages several files in one git add.
+func (r *Repo) StageFiles(paths ...string) error {
+	_, err := r.runWithStderr(append([]string{"add", "--"}, paths...)...)
 	return err
 }
 
 // UnstageFile unstages a file.
 func (r *Repo) UnstageFile(path string) error {
+	return r.UnstageFiles(path)
+}
+
+// UnstageFiles unstages several files in one git call.
+func (r *Repo) UnstageFiles(paths ...string) error {
 	if !r.HasCommits() {
-		_, err := r.run("rm", "--cached", "--", path)
+		_, err := r.runWithStderr(append([]string{"rm", "-q", "--cached", "--"}, paths...)...)
 		return err
 	}
-	_, err := r.run("reset", "HEAD", "--", path)
+	_, err := r.runWithStderr(append([]string{"reset", "-q", "HEAD", "--"}, paths...)...)
+	return err
+}
+
+// ParsedHunk is one hunk of a file's unified diff: its @@ header and its
+// lines, each still prefixed with ' ', '-', '+' or '\'.
+type ParsedHunk struct {
+	Header string
+	Lines  []string
+}
+
+// ParseHunks splits a single file's unified diff into its hunks.
+func ParseHunks(diff string) []ParsedHunk {
+	var hunks []ParsedHunk
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git"):
+			if len(hunks) > 0 {
+				return hunks // a second file; callers diff one path
+			}
+		case strings.HasPrefix(line, "@@"):
+			hunks = append(hunks, ParsedHunk{Header: line})
+		case len(hunks) > 0:
+			h := &hunks[len(hunks)-1]
+			h.Lines = append(h.Lines, line)
+		}
+	}
+	return hunks
+}
+
+// StageHunk stages one hunk of path's unstaged diff.
+func (r *Repo) StageHunk(path string, hunk ParsedHunk) error {
+	return r.applyHunk(path, hunk, false)
+}
+
+// UnstageHunk unstages one hunk of path's staged diff.
+func (r *Repo) UnstageHunk(path string, hunk ParsedHunk) error {
+	return r.applyHunk(path, hunk, true)
+}
+
+// applyHunk applies hunk to the index through git apply --cached, in
+// reverse to take it back out. A hunk without context lines (diff.context
+// set to 0, or a file that was empty) needs --unidiff-zero.
+func (r *Repo) applyHunk(path string, hunk ParsedHunk, reverse bool) error {
+	args := []string{"apply", "--cached"}
+	if reverse {
+		args = append(args, "--reverse")
+	}
+	if !hasContext(hunk) {
+		args = append(args, "--unidiff-zero")
+	}
+	_, err := r.runWithInput(FilePatch(path, hunk), append(args, "-")...)
+	return err
+}
+
+// ApplyPartialPatch applies a synthesized patch, such as one built from
+// PartialHunk, to the index when cached is set and to the working tree
+// otherwise. Narrowed hunks can end without trailing context, hence
+// --unidiff-zero.
+func (r *Repo) ApplyPartialPatch(patch string, cached bool) error {
+	args := []string{"apply", "--unidiff-zero"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	_, err := r.runWithInput(patch, append(args, "-")...)
 	return err
 }
 
+// FilePatch wraps hunks of path in the headers git apply needs.
+func FilePatch(path string, hunks ...ParsedHunk) string {
+	var b strings.Builder
+	b.WriteString("diff --git a/" + path + " b/" + path + "\n")
+	b.WriteString("--- a/" + path + "\n")
+	b.WriteString("+++ b/" + path + "\n")
+	for _, h := range hunks {
+		b.WriteString(h.Header + "\n")
+		for _, line := range h.Lines {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// PartialHunk narrows h to the changes keep selects, given removed lines'
+// old line numbers and added lines' new ones. An unselected addition is
+// dropped and an unselected removal kept as context, so only the chosen
+// lines change. With reverse the hunk is from a staged diff and the result
+// takes the chosen lines back out of the index: the sides swap, and it
+// applies forward from the index side. ok is false when keep selected no
+// change.
+func PartialHunk(h ParsedHunk, keep func(removed bool, num int) bool, reverse bool) (ParsedHunk, bool) {
+	oldNum, newNum := hunkStarts(h.Header)
+	start := oldNum
+	if reverse {
+		start = newNum
+	}
+	var lines []string
+	oldCount, newCount := 0, 0
+	changed, dropped := false, false
+	for _, line := range h.Lines {
+		if line == "" {
+			line = " " // an empty context line with its space trimmed
+		}
+		kind, text := line[0], line[1:]
+		selected := false
+		switch kind {
+		case '\\':
+			if !dropped {
+				lines = append(lines, line)
+			}
+			continue
+		case '-':
+			selected = keep(true, oldNum)
+			oldNum++
+		case '+':
+			selected = keep(false, newNum)
+			newNum++
+		default:
+			oldNum++
+			newNum++
+		}
+		if reverse && kind == '-' {
+			kind = '+'
+		} else if reverse && kind == '+' {
+			kind = '-'
+		}
+		switch {
+		case kind == ' ' || (kind == '-' && !selected):
+			kind = ' '
+			oldCount++
+			newCount++
+		case kind == '-':
+			oldCount++
+		case selected: // '+'
+			newCount++
+		default:
+			dropped = true
+			continue
+		}
+		changed = changed || selected
+		dropped = false
+		lines = append(lines, string(kind)+text)
+	}
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", start, oldCount, start, newCount)
+	return ParsedHunk{Header: header, Lines: lines}, changed
+}
+
+// hunkStarts reads the first old and new line numbers from a hunk header
+// like "@@ -12,7 +12,9 @@".
+func hunkStarts(header string) (oldStart, newStart int) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0, 0
+	}
+	parse := func(r string) int {
+		n, _ := strconv.Atoi(strings.SplitN(r[1:], ",", 2)[0])
+		return n
+	}
+	return parse(fields[1]), parse(fields[2])
+}
+
+// hasContext reports whether hunk has an unchanged line to anchor it.
+func hasContext(hunk ParsedHunk) bool {
+	for _, line := range hunk.Lines {
+		if strings.HasPrefix(line, " ") {
+			return true
+		}
+	}
+	return false
+}
+
 // StageAll stages all changes.
 func (r *Repo) StageAll() error {
-	_, err := r.run("add", "-A")
+	_, err := r.runWithStderr("add", "-A")
+	return err
+}
+
+// UnstageAll unstages everything, keeping the working tree as is. Before
+// the first commit there is no HEAD to reset to, so the index is emptied.
+func (r *Repo) UnstageAll() error {
+	if !r.HasCommits() {
+		_, err := r.runWithStderr("rm", "-r", "-q", "--cached", "--ignore-unmatch", ".")
+		return err
+	}
+	_, err := r.runWithStderr("reset", "-q", "HEAD")
+	return err
+}
+
+// StageTracked stages modifications and deletions of tracked files,
+// leaving untracked files alone (git add -u).
+func (r *Repo) StageTracked() error {
+	_, err := r.runWithStderr("add", "-u")
 	return err
 }
 
+// Clean removes untracked files and returns the affected paths.
+// With dryRun, nothing is deleted and the paths that would be removed are
+// returned. dirs also removes untracked directories; ignored includes files
+// matched by .gitignore, which are otherwise kept. paths, as listed by a dry
+// run, limits the clean to them, so nothing created since is removed.
+func (r *Repo) Clean(dryRun, dirs, ignored bool, paths ...string) ([]string, error) {
+	args := []string{"--literal-pathspecs", "clean"}
+	if dryRun {
+		args = append(args, "-n")
+	} else {
+		args = append(args, "-f")
+	}
+	if dirs {
+		args = append(args, "-d")
+	}
+	if ignored {
+		args = append(args, "-x")
+	}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	out, err := r.runWithStderr(args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseClean(out), nil
+}
+
 // StagedDiff returns the full diff of staged changes.
 func (r *Repo) StagedDiff() (string, error) {
-	return r.run("diff", "--cached", "--no-ext-diff", "--color=never")
+	return r.run(append(r.diffArgs("diff"), "--cached")...)
 }
 
 // Commit creates a commit with the given message.
-func (r *Repo) Commit(msg string) error {
-	_, err := r.run("commit", "-m", msg)
+// It returns the new commit's short hash, or "" when the commit was made but
+// its hash can't be read back. On failure the error carries both stdout and
+// stderr, since hooks often print fix instructions on stdout.
+func (r *Repo) Commit(msg string) (string, error) {
+	if _, err := r.runWithOutput("commit", "-m", msg); err != nil {
+		return "", err
+	}
+	out, err := r.run("rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", nil
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CommitFixup commits the staged changes as a "fixup!" commit for hash, to
+// be folded in later by RebaseAutosquash. It returns the new short hash.
+func (r *Repo) CommitFixup(hash string) (string, error) {
+	if out, err := r.run("diff", "--cached", "--name-only"); err != nil || strings.TrimSpace(out) == "" {
+		return "", fmt.Errorf("nothing staged for a fixup")
+	}
+	if _, err := r.runWithOutput("commit", "--fixup="+hash); err != nil {
+		return "", err
+	}
+	out, err := r.run("rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// RebaseAutosquash folds fixup! and squash! commits into their targets by
+// rebasing everything after base's parent (the whole history for a root
+// commit). The todo list and squash messages are accepted as generated,
+// so no editor opens. Tracked changes must be committed or stashed first.
+// If the rebase stops on a conflict, RebaseInProgress reports true.
+func (r *Repo) RebaseAutosquash(base string) error {
+	if r.IsDirty() {
+		return fmt.Errorf("working tree has changes; commit or stash them first")
+	}
+	args := []string{"rebase", "-i", "--autosquash", base + "~1"}
+	if _, err := r.run("rev-parse", "--verify", "--quiet", base+"~1"); err != nil {
+		args = []string{"rebase", "-i", "--autosquash", "--root"}
+	}
+	_, err := r.runWithEnv([]string{"GIT_SEQUENCE_EDITOR=true", "GIT_EDITOR=true"}, args...)
 	return err
 }
 
+// RebaseInProgress reports whether a rebase is stopped, e.g. on conflicts.
+func (r *Repo) RebaseInProgress() bool {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		if path := r.gitPath(name); path != "" {
+			if _, err := os.Stat(path); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Log returns the n most recent commits.
 func (r *Repo) Log(n int) ([]Commit, error) {
-	format := "%H%x00%h%x00%an%x00%ar%x00%s"
-	out, err := r.run("log", "-"+strconv.Itoa(n), "--format="+format)
+	return r.LogFiltered(LogOptions{Max: n})
+}
+
+// LogOptions scopes LogFiltered. Zero values mean no filter; Max <= 0
+// falls back to DefaultLogMax.
+type LogOptions struct {
+	Max    int
+	Since  string // any date git understands, e.g. "1 week ago"
+	Until  string
+	Author string // regex matched against author name/email
+
+	FirstParent bool // follow only the first parent of merges
+	Merges      bool // only merge commits
+	NoMerges    bool // skip merge commits
+}
+
+// DefaultLogMax is the number of commits loaded when no limit is given.
+const DefaultLogMax = 100
+
+// LogFiltered returns commits matching opts, most recent first.
+func (r *Repo) LogFiltered(opts LogOptions) ([]Commit, error) {
+	out, err := r.runWithStderr(logArgs(opts)...)
 	if err != nil {
 		return nil, err
 	}
 	return parseLog(out), nil
 }
 
+// logFormat is the git log format parsed by parseLog.
+const logFormat = "--format=%H%x00%h%x00%an%x00%ar%x00%s"
+
+func logArgs(opts LogOptions) []string {
+	n := opts.Max
+	if n <= 0 {
+		n = DefaultLogMax
+	}
+	args := []string{"log", "-" + strconv.Itoa(n), logFormat}
+	if opts.Since != "" {
+		args = append(args, "--since="+opts.Since)
+	}
+	if opts.Until != "" {
+		args = append(args, "--until="+opts.Until)
+	}
+	if opts.Author != "" {
+		args = append(args, "--author="+opts.Author)
+	}
+	if opts.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if opts.Merges {
+		args = append(args, "--merges")
+	}
+	if opts.NoMerges {
+		args = append(args, "--no-merges")
+	}
+	return args
+}
+
+// UpstreamDivergence lists the merge base of HEAD and @{u} and the commits
+// on each side of it (up to DefaultLogMax each, with the full counts).
+func (r *Repo) UpstreamDivergence() (Divergence, error) {
+	upstream, err := r.runWithStderr("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	if err != nil {
+		return Divergence{}, err
+	}
+	d := Divergence{Upstream: strings.TrimSpace(upstream)}
+	if base, err := r.run("merge-base", "HEAD", "@{u}"); err == nil {
+		base = strings.TrimSpace(base)
+		d.MergeBase = base[:min(7, len(base))]
+	}
+	limit := "-" + strconv.Itoa(DefaultLogMax)
+	ahead, err := r.runWithStderr("log", limit, logFormat, "@{u}..HEAD")
+	if err != nil {
+		return Divergence{}, err
+	}
+	behind, err := r.runWithStderr("log", limit, logFormat, "HEAD..@{u}")
+	if err != nil {
+		return Divergence{}, err
+	}
+	d.Ahead, d.Behind = parseLog(ahead), parseLog(behind)
+	d.AheadCount, d.BehindCount = len(d.Ahead), len(d.Behind)
+	if out, err := r.run("rev-list", "--left-right", "--count", "HEAD...@{u}"); err == nil {
+		if parts := strings.Fields(out); len(parts) == 2 {
+			d.AheadCount, _ = strconv.Atoi(parts[0])
+			d.BehindCount, _ = strconv.Atoi(parts[1])
+		}
+	}
+	return d, nil
+}
+
+// RemoteWebURL returns the browser URL of the current branch's upstream
+// remote, e.g. "https://github.com/org/repo".
+func (r *Repo) RemoteWebURL() (string, error) {
+	info := r.UpstreamStatus()
+	if info.Upstream == "" {
+		return "", fmt.Errorf("no upstream")
+	}
+	return WebURL(info.URL)
+}
+
+// WebURL converts a remote URL (scp-style SSH, ssh:// or https://) into
+// its https web URL.
+func WebURL(remote string) (string, error) {
+	remote = strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	if host, path, ok := strings.Cut(remote, ":"); ok && !strings.Contains(remote, "://") {
+		// scp-style: git@host:org/repo
+		host = host[strings.LastIndex(host, "@")+1:]
+		if host == "" {
+			return "", fmt.Errorf("unrecognized remote %q", remote)
+		}
+		return "https://" + host + "/" + strings.TrimPrefix(path, "/"), nil
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("unrecognized remote %q", remote)
+	}
+	return "https://" + u.Hostname() + u.Path, nil
+}
+
+// CompareURL returns the "create pull/merge request" URL for branch on a
+// GitHub or GitLab web URL. On GitHub the branch is part of the path, so
+// each of its segments is escaped and its slashes are kept.
+func CompareURL(webURL, branch string) (string, error) {
+	u, err := url.Parse(webURL)
+	if err != nil {
+		return "", err
+	}
+	switch host := u.Hostname(); {
+	case strings.Contains(host, "github"):
+		segs := strings.Split(branch, "/")
+		for i, s := range segs {
+			segs[i] = url.PathEscape(s)
+		}
+		return webURL + "/compare/" + strings.Join(segs, "/") + "?expand=1", nil
+	case strings.Contains(host, "gitlab"):
+		return webURL + "/-/merge_requests/new?merge_request%5Bsource_branch%5D=" + url.QueryEscape(branch), nil
+	default:
+		return "", fmt.Errorf("unsupported host %q", host)
+	}
+}
+
+// ResolveRef resolves a (partial) hash or ref to a full commit hash.
+// Errors carry git's message, e.g. for ambiguous short hashes.
+func (r *Repo) ResolveRef(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid ref %q", ref)
+	}
+	out, err := r.runWithStderr("rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CommitBody returns a commit message without its subject line.
+func (r *Repo) CommitBody(hash string) (string, error) {
+	out, err := r.runWithStderr("log", "-1", "--format=%b", hash)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// ShowBlob returns the raw bytes of path at ref. An empty ref reads the
+// index version (git show :<path>).
+func (r *Repo) ShowBlob(ref, path string) ([]byte, error) {
+	out, err := r.run("show", ref+":"+path)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// BlameLine is the commit that last changed one line.
+type BlameLine struct {
+	Hash    string // zero hash for a change that is not committed yet
+	Author  string
+	Time    time.Time
+	Summary string
+}
+
+// Uncommitted reports whether the line changed after the last commit.
+func (b BlameLine) Uncommitted() bool {
+	return strings.Trim(b.Hash, "0") == ""
+}
+
+// BlameOld blames line of the old side of DiffFile(path, staged, ref): the
+// ref (a range's base), HEAD for staged diffs, or else the index.
+func (r *Repo) BlameOld(path string, staged bool, ref string, line int) (BlameLine, error) {
+	args := []string{"blame", "--porcelain", "-L", fmt.Sprintf("%d,%d", line, line)}
+	var out string
+	var err error
+	switch {
+	case ref != "":
+		out, err = r.runWithStderr(append(args, r.oldSide(ref), "--", path)...)
+	case staged:
+		out, err = r.runWithStderr(append(args, "HEAD", "--", path)...)
+	default:
+		var index []byte
+		if index, err = r.ShowBlob("", path); err == nil {
+			out, err = r.runWithInput(string(index), append(args, "--contents", "-", "--", path)...)
+		}
+	}
+	if err != nil {
+		return BlameLine{}, err
+	}
+	return parseBlamePorcelain(out), nil
+}
+
+// oldSide is the revision a diff against ref compares from: the merge base
+// of a three-dot range, the left end of a two-dot range, or ref itself.
+func (r *Repo) oldSide(ref string) string {
+	if a, b, ok := strings.Cut(ref, "..."); ok {
+		if b == "" {
+			b = "HEAD"
+		}
+		if base, err := r.run("merge-base", a, b); err == nil {
+			return strings.TrimSpace(base)
+		}
+		return a
+	}
+	if a, _, ok := strings.Cut(ref, ".."); ok {
+		return a
+	}
+	return r.diffBase(ref)
+}
+
+// parseBlamePorcelain reads the header of one git blame --porcelain entry.
+func parseBlamePorcelain(out string) BlameLine {
+	var b BlameLine
+	for i, line := range strings.Split(out, "\n") {
+		if i == 0 {
+			b.Hash, _, _ = strings.Cut(line, " ")
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			b.Author = value
+		case "author-time":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				b.Time = time.Unix(sec, 0)
+			}
+		case "summary":
+			b.Summary = value
+		}
+		if strings.HasPrefix(line, "\t") {
+			break
+		}
+	}
+	return b
+}
+
 // CommitDiff returns the full diff for a commit.
 // For the root commit (no parent), uses diff-tree against empty tree.
 func (r *Repo) CommitDiff(hash string) (string, error) {
-	out, err := r.run("diff", hash+"~1", hash, "--no-ext-diff", "--color=never")
+	out, err := r.run(append(r.diffArgs("diff"), hash+"~1", hash)...)
 	if err != nil {
+		// A grafted commit would diff against the empty tree and show
+		// every file as added.
+		if r.isShallowBoundary(hash) {
+			return "", ErrShallowBoundary
+		}
 		// Root commit — diff against empty tree
-		return r.run("diff-tree", "-p", "--root", "--no-ext-diff", "--color=never", hash)
+		return r.run(append(r.diffArgs("diff-tree"), "-p", "--root", hash)...)
 	}
 	return out, nil
 }
 
-// CommitDiffFiles returns files changed in a commit.
+// RangeDiff returns the cumulative diff from commit a to commit b.
+func (r *Repo) RangeDiff(a, b string) (string, error) {
+	return r.run(append(r.diffArgs("diff"), a, b)...)
+}
+
+// CommitDiffFiles returns files changed in a commit, with line stats.
 func (r *Repo) CommitDiffFiles(hash string) ([]FileChange, error) {
-	out, err := r.run("diff", hash+"~1", hash, "--name-status")
+	ref, err := r.CommitRef(hash)
 	if err != nil {
 		return nil, err
 	}
-	return parseNameStatus(out), nil
+	return r.changedFilesRef(ref, false)
+}
+
+// CommitRef is the two-dot spec diffing a commit against its first parent,
+// or against the empty tree for a root commit. It can be passed anywhere a
+// ref is accepted by DiffFile. A shallow clone's boundary commit has a
+// parent that wasn't fetched and returns ErrShallowBoundary, as CommitDiff.
+func (r *Repo) CommitRef(hash string) (string, error) {
+	if r.isShallowBoundary(hash) {
+		return "", ErrShallowBoundary
+	}
+	return r.diffBase(hash+"~1") + ".." + hash, nil
 }
 
 // run executes a git command and returns stdout.
 func (r *Repo) run(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
+	cmd := exec.Command(r.git, args...)
 	cmd.Dir = r.dir
 	out, err := cmd.Output()
 	if err != nil {
@@ -324,7 +1261,7 @@ func (r *Repo) run(args ...string) (string, error) {
 // runWithStderr executes a git command and returns stdout.
 // On error, includes stderr in the error message for better diagnostics.
 func (r *Repo) runWithStderr(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
+	cmd := exec.Command(r.git, args...)
 	cmd.Dir = r.dir
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -339,10 +1276,94 @@ func (r *Repo) runWithStderr(args ...string) (string, error) {
 	return stdout.String(), nil
 }
 
+// runWithOutput is like runWithStderr but on error reports stdout and stderr
+// together, preserving hook output such as linter messages.
+func (r *Repo) runWithOutput(args ...string) (string, error) {
+	return r.runWithEnv(nil, args...)
+}
+
+// runWithInput is runWithStderr with input on stdin.
+func (r *Repo) runWithInput(input string, args ...string) (string, error) {
+	cmd := exec.Command(r.git, args...)
+	cmd.Dir = r.dir
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("%s", msg)
+	}
+	return stdout.String(), nil
+}
+
+// runWithEnv is runWithOutput with extra environment variables, e.g. to
+// stand in for an interactive editor.
+func (r *Repo) runWithEnv(env []string, args ...string) (string, error) {
+	return r.runWithEnvContext(context.Background(), env, args...)
+}
+
+// runWithEnvContext is runWithEnv killed when ctx is done.
+func (r *Repo) runWithEnvContext(ctx context.Context, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, r.git, args...)
+	cmd.Dir = r.dir
+	cmd.WaitDelay = time.Second // don't wait on pipes held by killed children
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(strings.TrimSpace(stdout.String()) + "\n" + strings.TrimSpace(stderr.String()))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("%s", msg)
+	}
+	return stdout.String(), nil
+}
+
+// emptyTree is git's well-known hash of the empty tree, the base to diff a
+// root commit or an empty index against.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// diffBase maps the parent of a root commit (root~1, root^, root~) to the
+// empty tree so diffs against it show every file as added, and a
+// MergeBaseRef to the merge base it names. Other refs are returned
+// unchanged and left for git to resolve or reject.
+func (r *Repo) diffBase(ref string) string {
+	if tip, ok := strings.CutSuffix(ref, "..."); ok && tip != "" {
+		if base, err := r.run("merge-base", tip, "HEAD"); err == nil {
+			return strings.TrimSpace(base)
+		}
+		return ref
+	}
+	base, ok := strings.CutSuffix(ref, "~1")
+	if !ok {
+		base, ok = strings.CutSuffix(ref, "^")
+	}
+	if !ok {
+		base, ok = strings.CutSuffix(ref, "~")
+	}
+	if !ok || base == "" {
+		return ref
+	}
+	if _, err := r.run("rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+		return ref
+	}
+	if _, err := r.run("rev-parse", "--verify", "--quiet", base+"^{commit}"); err != nil {
+		return ref
+	}
+	return emptyTree
+}
+
 // diffNameStatusEmptyTree lists staged files when there are no commits yet.
 func (r *Repo) diffNameStatusEmptyTree() ([]FileChange, error) {
-	// 4b825dc... is git's well-known empty tree hash
-	out, err := r.run("diff-index", "--name-status", "--cached", "4b825dc642cb6eb9a060e54bf899d69f82c6b18f")
+	out, err := r.run("diff-index", "--name-status", "--cached", emptyTree)
 	if err != nil {
 		return nil, err
 	}
@@ -369,23 +1390,35 @@ func (r *Repo) diffNumStat(extraArgs ...string) (map[string]lineStats, error) {
 }
 
 // changedFilesRef returns files changed compared to a ref.
-func (r *Repo) changedFilesRef(ref string) ([]FileChange, error) {
-	out, err := r.run("diff", "--name-status", "--no-ext-diff", "--color=never", ref)
+// changedFilesRef lists changes against ref: the working tree, or with
+// staged the index (git diff --cached <ref>).
+func (r *Repo) changedFilesRef(ref string, staged bool) ([]FileChange, error) {
+	var extra []string
+	if staged {
+		extra = append(extra, "--cached")
+	}
+	extra = append(extra, r.diffBase(ref))
+	args := append([]string{"diff", "--name-status", "--no-ext-diff", "--color=never"}, extra...)
+	out, err := r.run(args...)
 	if err != nil {
 		return nil, err
 	}
 	files := parseNameStatus(out)
-	stats, err := r.diffNumStat(ref)
+	stats, err := r.diffNumStat(extra...)
 	if err != nil {
 		return nil, err
 	}
 	applyStats(files, stats)
+	for i := range files {
+		files[i].Staged = staged
+	}
 	return files, nil
 }
 
 type lineStats struct {
 	added   int
 	deleted int
+	binary  bool
 }
 
 func parseNumStat(out string) map[string]lineStats {
@@ -401,7 +1434,7 @@ func parseNumStat(out string) map[string]lineStats {
 		path := parseNumStatPath(parts[len(parts)-1])
 		added := parseNumStatInt(parts[0])
 		deleted := parseNumStatInt(parts[1])
-		stats[path] = lineStats{added: added, deleted: deleted}
+		stats[path] = lineStats{added: added, deleted: deleted, binary: parts[0] == "-" && parts[1] == "-"}
 	}
 	return stats
 }
@@ -447,6 +1480,7 @@ func applyStats(files []FileChange, stats map[string]lineStats) {
 		}
 		files[i].AddedLines = st.added
 		files[i].DeletedLines = st.deleted
+		files[i].Binary = st.binary
 	}
 }
 
@@ -472,6 +1506,32 @@ func parseNameStatus(out string) []FileChange {
 	return files
 }
 
+// parseBranchInfo parses for-each-ref output with null-byte separators.
+func parseBranchInfo(out string) []BranchInfo {
+	var branches []BranchInfo
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.SplitN(line, "\x00", 3)
+		if len(parts) < 3 || parts[0] == "" {
+			continue
+		}
+		branches = append(branches, BranchInfo{Name: parts[0], Subject: parts[1], Date: parts[2]})
+	}
+	return branches
+}
+
+// parseClean extracts paths from git clean output ("Would remove x" / "Removing x").
+func parseClean(out string) []string {
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if p, ok := strings.CutPrefix(line, "Would remove "); ok {
+			paths = append(paths, p)
+		} else if p, ok := strings.CutPrefix(line, "Removing "); ok {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
 // parseLog parses git log output with null-byte separators.
 func parseLog(out string) []Commit {
 	var commits []Commit
@@ -1,10 +1,16 @@
 package git
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 // gitEnv returns env vars that fully isolate git from host config.
@@ -150,15 +156,32 @@ func TestParseLog(t *testing.T) {
 	}
 }
 
+func TestParseBranchInfo(t *testing.T) {
+	t.Parallel()
+	got := parseBranchInfo("main\x00fix bug\x002 days ago\nfeat\x00\x00now\nbroken\n")
+	if len(got) != 2 {
+		t.Fatalf("len=%d, want 2: %v", len(got), got)
+	}
+	if got[0] != (BranchInfo{Name: "main", Subject: "fix bug", Date: "2 days ago"}) {
+		t.Errorf("got[0]=%+v", got[0])
+	}
+	if got[1].Name != "feat" || got[1].Subject != "" {
+		t.Errorf("got[1]=%+v", got[1])
+	}
+}
+
 func TestParseNumStat(t *testing.T) {
 	t.Parallel()
 	got := parseNumStat("12\t3\tfile.go\n-\t-\tbinary.dat\n5\t2\told/name.go => new/name.go\n7\t1\tsrc/{old => new}/name.go")
 	if got["file.go"].added != 12 || got["file.go"].deleted != 3 {
 		t.Fatalf("file.go stats mismatch: %+v", got["file.go"])
 	}
-	if got["binary.dat"].added != 0 || got["binary.dat"].deleted != 0 {
+	if got["binary.dat"].added != 0 || got["binary.dat"].deleted != 0 || !got["binary.dat"].binary {
 		t.Fatalf("binary stats mismatch: %+v", got["binary.dat"])
 	}
+	if got["file.go"].binary {
+		t.Fatal("file.go should not be binary")
+	}
 	if got["new/name.go"].added != 5 || got["new/name.go"].deleted != 2 {
 		t.Fatalf("rename stats mismatch: %+v", got["new/name.go"])
 	}
@@ -181,8 +204,70 @@ func TestNewRepo_NotGitDir(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 	_, err := NewRepo(dir)
-	if err == nil {
-		t.Error("expected error for non-git dir")
+	if !errors.Is(err, ErrNotRepository) {
+		t.Errorf("err = %v, want ErrNotRepository", err)
+	}
+}
+
+func TestNewRepo_LinkedWorktree(t *testing.T) {
+	t.Parallel()
+	main := setupTestRepo(t)
+	addCommit(t, main, "f.txt", "v1\n", "init")
+	wt := filepath.Join(t.TempDir(), "wt")
+	gitRun(t, main.Dir(), "worktree", "add", "-q", "-b", "side", wt)
+
+	repo, err := NewRepo(wt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.EvalSymlinks(wt); repo.Dir() != want {
+		t.Errorf("Dir() = %q, want the worktree %q", repo.Dir(), want)
+	}
+	if got, want := repo.LocalConfigPath(), main.LocalConfigPath(); got != want {
+		t.Errorf("LocalConfigPath() = %q, want the shared %q", got, want)
+	}
+	writeFile(t, repo, "f.txt", "v2\n")
+	if files, err := repo.ChangedFiles(false, ""); err != nil || len(files) != 1 {
+		t.Errorf("ChangedFiles in worktree = %+v, %v", files, err)
+	}
+}
+
+func TestNewRepo_Bare(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q", "--bare")
+	_, err := NewRepo(dir)
+	if err == nil || !strings.Contains(err.Error(), "bare repository") {
+		t.Errorf("err = %v, want a bare repository error", err)
+	}
+}
+
+// TestNewRepo_GitDirEnv covers the dotfiles setup: a bare repo used through
+// GIT_DIR with the home directory as GIT_WORK_TREE. It sets process env, so
+// it can't run in parallel.
+func TestNewRepo_GitDirEnv(t *testing.T) {
+	gitDir := t.TempDir()
+	gitRun(t, gitDir, "init", "-q", "--bare")
+	workTree, _ := filepath.EvalSymlinks(t.TempDir())
+	if err := os.WriteFile(filepath.Join(workTree, ".vimrc"), []byte("set nu\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GIT_DIR", gitDir)
+	if _, err := NewRepo(workTree); err == nil || !strings.Contains(err.Error(), "bare repository") {
+		t.Errorf("GIT_DIR without a work tree: err = %v, want a bare repository error", err)
+	}
+
+	t.Setenv("GIT_WORK_TREE", workTree)
+	repo, err := NewRepo(workTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Dir() != workTree {
+		t.Errorf("Dir() = %q, want %q", repo.Dir(), workTree)
+	}
+	if untracked, err := repo.UntrackedFiles(); err != nil || len(untracked) != 1 || untracked[0] != ".vimrc" {
+		t.Errorf("UntrackedFiles = %v, %v", untracked, err)
 	}
 }
 
@@ -298,6 +383,43 @@ func TestChangedFiles_Ref(t *testing.T) {
 	}
 }
 
+func TestChangedFiles_StagedRef(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "a.txt", "v1\n", "init")
+	addCommit(t, repo, "b.txt", "v1\n", "second")
+	writeFile(t, repo, "a.txt", "v1\nstaged\n")
+	if err := repo.StageFile("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo, "a.txt", "v1\nstaged\nunstaged\n")
+
+	files, err := repo.ChangedFiles(true, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Index vs HEAD~1: a.txt staged edit and b.txt from the second commit.
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %+v", files)
+	}
+	for _, f := range files {
+		if !f.Staged {
+			t.Errorf("%s should be marked staged", f.Path)
+		}
+		if f.Path == "a.txt" && f.AddedLines != 1 {
+			t.Errorf("a.txt AddedLines=%d, want 1 (unstaged line excluded)", f.AddedLines)
+		}
+	}
+
+	diff, err := repo.DiffFile("a.txt", true, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "+staged") || strings.Contains(diff, "+unstaged") {
+		t.Errorf("cached ref diff should contain only staged content:\n%s", diff)
+	}
+}
+
 func TestChangedFiles_StagedRenameWithEdits_HasStats(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
@@ -368,6 +490,18 @@ func TestStageFile(t *testing.T) {
 	}
 }
 
+func TestStageFile_ErrorIncludesStderr(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	err := repo.StageFile("missing.txt")
+	if err == nil {
+		t.Fatal("expected error staging a missing path")
+	}
+	if !strings.Contains(err.Error(), "missing.txt") {
+		t.Errorf("error should carry git's message, got %q", err)
+	}
+}
+
 func TestUnstageFile(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
@@ -404,6 +538,75 @@ func TestUnstageFile_NoCommits(t *testing.T) {
 	}
 }
 
+func TestNewRepoWithGit_InvalidBinary(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	tests := []struct {
+		name, bin, wantInErr string
+	}{
+		{"missing", "/nonexistent/git", "not found"},
+		{"not git", "true", "does not look like git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewRepoWithGit(repo.Dir(), tt.bin)
+			if err == nil || !strings.Contains(err.Error(), tt.wantInErr) {
+				t.Errorf("err = %v, want containing %q", err, tt.wantInErr)
+			}
+		})
+	}
+}
+
+func TestNewRepoWithGit_FullPath(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	bin, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not on PATH")
+	}
+	r, err := NewRepoWithGit(repo.Dir(), bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Dir() != repo.Dir() {
+		t.Errorf("Dir() = %q, want %q", r.Dir(), repo.Dir())
+	}
+}
+
+func TestNewRepo_FromSubdirectory(t *testing.T) {
+	t.Parallel()
+	root := setupTestRepo(t)
+	for _, d := range []string{"web/ui", "api"} {
+		if err := os.MkdirAll(filepath.Join(root.Dir(), d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addCommit(t, root, "api/server.go", "v1", "init")
+	writeFile(t, root, "api/server.go", "v2")
+
+	repo, err := NewRepo(filepath.Join(root.Dir(), "web", "ui"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Dir() != root.Dir() {
+		t.Errorf("Dir() = %q, want repo root %q", repo.Dir(), root.Dir())
+	}
+	files, err := repo.ChangedFiles(false, "")
+	if err != nil || len(files) != 1 || files[0].Path != "api/server.go" {
+		t.Fatalf("ChangedFiles = %+v, %v; want api/server.go", files, err)
+	}
+	if content, err := repo.ReadFileContent(files[0].Path); err != nil || content != "v2" {
+		t.Errorf("ReadFileContent = %q, %v; want v2", content, err)
+	}
+	if got, want := repo.AbsPath("api/server.go"), filepath.Join(root.Dir(), "api", "server.go"); got != want {
+		t.Errorf("AbsPath = %q, want %q", got, want)
+	}
+	if got, want := repo.RelPath("api/server.go"), filepath.Join("..", "..", "api", "server.go"); got != want {
+		t.Errorf("RelPath = %q, want %q", got, want)
+	}
+}
+
 func TestStageAll(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
@@ -420,6 +623,140 @@ func TestStageAll(t *testing.T) {
 	}
 }
 
+func TestUnstageAll(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		firstHead bool
+	}{
+		{"with_commits", true},
+		{"before_first_commit", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			repo := setupTestRepo(t)
+			if tt.firstHead {
+				addCommit(t, repo, "f.txt", "v1", "init")
+				writeFile(t, repo, "f.txt", "v2")
+			}
+			writeFile(t, repo, "a.txt", "a")
+			writeFile(t, repo, "b.txt", "b")
+			if err := repo.StageAll(); err != nil {
+				t.Fatal(err)
+			}
+			if files, _ := repo.ChangedFiles(true, ""); len(files) < 2 {
+				t.Fatalf("expected staged files before unstaging, got %d", len(files))
+			}
+
+			if err := repo.UnstageAll(); err != nil {
+				t.Fatal(err)
+			}
+			if files, _ := repo.ChangedFiles(true, ""); len(files) != 0 {
+				t.Errorf("expected 0 staged files, got %d", len(files))
+			}
+			untracked, _ := repo.UntrackedFiles()
+			if len(untracked) != 2 {
+				t.Errorf("unstaged new files should be untracked again, got %v", untracked)
+			}
+			if err := repo.UnstageAll(); err != nil {
+				t.Errorf("unstaging an empty index: %v", err)
+			}
+		})
+	}
+}
+
+func TestStageTracked_SkipsUntracked(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	writeFile(t, repo, "f.txt", "v2")
+	writeFile(t, repo, "new.txt", "n")
+
+	if err := repo.StageTracked(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := repo.ChangedFiles(true, "")
+	if len(files) != 1 || files[0].Path != "f.txt" {
+		t.Errorf("staged = %+v, want only f.txt", files)
+	}
+	if untracked, _ := repo.UntrackedFiles(); len(untracked) != 1 {
+		t.Errorf("untracked = %v, want new.txt left alone", untracked)
+	}
+}
+
+func TestStageFiles_UnstageFiles(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "a.txt", "a", "init")
+	addCommit(t, repo, "b.txt", "b", "second")
+	writeFile(t, repo, "a.txt", "a2")
+	writeFile(t, repo, "b.txt", "b2")
+	writeFile(t, repo, "c.txt", "c")
+
+	if err := repo.StageFiles("a.txt", "b.txt", "c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := repo.ChangedFiles(true, ""); len(files) != 3 {
+		t.Fatalf("staged %d files, want 3", len(files))
+	}
+	if err := repo.UnstageFiles("a.txt", "c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := repo.ChangedFiles(true, "")
+	if len(files) != 1 || files[0].Path != "b.txt" {
+		t.Errorf("staged = %+v, want only b.txt", files)
+	}
+}
+
+func TestClean(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, ".gitignore", "build.log\n", "init")
+	writeFile(t, repo, "junk.txt", "x")
+	writeFile(t, repo, "build.log", "ignored")
+	if err := os.Mkdir(filepath.Join(repo.Dir(), "out"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo, "out/a.o", "obj")
+
+	preview, err := repo.Clean(true, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview) != 2 || preview[0] != "junk.txt" || preview[1] != "out/" {
+		t.Errorf("preview=%v, want [junk.txt out/]", preview)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Dir(), "junk.txt")); err != nil {
+		t.Error("dry run should not delete files")
+	}
+
+	withIgnored, _ := repo.Clean(true, true, true)
+	if len(withIgnored) != 3 {
+		t.Errorf("with ignored=%v, want 3 paths", withIgnored)
+	}
+
+	// A file created after the preview is not in it and survives.
+	writeFile(t, repo, "late.txt", "x")
+	removed, err := repo.Clean(false, true, false, preview...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("removed=%v, want 2 paths", removed)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Dir(), "late.txt")); err != nil {
+		t.Error("file not in the preview should survive clean")
+	}
+	os.Remove(filepath.Join(repo.Dir(), "late.txt"))
+	if _, err := os.Stat(filepath.Join(repo.Dir(), "build.log")); err != nil {
+		t.Error("ignored file should survive clean without ignored flag")
+	}
+	if untracked, _ := repo.UntrackedFiles(); len(untracked) != 0 {
+		t.Errorf("untracked after clean=%v, want none", untracked)
+	}
+}
+
 func TestDiffFile(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
@@ -443,14 +780,43 @@ func TestCommit(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := repo.Commit("test commit"); err != nil {
+	hash, err := repo.Commit("test commit")
+	if err != nil {
 		t.Fatal(err)
 	}
+	if commits, _ := repo.Log(1); len(commits) != 1 || commits[0].Short != hash {
+		t.Errorf("Commit returned %q, want HEAD short hash %v", hash, commits)
+	}
 	if !repo.HasCommits() {
 		t.Error("should have commits after Commit()")
 	}
 }
 
+func TestCommit_HookOutput(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	hook := "#!/bin/sh\necho 'lint: run make fmt'\necho 'hook failed' >&2\nexit 1\n"
+	hooksDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(hooksDir, "pre-commit"), []byte(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, repo.Dir(), "config", "core.hooksPath", hooksDir)
+	writeFile(t, repo, "f.txt", "hello")
+	if err := repo.StageFile("f.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := repo.Commit("blocked")
+	if err == nil {
+		t.Fatal("expected hook failure")
+	}
+	for _, want := range []string{"lint: run make fmt", "hook failed"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err.Error(), want)
+		}
+	}
+}
+
 func TestReadFileContent(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
@@ -483,91 +849,519 @@ func TestLog(t *testing.T) {
 	}
 }
 
-func TestCommitDiff(t *testing.T) {
+func TestLogArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		opts LogOptions
+		want []string
+	}{
+		{"defaults", LogOptions{}, []string{"-100"}},
+		{"max", LogOptions{Max: 5}, []string{"-5"}},
+		{"filters", LogOptions{Since: "1 week ago", Until: "yesterday", Author: "alice"},
+			[]string{"-100", "--since=1 week ago", "--until=yesterday", "--author=alice"}},
+		{"first parent", LogOptions{FirstParent: true, NoMerges: true}, []string{"-100", "--first-parent", "--no-merges"}},
+		{"merges", LogOptions{Merges: true}, []string{"-100", "--merges"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := logArgs(tt.opts)
+			// skip "log" and the --format arg
+			rest := append([]string{got[1]}, got[3:]...)
+			if strings.Join(rest, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("logArgs(%+v)=%v, want %v", tt.opts, rest, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogFiltered_Author(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
-	addCommit(t, repo, "f.txt", "v1", "init")
-	addCommit(t, repo, "f.txt", "v2", "update")
+	addCommit(t, repo, "f.txt", "v1", "by test")
+	writeFile(t, repo, "f.txt", "v2")
+	gitRun(t, repo.Dir(), "add", "f.txt")
+	gitRun(t, repo.Dir(), "commit", "--author=alice <alice@example.com>", "-m", "by alice")
 
-	commits, _ := repo.Log(1)
-	diff, err := repo.CommitDiff(commits[0].Hash)
+	commits, err := repo.LogFiltered(LogOptions{Author: "alice"})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if diff == "" {
-		t.Error("expected non-empty commit diff")
+	if len(commits) != 1 || commits[0].Subject != "by alice" {
+		t.Errorf("author filter got %+v", commits)
 	}
 }
 
-func TestListBranches(t *testing.T) {
+func TestLogFiltered_FirstParentAndMerges(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
 	addCommit(t, repo, "f.txt", "v1", "init")
-	gitRun(t, repo.Dir(), "branch", "feature-a")
-	gitRun(t, repo.Dir(), "branch", "feature-b")
+	gitRun(t, repo.Dir(), "checkout", "-q", "-b", "feature")
+	addCommit(t, repo, "g.txt", "g", "feature work")
+	gitRun(t, repo.Dir(), "checkout", "-q", "-")
+	gitRun(t, repo.Dir(), "merge", "-q", "--no-ff", "-m", "merge feature", "feature")
 
-	branches, err := repo.ListBranches()
-	if err != nil {
-		t.Fatal(err)
+	subjects := func(opts LogOptions) string {
+		t.Helper()
+		commits, err := repo.LogFiltered(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s []string
+		for _, c := range commits {
+			s = append(s, c.Subject)
+		}
+		return strings.Join(s, ",")
 	}
-	if len(branches) != 3 {
-		t.Fatalf("expected 3 branches, got %d: %v", len(branches), branches)
+	if got := subjects(LogOptions{FirstParent: true}); got != "merge feature,init" {
+		t.Errorf("first-parent = %q", got)
+	}
+	if got := subjects(LogOptions{Merges: true}); got != "merge feature" {
+		t.Errorf("merges = %q", got)
+	}
+	if got := subjects(LogOptions{NoMerges: true}); strings.Contains(got, "merge feature") {
+		t.Errorf("no-merges = %q", got)
 	}
 }
 
-func TestListBranches_NoCommits(t *testing.T) {
+func TestCommitFixup_RebaseAutosquash(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
+	addCommit(t, repo, "a.txt", "a1", "add a")
+	addCommit(t, repo, "b.txt", "b1", "add b")
+	commits, _ := repo.Log(10)
+	target := commits[1].Hash // "add a"
 
-	branches, err := repo.ListBranches()
-	if err != nil {
+	if _, err := repo.CommitFixup(target); err == nil {
+		t.Error("fixup with nothing staged should fail")
+	}
+	writeFile(t, repo, "a.txt", "a2")
+	gitRun(t, repo.Dir(), "add", "a.txt")
+	if _, err := repo.CommitFixup(target); err != nil {
 		t.Fatal(err)
 	}
-	if len(branches) != 0 {
-		t.Errorf("expected 0 branches, got %d", len(branches))
+	commits, _ = repo.Log(10)
+	if commits[0].Subject != "fixup! add a" {
+		t.Fatalf("fixup subject = %q", commits[0].Subject)
 	}
-}
 
-func TestCheckoutBranch(t *testing.T) {
-	t.Parallel()
-	repo := setupTestRepo(t)
-	addCommit(t, repo, "f.txt", "v1", "init")
-	gitRun(t, repo.Dir(), "branch", "other")
+	writeFile(t, repo, "b.txt", "dirty")
+	if err := repo.RebaseAutosquash(target); err == nil {
+		t.Error("autosquash on a dirty tree should fail")
+	}
+	gitRun(t, repo.Dir(), "checkout", "--", "b.txt")
 
-	if err := repo.CheckoutBranch("other"); err != nil {
+	if err := repo.RebaseAutosquash(target); err != nil {
 		t.Fatal(err)
 	}
-	if got := repo.BranchName(); got != "other" {
-		t.Errorf("branch=%q, want %q", got, "other")
+	commits, _ = repo.Log(10)
+	if len(commits) != 2 || commits[1].Subject != "add a" {
+		t.Errorf("after autosquash got %+v", commits)
+	}
+	if repo.RebaseInProgress() {
+		t.Error("no rebase should be in progress")
 	}
 }
 
-func TestCheckoutBranch_Dirty(t *testing.T) {
+func TestRebaseAutosquash_Conflict(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
-	addCommit(t, repo, "f.txt", "v1", "init")
-	// Create branch with different content for the same file
-	gitRun(t, repo.Dir(), "branch", "other")
-	gitRun(t, repo.Dir(), "checkout", "other")
-	addCommit(t, repo, "f.txt", "v2-other", "other change")
-	gitRun(t, repo.Dir(), "checkout", "-")
-	// Now dirty the file so switch conflicts
-	writeFile(t, repo, "f.txt", "dirty")
+	addCommit(t, repo, "a.txt", "a1", "add a")
+	addCommit(t, repo, "a.txt", "a2", "edit a")
+	commits, _ := repo.Log(10)
+	target := commits[1].Hash // "add a"
+	writeFile(t, repo, "a.txt", "a3")
+	gitRun(t, repo.Dir(), "add", "a.txt")
+	if _, err := repo.CommitFixup(target); err != nil {
+		t.Fatal(err)
+	}
 
-	err := repo.CheckoutBranch("other")
-	if err == nil {
-		t.Error("expected error switching with dirty worktree")
+	if err := repo.RebaseAutosquash(target); err == nil {
+		t.Fatal("expected conflict")
+	}
+	if !repo.RebaseInProgress() {
+		t.Error("conflicted rebase should be in progress")
 	}
 }
 
-func TestCommitDiffFiles(t *testing.T) {
+func TestChangedFiles_AgainstRootParent(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
-	addCommit(t, repo, "f.txt", "v1", "init")
-	addCommit(t, repo, "f.txt", "v2", "update")
+	addCommit(t, repo, "a.txt", "hello\n", "root")
 
-	commits, _ := repo.Log(1)
-	files, err := repo.CommitDiffFiles(commits[0].Hash)
+	for _, ref := range []string{"HEAD~1", "HEAD^", "HEAD~"} {
+		files, err := repo.ChangedFiles(false, ref)
+		if err != nil {
+			t.Fatalf("%s: %v", ref, err)
+		}
+		if len(files) != 1 || files[0].Path != "a.txt" || files[0].Status != StatusAdded {
+			t.Errorf("%s: files = %+v, want a.txt added", ref, files)
+		}
+	}
+	diff, err := repo.DiffFile("a.txt", true, "HEAD~1")
+	if err != nil || !strings.Contains(diff, "+hello") {
+		t.Errorf("DiffFile = %q, %v; want the whole file added", diff, err)
+	}
+
+	commits, _ := repo.Log(1)
+	files, err := repo.CommitDiffFiles(commits[0].Hash)
+	if err != nil || len(files) != 1 {
+		t.Errorf("CommitDiffFiles(root) = %+v, %v", files, err)
+	}
+
+	if _, err := repo.ChangedFiles(false, "HEAD~2"); err == nil {
+		t.Error("a ref beyond the root should still fail")
+	}
+}
+
+func TestLastFetchTime(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	if got := repo.LastFetchTime(); !got.IsZero() {
+		t.Errorf("LastFetchTime before any fetch = %v, want zero", got)
+	}
+
+	fetched := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	path := filepath.Join(repo.Dir(), ".git", "FETCH_HEAD")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, fetched, fetched); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.LastFetchTime(); !got.Equal(fetched) {
+		t.Errorf("LastFetchTime = %v, want %v", got, fetched)
+	}
+}
+
+func TestWebURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		remote, want string
+		wantErr      bool
+	}{
+		{"git@github.com:org/repo.git", "https://github.com/org/repo", false},
+		{"github.com:org/repo", "https://github.com/org/repo", false},
+		{"ssh://git@gitlab.com:2222/group/sub/repo.git", "https://gitlab.com/group/sub/repo", false},
+		{"https://user@github.com/org/repo.git", "https://github.com/org/repo", false},
+		{"/tmp/bare", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := WebURL(tt.remote)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("WebURL(%q)=%q, %v; want %q, wantErr=%v", tt.remote, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCompareURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		web, branch, want string
+		wantErr           bool
+	}{
+		{"https://github.com/org/repo", "feat/x", "https://github.com/org/repo/compare/feat/x?expand=1", false},
+		{"https://github.com/org/repo", "fix/#12?a&b", "https://github.com/org/repo/compare/fix/%2312%3Fa&b?expand=1", false},
+		{"https://gitlab.com/g/repo", "feat/x", "https://gitlab.com/g/repo/-/merge_requests/new?merge_request%5Bsource_branch%5D=feat%2Fx", false},
+		{"https://example.com/org/repo", "main", "", true},
+	}
+	for _, tt := range tests {
+		got, err := CompareURL(tt.web, tt.branch)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("CompareURL(%q, %q)=%q, %v; want %q", tt.web, tt.branch, got, err, tt.want)
+		}
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "first")
+	addCommit(t, repo, "f.txt", "v2", "second")
+	commits, _ := repo.Log(2)
+
+	tests := []struct {
+		ref, want string
+		wantErr   bool
+	}{
+		{commits[1].Hash[:7], commits[1].Hash, false},
+		{"HEAD", commits[0].Hash, false},
+		{"HEAD~1", commits[1].Hash, false},
+		{"deadbeefdead", "", true},
+		{"--all", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := repo.ResolveRef(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ResolveRef(%q) err=%v, wantErr=%v", tt.ref, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ResolveRef(%q)=%q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestShowBlob(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "bin.dat", "a\x00b", "init")
+	writeFile(t, repo, "bin.dat", "a\x00c")
+	if err := repo.StageFile("bin.dat"); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := repo.ShowBlob("HEAD", "bin.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(head) != "a\x00b" {
+		t.Errorf("HEAD blob=%q", head)
+	}
+	index, err := repo.ShowBlob("", "bin.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(index) != "a\x00c" {
+		t.Errorf("index blob=%q", index)
+	}
+	if _, err := repo.ShowBlob("HEAD", "missing.dat"); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
+
+func TestCommitDiff(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	addCommit(t, repo, "f.txt", "v2", "update")
+
+	commits, _ := repo.Log(1)
+	diff, err := repo.CommitDiff(commits[0].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == "" {
+		t.Error("expected non-empty commit diff")
+	}
+}
+
+func TestRangeDiff(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "a.txt", "v1", "init")
+	addCommit(t, repo, "a.txt", "v2", "second")
+	addCommit(t, repo, "b.txt", "new", "third")
+
+	commits, _ := repo.Log(3)
+	diff, err := repo.RangeDiff(commits[2].Hash, commits[0].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"a/a.txt", "+v2", "b/b.txt", "+new"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("range diff missing %q:\n%s", want, diff)
+		}
+	}
+	if strings.Contains(diff, "+v1") {
+		t.Errorf("range diff should start after the first commit:\n%s", diff)
+	}
+}
+
+func TestListBranches(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	gitRun(t, repo.Dir(), "branch", "feature-a")
+	gitRun(t, repo.Dir(), "branch", "feature-b")
+
+	branches, err := repo.ListBranches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 3 {
+		t.Fatalf("expected 3 branches, got %d: %v", len(branches), branches)
+	}
+}
+
+func TestListBranches_NoCommits(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+
+	branches, err := repo.ListBranches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 0 {
+		t.Errorf("expected 0 branches, got %d", len(branches))
+	}
+}
+
+func TestListBranchesDetailed(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "first subject")
+	gitRun(t, repo.Dir(), "branch", "feature-a")
+
+	branches, err := repo.ListBranchesDetailed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 2 {
+		t.Fatalf("expected 2 branches, got %v", branches)
+	}
+	for _, b := range branches {
+		if b.Subject != "first subject" || b.Date == "" {
+			t.Errorf("branch %+v missing commit info", b)
+		}
+	}
+}
+
+func TestMergedBranches(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	gitRun(t, repo.Dir(), "branch", "merged")
+	gitRun(t, repo.Dir(), "checkout", "-b", "unmerged")
+	addCommit(t, repo, "g.txt", "v1", "ahead")
+	gitRun(t, repo.Dir(), "checkout", "-")
+
+	merged, err := repo.MergedBranches("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]bool{}
+	for _, b := range merged {
+		set[b] = true
+	}
+	if !set["merged"] {
+		t.Errorf("expected merged branch in %v", merged)
+	}
+	if set["unmerged"] {
+		t.Errorf("unmerged branch should not be listed: %v", merged)
+	}
+}
+
+func TestCheckoutBranch(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	gitRun(t, repo.Dir(), "branch", "other")
+
+	if err := repo.CheckoutBranch("other"); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.BranchName(); got != "other" {
+		t.Errorf("branch=%q, want %q", got, "other")
+	}
+}
+
+func TestDiffToolCmd(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	tests := []struct {
+		name   string
+		cmd    *exec.Cmd
+		suffix []string
+	}{
+		{"default_tool", repo.DiffToolCmd("a.go", false, "", ""), []string{"difftool", "--no-prompt", "--", "a.go"}},
+		{"staged_with_tool", repo.DiffToolCmd("a.go", true, "", "meld"), []string{"difftool", "--no-prompt", "--tool=meld", "--cached", "--", "a.go"}},
+		{"ref", repo.DiffToolCmd("a.go", false, "main", ""), []string{"difftool", "--no-prompt", "main", "--", "a.go"}},
+		{"mergetool", repo.MergeToolCmd("a.go", "vimdiff"), []string{"mergetool", "--no-prompt", "--tool=vimdiff", "--", "a.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := tt.cmd.Args[1:]
+			if !reflect.DeepEqual(got, tt.suffix) {
+				t.Errorf("args = %q, want %q", got, tt.suffix)
+			}
+			if tt.cmd.Dir != repo.Dir() {
+				t.Errorf("dir = %q, want %q", tt.cmd.Dir, repo.Dir())
+			}
+		})
+	}
+}
+
+func TestIsDirtyAndStash(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	writeFile(t, repo, "new.txt", "untracked")
+	if repo.IsDirty() {
+		t.Error("untracked files alone should not count as dirty")
+	}
+	writeFile(t, repo, "f.txt", "v2")
+	if !repo.IsDirty() {
+		t.Fatal("modified tracked file should be dirty")
+	}
+	if err := repo.Stash("test stash"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.IsDirty() {
+		t.Error("tree should be clean after stashing")
+	}
+	out, err := repo.run("stash", "list")
+	if err != nil || !strings.Contains(out, "test stash") {
+		t.Errorf("stash list = %q, %v", out, err)
+	}
+}
+
+func TestCheckoutDetached(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	addCommit(t, repo, "f.txt", "v2", "second")
+
+	if name, detached := repo.HeadName(); detached || name == "" {
+		t.Fatalf("HeadName = %q, %v; want a branch", name, detached)
+	}
+	if err := repo.CheckoutDetached("HEAD~1"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := repo.run("rev-parse", "--short", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.TrimSpace(out)
+	name, detached := repo.HeadName()
+	if !detached || name != want {
+		t.Errorf("HeadName = %q, %v; want %q, true", name, detached, want)
+	}
+	if err := repo.CheckoutDetached("no-such-ref"); err == nil {
+		t.Error("expected error for unknown ref")
+	}
+}
+
+func TestCheckoutBranch_Dirty(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	// Create branch with different content for the same file
+	gitRun(t, repo.Dir(), "branch", "other")
+	gitRun(t, repo.Dir(), "checkout", "other")
+	addCommit(t, repo, "f.txt", "v2-other", "other change")
+	gitRun(t, repo.Dir(), "checkout", "-")
+	// Now dirty the file so switch conflicts
+	writeFile(t, repo, "f.txt", "dirty")
+
+	err := repo.CheckoutBranch("other")
+	if err == nil {
+		t.Error("expected error switching with dirty worktree")
+	}
+}
+
+func TestCommitDiffFiles(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1", "init")
+	addCommit(t, repo, "f.txt", "v2", "update")
+
+	commits, _ := repo.Log(1)
+	files, err := repo.CommitDiffFiles(commits[0].Hash)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -579,6 +1373,37 @@ func TestCommitDiffFiles(t *testing.T) {
 	}
 }
 
+func TestCommitRef(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "a.txt", "a1\n", "root")
+	addCommit(t, repo, "b.txt", "b1\n", "second")
+	writeFile(t, repo, "b.txt", "dirty\n")
+	commits, _ := repo.Log(2)
+	second, root := commits[0].Hash, commits[1].Hash
+
+	secondRef, err := repo.CommitRef(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := repo.ChangedFiles(false, secondRef)
+	if err != nil || len(files) != 1 || files[0].Path != "b.txt" || files[0].AddedLines != 1 {
+		t.Fatalf("files = %+v, %v; want b.txt with +1", files, err)
+	}
+	diff, err := repo.DiffFile("b.txt", false, secondRef)
+	if err != nil || !strings.Contains(diff, "+b1") || strings.Contains(diff, "dirty") {
+		t.Errorf("commit diff should ignore the working tree: %v\n%s", err, diff)
+	}
+	rootRef, err := repo.CommitRef(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err = repo.ChangedFiles(false, rootRef)
+	if err != nil || len(files) != 1 || files[0].Path != "a.txt" {
+		t.Errorf("root commit files = %+v, %v", files, err)
+	}
+}
+
 func TestCreateBranch(t *testing.T) {
 	t.Parallel()
 	repo := setupTestRepo(t)
@@ -662,4 +1487,497 @@ func TestPushSetUpstream(t *testing.T) {
 	if info.Upstream == "" {
 		t.Error("upstream should be configured after PushSetUpstream")
 	}
+	if info.URL != bare {
+		t.Errorf("upstream URL=%q, want %q", info.URL, bare)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+	upstream := setupTestRepo(t)
+	addCommit(t, upstream, "f.txt", "v1", "init")
+
+	repo := setupTestRepo(t)
+	if err := repo.Fetch("origin"); !errors.Is(err, ErrNoRemote) {
+		t.Fatalf("Fetch without origin = %v, want ErrNoRemote", err)
+	}
+	gitRun(t, repo.Dir(), "remote", "add", "origin", upstream.Dir())
+	if err := repo.Fetch("origin"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.LastFetchTime().IsZero() {
+		t.Error("LastFetchTime should be set after Fetch")
+	}
+	gitRun(t, repo.Dir(), "remote", "set-url", "origin", filepath.Join(t.TempDir(), "missing"))
+	if err := repo.Fetch("origin"); err == nil || errors.Is(err, ErrNoRemote) {
+		t.Errorf("unreachable origin: err = %v, want a fetch error", err)
+	}
+
+	// A remote that never answers is given up on.
+	gitRun(t, repo.Dir(), "config", "protocol.ext.allow", "always")
+	gitRun(t, repo.Dir(), "remote", "set-url", "origin", "ext::sleep 5")
+	start := time.Now()
+	if err := repo.fetch("origin", 200*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("hanging origin: err = %v, want a timeout", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("timed out fetch took %s", d)
+	}
+}
+
+func TestFetchEnv_KeepsUserSSHCommand(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	batch := "GIT_SSH_COMMAND=ssh -o BatchMode=yes"
+	if os.Getenv("GIT_SSH_COMMAND") == "" && os.Getenv("GIT_SSH") == "" {
+		if env := repo.fetchEnv(); !slices.Contains(env, batch) {
+			t.Errorf("no ssh command configured: env = %v, want BatchMode", env)
+		}
+	}
+	gitRun(t, repo.Dir(), "config", "core.sshCommand", "ssh -i ~/.ssh/work")
+	env := repo.fetchEnv()
+	if slices.Contains(env, batch) {
+		t.Errorf("core.sshCommand set: env = %v, should not override it", env)
+	}
+	if !slices.Contains(env, "GIT_TERMINAL_PROMPT=0") {
+		t.Errorf("env = %v, want GIT_TERMINAL_PROMPT=0", env)
+	}
+}
+
+func TestChangedFilesRange(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "base.txt", "v1\n", "init")
+	gitRun(t, repo.Dir(), "branch", "base")
+	addCommit(t, repo, "feature.txt", "f\n", "feature")
+	gitRun(t, repo.Dir(), "checkout", "-q", "base")
+	addCommit(t, repo, "base.txt", "v2\n", "base moves on")
+	gitRun(t, repo.Dir(), "checkout", "-q", "-")
+	writeFile(t, repo, "feature.txt", "dirty\n")
+
+	// Only the branch's commits: not base's later work, not the working tree.
+	files, err := repo.ChangedFilesRange("base", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "feature.txt" || files[0].Staged {
+		t.Fatalf("files = %+v, want feature.txt only", files)
+	}
+	diff, err := repo.DiffFile("feature.txt", false, RangeRef("base", "HEAD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "+f") || strings.Contains(diff, "dirty") {
+		t.Errorf("range diff should show the committed change only:\n%s", diff)
+	}
+}
+
+func TestChangedFiles_MergeBaseRef(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "base.txt", "v1\n", "init")
+	gitRun(t, repo.Dir(), "branch", "main-line")
+	addCommit(t, repo, "feature.txt", "f\n", "feature")
+	gitRun(t, repo.Dir(), "checkout", "-q", "main-line")
+	addCommit(t, repo, "base.txt", "v2\n", "main moves on")
+	gitRun(t, repo.Dir(), "checkout", "-q", "-")
+	writeFile(t, repo, "feature.txt", "dirty\n")
+
+	paths := func(ref string) []string {
+		t.Helper()
+		files, err := repo.ChangedFiles(false, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, f := range files {
+			out = append(out, f.Path)
+		}
+		return out
+	}
+	// Two-dot: the tip's later work shows up as a (reverse) change.
+	if got := paths("main-line"); !reflect.DeepEqual(got, []string{"base.txt", "feature.txt"}) {
+		t.Errorf("tip comparison = %v, want base.txt and feature.txt", got)
+	}
+	// Three-dot: only the branch's own changes, working tree included.
+	if got := paths(MergeBaseRef("main-line")); !reflect.DeepEqual(got, []string{"feature.txt"}) {
+		t.Errorf("merge-base comparison = %v, want feature.txt only", got)
+	}
+	diff, err := repo.DiffFile("feature.txt", false, MergeBaseRef("main-line"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "+dirty") {
+		t.Errorf("merge-base diff should include the working tree:\n%s", diff)
+	}
+}
+
+func TestDiffFile_WordDiff(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "doc.txt", "the quick fox\n", "init")
+	writeFile(t, repo, "doc.txt", "the slow fox\n")
+
+	plain, err := repo.DiffFile("doc.txt", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plain, "-the quick fox") || strings.Contains(plain, "[-") {
+		t.Errorf("line diff:\n%s", plain)
+	}
+	words := repo.WithWordDiff(true)
+	if !words.WordDiff() || repo.WordDiff() {
+		t.Fatal("WithWordDiff should only change the copy")
+	}
+	diff, err := words.DiffFile("doc.txt", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "the [-quick-]{+slow+} fox") {
+		t.Errorf("word diff:\n%s", diff)
+	}
+	gitRun(t, repo.Dir(), "add", "doc.txt")
+	staged, err := words.StagedDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(staged, "+the slow fox") || strings.Contains(staged, "{+") {
+		t.Errorf("staged diff should stay line-based:\n%s", staged)
+	}
+}
+
+func TestBlameOld(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "a\nb\nc\n", "first")
+	addCommit(t, repo, "f.txt", "a\nB\nc\n", "second")
+
+	summary := func(staged bool, ref string) string {
+		t.Helper()
+		b, err := repo.BlameOld("f.txt", staged, ref, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Uncommitted() {
+			return "uncommitted"
+		}
+		if b.Author != "test" || b.Time.IsZero() {
+			t.Errorf("author=%q time=%v", b.Author, b.Time)
+		}
+		return b.Summary
+	}
+	writeFile(t, repo, "f.txt", "a\nc\n")
+	if got := summary(false, ""); got != "second" {
+		t.Errorf("unstaged removal: %q, want second", got)
+	}
+	if got := summary(false, "HEAD~1"); got != "first" {
+		t.Errorf("against HEAD~1: %q, want first", got)
+	}
+	gitRun(t, repo.Dir(), "add", "f.txt")
+	if got := summary(true, ""); got != "second" {
+		t.Errorf("staged removal: %q, want second", got)
+	}
+	// The index differs from HEAD: a line staged but not committed.
+	writeFile(t, repo, "f.txt", "a\nX\nc\n")
+	gitRun(t, repo.Dir(), "add", "f.txt")
+	writeFile(t, repo, "f.txt", "a\nc\n")
+	if got := summary(false, ""); got != "uncommitted" {
+		t.Errorf("staged-only line: %q, want uncommitted", got)
+	}
+}
+
+func TestStageHunk(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+	base := strings.Join(lines, "\n") + "\n"
+	addCommit(t, repo, "f.txt", base, "init")
+	// A mixed hunk near the top and an added-only hunk at the end.
+	writeFile(t, repo, "f.txt", strings.Replace(base, "2\n", "two\n", 1)+"21\n22\n")
+
+	hunks := func(staged bool) []ParsedHunk {
+		t.Helper()
+		raw, err := repo.DiffFile("f.txt", staged, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ParseHunks(raw)
+	}
+	unstaged := hunks(false)
+	if len(unstaged) != 2 {
+		t.Fatalf("unstaged hunks = %d, want 2", len(unstaged))
+	}
+	if err := repo.StageHunk("f.txt", unstaged[1]); err != nil {
+		t.Fatal(err)
+	}
+	staged := hunks(true)
+	if len(staged) != 1 || !reflect.DeepEqual(staged[0].Lines[len(staged[0].Lines)-2:], []string{"+21", "+22"}) {
+		t.Fatalf("after staging the added hunk, staged = %+v", staged)
+	}
+	if left := hunks(false); len(left) != 1 || !strings.Contains(strings.Join(left[0].Lines, "\n"), "+two") {
+		t.Fatalf("after staging the added hunk, unstaged = %+v", left)
+	}
+
+	if err := repo.StageHunk("f.txt", hunks(false)[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(hunks(false)); got != 0 {
+		t.Errorf("unstaged hunks after staging both = %d, want 0", got)
+	}
+	if err := repo.UnstageHunk("f.txt", hunks(true)[0]); err != nil {
+		t.Fatal(err)
+	}
+	if left := hunks(false); len(left) != 1 || !strings.Contains(strings.Join(left[0].Lines, "\n"), "+two") {
+		t.Errorf("after unstaging the mixed hunk, unstaged = %+v", left)
+	}
+
+	// A file that was empty has no context to anchor the hunk.
+	addCommit(t, repo, "empty.txt", "", "empty")
+	writeFile(t, repo, "empty.txt", "a\nb\n")
+	raw, err := repo.DiffFile("empty.txt", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.StageHunk("empty.txt", ParseHunks(raw)[0]); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := repo.DiffFile("empty.txt", false, ""); raw != "" {
+		t.Errorf("empty.txt still has unstaged changes:\n%s", raw)
+	}
+}
+
+func TestPartialHunk(t *testing.T) {
+	t.Parallel()
+	h := ParsedHunk{Header: "@@ -3,4 +3,4 @@ func f()", Lines: []string{" a", "-b", "-c", "+B", "+C", " d", `\ No newline at end of file`}}
+	// pick selects removed lines by old number and added lines by new.
+	pick := func(removed, added int) func(bool, int) bool {
+		return func(rm bool, num int) bool {
+			if rm {
+				return num == removed
+			}
+			return num == added
+		}
+	}
+	tests := []struct {
+		name    string
+		keep    func(bool, int) bool
+		reverse bool
+		want    ParsedHunk
+		ok      bool
+	}{
+		{"one_removal", pick(5, 0), false, ParsedHunk{Header: "@@ -3,4 +3,3 @@", Lines: []string{" a", " b", "-c", " d", `\ No newline at end of file`}}, true},
+		{"one_addition", pick(0, 4), false, ParsedHunk{Header: "@@ -3,4 +3,5 @@", Lines: []string{" a", " b", " c", "+B", " d", `\ No newline at end of file`}}, true},
+		{"unstage_one_addition", pick(0, 5), true, ParsedHunk{Header: "@@ -3,4 +3,3 @@", Lines: []string{" a", " B", "-C", " d", `\ No newline at end of file`}}, true},
+		{"unstage_one_removal", pick(4, 0), true, ParsedHunk{Header: "@@ -3,4 +3,5 @@", Lines: []string{" a", "+b", " B", " C", " d", `\ No newline at end of file`}}, true},
+		{"context_only", pick(0, 0), false, ParsedHunk{Header: "@@ -3,4 +3,4 @@", Lines: []string{" a", " b", " c", " d", `\ No newline at end of file`}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := PartialHunk(h, tt.keep, tt.reverse)
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PartialHunk = %+v, %v\nwant %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestApplyPartialPatch(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	base := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	addCommit(t, repo, "f.txt", base, "init")
+	// Two hunks: 2 -> two (mixed) at the top, 11 and 12 appended to at the end.
+	writeFile(t, repo, "f.txt", strings.Replace(base, "2\n", "two\n", 1)+"13\n14\n")
+	raw, err := repo.DiffFile("f.txt", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pick the addition of "two" (new line 2) and of "14" (new line 14),
+	// across both hunks; each hunk becomes its own patch.
+	keep := func(removed bool, num int) bool { return !removed && (num == 2 || num == 14) }
+	var hunks []ParsedHunk
+	for _, h := range ParseHunks(raw) {
+		if p, ok := PartialHunk(h, keep, false); ok {
+			hunks = append(hunks, p)
+		}
+	}
+	if len(hunks) != 2 {
+		t.Fatalf("partial hunks = %d, want 2", len(hunks))
+	}
+	for i := len(hunks) - 1; i >= 0; i-- {
+		if err := repo.ApplyPartialPatch(FilePatch("f.txt", hunks[i]), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index := func() string {
+		t.Helper()
+		out, err := repo.ShowBlob("", "f.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+	if got := index(); got != "1\n2\ntwo\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n14\n" {
+		t.Errorf("index after staging two lines:\n%s", got)
+	}
+
+	// Unstage "14" again from the staged diff.
+	raw, err = repo.DiffFile("f.txt", true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := ParseHunks(raw)
+	p, ok := PartialHunk(hs[len(hs)-1], func(removed bool, num int) bool { return !removed && num == 14 }, true)
+	if !ok {
+		t.Fatal("unstage: no change selected")
+	}
+	if err := repo.ApplyPartialPatch(FilePatch("f.txt", p), true); err != nil {
+		t.Fatal(err)
+	}
+	if got := index(); got != "1\n2\ntwo\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n" {
+		t.Errorf("index after unstaging a line:\n%s", got)
+	}
+}
+
+func TestUpstreamDivergence(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1\n", "init")
+	gitRun(t, repo.Dir(), "branch", "up")
+	addCommit(t, repo, "mine.txt", "m\n", "local work")
+	gitRun(t, repo.Dir(), "checkout", "-q", "up")
+	addCommit(t, repo, "theirs.txt", "t\n", "upstream work 1")
+	addCommit(t, repo, "theirs.txt", "t2\n", "upstream work 2")
+	gitRun(t, repo.Dir(), "checkout", "-q", "-")
+	gitRun(t, repo.Dir(), "branch", "--set-upstream-to=up")
+
+	d, err := repo.UpstreamDivergence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Upstream != "up" || len(d.MergeBase) != 7 {
+		t.Errorf("Upstream=%q MergeBase=%q", d.Upstream, d.MergeBase)
+	}
+	if len(d.Ahead) != 1 || d.Ahead[0].Subject != "local work" {
+		t.Errorf("Ahead = %+v", d.Ahead)
+	}
+	if len(d.Behind) != 2 || d.Behind[0].Subject != "upstream work 2" {
+		t.Errorf("Behind = %+v", d.Behind)
+	}
+	if d.AheadCount != 1 || d.BehindCount != 2 {
+		t.Errorf("counts = %d/%d, want 1/2", d.AheadCount, d.BehindCount)
+	}
+}
+
+func TestUpstreamDivergence_NoUpstream(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.txt", "v1\n", "init")
+	if _, err := repo.UpstreamDivergence(); err == nil {
+		t.Error("expected error without an upstream")
+	}
+}
+
+func TestWithDiffAlgorithm(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	addCommit(t, repo, "f.go", "a\nb\nc\n", "init")
+	writeFile(t, repo, "f.go", "a\nc\nb\n")
+
+	tests := []struct{ algo, want string }{
+		{"histogram", "histogram"},
+		{"patience", "patience"},
+		{"", "myers"},
+		{"bogus", "myers"},
+	}
+	for _, tt := range tests {
+		r := repo.WithDiffAlgorithm(tt.algo)
+		if got := r.DiffAlgorithm(); got != tt.want {
+			t.Errorf("WithDiffAlgorithm(%q).DiffAlgorithm() = %q, want %q", tt.algo, got, tt.want)
+		}
+		diff, err := r.DiffFile("f.go", false, "")
+		if err != nil || !strings.Contains(diff, "@@") {
+			t.Errorf("%s diff failed: %v\n%s", tt.want, err, diff)
+		}
+	}
+	if repo.DiffAlgorithm() != "myers" {
+		t.Error("WithDiffAlgorithm should not modify the original repo")
+	}
+}
+
+func TestCommitBody(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	writeFile(t, repo, "f.txt", "v1")
+	gitRun(t, repo.Dir(), "add", "f.txt")
+	gitRun(t, repo.Dir(), "commit", "-m", "subject", "-m", "first para", "-m", "second para")
+
+	body, err := repo.CommitBody("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "first para\n\nsecond para" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestDiffFileFull(t *testing.T) {
+	t.Parallel()
+	repo := setupTestRepo(t)
+	var lines []string
+	for i := range 30 {
+		lines = append(lines, "line "+strconv.Itoa(i))
+	}
+	addCommit(t, repo, "f.txt", strings.Join(lines, "\n")+"\n", "init")
+	lines[29] = "changed"
+	writeFile(t, repo, "f.txt", strings.Join(lines, "\n")+"\n")
+
+	hunks, err := repo.DiffFile("f.txt", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(hunks, " line 0\n") {
+		t.Error("plain diff should only carry nearby context")
+	}
+	full, err := repo.DiffFileFull("f.txt", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(full, " line 0\n") || !strings.Contains(full, "+changed") {
+		t.Errorf("full diff should include the whole file:\n%s", full)
+	}
+}
+
+func TestShallowClone(t *testing.T) {
+	t.Parallel()
+	src := setupTestRepo(t)
+	addCommit(t, src, "f.txt", "v1\n", "first")
+	addCommit(t, src, "f.txt", "v2\n", "second")
+	if src.IsShallow() {
+		t.Fatal("a full repo should not be shallow")
+	}
+
+	dst := filepath.Join(t.TempDir(), "clone")
+	gitRun(t, src.Dir(), "clone", "-q", "--depth", "1", "file://"+src.Dir(), dst)
+	repo, err := NewRepo(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !repo.IsShallow() {
+		t.Fatal("a --depth 1 clone should be shallow")
+	}
+	if _, err := repo.CommitDiff("HEAD"); !errors.Is(err, ErrShallowBoundary) {
+		t.Errorf("CommitDiff at the boundary: err = %v, want ErrShallowBoundary", err)
+	}
+	if _, err := repo.CommitDiffFiles("HEAD"); !errors.Is(err, ErrShallowBoundary) {
+		t.Errorf("CommitDiffFiles at the boundary: err = %v, want ErrShallowBoundary", err)
+	}
+	info := repo.UpstreamStatus()
+	if info.Upstream == "" || !info.Shallow || info.Ahead != 0 || info.Behind != 0 {
+		t.Errorf("UpstreamStatus = %+v, want upstream with ahead/behind skipped", info)
+	}
 }
@@ -48,14 +48,19 @@ type Theme struct {
 	// Accent
 	AccentFg string
 
+	// Author colors for the log, picked by hashing the author name
+	AuthorPalette []string
+
 	// Chroma syntax theme name
 	ChromaStyle string
 }
 
 // Themes is the registry of built-in themes.
 var Themes = map[string]Theme{
-	"dark":  DarkTheme(),
-	"light": LightTheme(),
+	"dark":            DarkTheme(),
+	"light":           LightTheme(),
+	"solarized-dark":  SolarizedDarkTheme(),
+	"solarized-light": SolarizedLightTheme(),
 }
 
 // DarkTheme returns a Catppuccin Mocha-inspired pastel dark theme.
@@ -98,6 +103,8 @@ func DarkTheme() Theme {
 
 		AccentFg: "#c678dd",
 
+		AuthorPalette: []string{"#f38ba8", "#fab387", "#f9e2af", "#a6e3a1", "#94e2d5", "#89b4fa", "#cba6f7", "#f5c2e7"},
+
 		ChromaStyle: "catppuccin-mocha",
 	}
 }
@@ -142,6 +149,102 @@ func LightTheme() Theme {
 
 		AccentFg: "#8839ef",
 
+		AuthorPalette: []string{"#d20f39", "#c4510a", "#a86a0c", "#2d7d1f", "#137a80", "#1e66f5", "#8839ef", "#c2357f"},
+
 		ChromaStyle: "catppuccin-latte",
 	}
 }
+
+// SolarizedDarkTheme returns a Solarized dark theme. Body text uses base1
+// instead of base0 to reach 4.5:1 contrast.
+func SolarizedDarkTheme() Theme {
+	return Theme{
+		Bg: "#002b36",
+		Fg: "#93a1a1",
+
+		AddedFg:   "#859900",
+		AddedBg:   "#0b3a2a",
+		RemovedFg: "#dc322f",
+		RemovedBg: "#3a1a1f",
+		HunkFg:    "#6c71c4",
+
+		LineNumFg:        "#586e75",
+		LineNumAddedFg:   "#859900",
+		LineNumRemovedFg: "#dc322f",
+
+		HeaderBg: "#073642",
+		HeaderFg: "#268bd2",
+
+		HunkBg: "#073642",
+
+		CardBg: "#073642",
+
+		SelectedBg:  "#0a4a5a",
+		SelectedFg:  "#eee8d5",
+		StagedFg:    "#859900",
+		ModifiedFg:  "#cb4b16",
+		AddedFileFg: "#859900",
+		DeletedFg:   "#dc322f",
+		RenamedFg:   "#6c71c4",
+		UntrackedFg: "#586e75",
+
+		BorderFg:    "#268bd2",
+		StatusBarBg: "#073642",
+		StatusBarFg: "#93a1a1",
+		HelpKeyFg:   "#268bd2",
+		HelpDescFg:  "#839496",
+
+		AccentFg: "#268bd2",
+
+		AuthorPalette: []string{"#dc322f", "#cb4b16", "#b58900", "#859900", "#2aa198", "#268bd2", "#6c71c4", "#d33682"},
+
+		ChromaStyle: "solarized-dark",
+	}
+}
+
+// SolarizedLightTheme returns a Solarized light theme. Body text and the
+// yellow/green/cyan accents are darkened to meet contrast on base3.
+func SolarizedLightTheme() Theme {
+	return Theme{
+		Bg: "#fdf6e3",
+		Fg: "#475b62",
+
+		AddedFg:   "#5f7000",
+		AddedBg:   "#eaf0d0",
+		RemovedFg: "#dc322f",
+		RemovedBg: "#fbe3dc",
+		HunkFg:    "#6c71c4",
+
+		LineNumFg:        "#93a1a1",
+		LineNumAddedFg:   "#6f8000",
+		LineNumRemovedFg: "#dc322f",
+
+		HeaderBg: "#eee8d5",
+		HeaderFg: "#6c71c4",
+
+		HunkBg: "#eee8d5",
+
+		CardBg: "#eee8d5",
+
+		SelectedBg:  "#d3e4ea",
+		SelectedFg:  "#002b36",
+		StagedFg:    "#6f8000",
+		ModifiedFg:  "#cb4b16",
+		AddedFileFg: "#6f8000",
+		DeletedFg:   "#dc322f",
+		RenamedFg:   "#6c71c4",
+		UntrackedFg: "#93a1a1",
+
+		BorderFg:    "#268bd2",
+		StatusBarBg: "#eee8d5",
+		StatusBarFg: "#073642",
+		HelpKeyFg:   "#268bd2",
+		HelpDescFg:  "#657b83",
+
+		AccentFg: "#6c71c4",
+
+		AuthorPalette: []string{"#dc322f", "#cb4b16", "#9a7500", "#6f8000", "#1f8079", "#268bd2", "#6c71c4", "#d33682"},
+
+		ChromaStyle: "solarized-light",
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 func TestThemes_MapCompleteness(t *testing.T) {
 	t.Parallel()
-	for _, name := range []string{"dark", "light"} {
+	for _, name := range []string{"dark", "light", "solarized-dark", "solarized-light"} {
 		if _, ok := Themes[name]; !ok {
 			t.Errorf("Themes map missing %q", name)
 		}
@@ -136,6 +136,41 @@ func checkContrast(t *testing.T, th Theme, label string) {
 	}
 }
 
+func checkAuthorPalette(t *testing.T, th Theme, label string) {
+	t.Helper()
+	if len(th.AuthorPalette) == 0 {
+		t.Fatalf("%s.AuthorPalette is empty", label)
+	}
+	for _, c := range th.AuthorPalette {
+		if !hexColorRe.MatchString(c) {
+			t.Errorf("%s.AuthorPalette: %q is not valid hex", label, c)
+			continue
+		}
+		if ratio := contrastRatio(c, th.Bg); ratio < 3.0 {
+			t.Errorf("%s.AuthorPalette %s: contrast %.2f < 3.0 on Bg", label, c, ratio)
+		}
+	}
+}
+
+func TestThemes_AuthorPalette(t *testing.T) {
+	t.Parallel()
+	checkAuthorPalette(t, DarkTheme(), "DarkTheme")
+	checkAuthorPalette(t, LightTheme(), "LightTheme")
+}
+
+func TestSolarizedThemes(t *testing.T) {
+	t.Parallel()
+	for label, th := range map[string]Theme{
+		"SolarizedDarkTheme":  SolarizedDarkTheme(),
+		"SolarizedLightTheme": SolarizedLightTheme(),
+	} {
+		checkNonEmpty(t, th, label)
+		checkValidHex(t, th, label)
+		checkContrast(t, th, label)
+		checkAuthorPalette(t, th, label)
+	}
+}
+
 func TestDarkTheme_ContrastRatios(t *testing.T) {
 	t.Parallel()
 	checkContrast(t, DarkTheme(), "DarkTheme")
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Blame for removed lines: an on-demand lookup of who last changed a line
+// the diff removes, shown in the status bar.
+
+// removedLineNums maps each parsed line to its old line number when it is
+// removed and -1 otherwise. Like lineKinds it is indexed by rendered row.
+func removedLineNums(parsed ParsedDiff) []int {
+	if parsed.Binary {
+		return nil
+	}
+	nums := make([]int, len(parsed.Lines))
+	for i, dl := range parsed.Lines {
+		nums[i] = -1
+		if dl.Type == LineRemoved {
+			nums[i] = dl.OldNum
+		}
+	}
+	return nums
+}
+
+// removedRow picks the removed line to blame: the one under the diff-line
+// cursor, or without a cursor the first one on screen. -1 if there is none.
+func (m Model) removedRow() int {
+	if m.relativeGutterActive() {
+		if m.diffCursor < len(m.diffOldNums) && m.diffOldNums[m.diffCursor] > 0 {
+			return m.diffCursor
+		}
+		return -1
+	}
+	end := min(m.viewport.YOffset+m.viewport.Height, len(m.diffOldNums))
+	for i := m.viewport.YOffset; i < end; i++ {
+		if m.diffOldNums[i] > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m Model) blameRemoved() (tea.Model, tea.Cmd) {
+	row := m.removedRow()
+	if row < 0 || m.cursor >= len(m.files) {
+		m.statusMsg = "no removed line to blame"
+		return m, nil
+	}
+	f := m.files[m.cursor]
+	path := f.change.Path
+	if f.change.OldPath != "" {
+		path = f.change.OldPath
+	}
+	line := m.diffOldNums[row]
+	repo := m.repo
+	staged := f.change.Staged
+	ref := m.ref
+	m.statusMsg = fmt.Sprintf("blaming line %d...", line)
+	return m, func() tea.Msg {
+		b, err := repo.BlameOld(path, staged, ref, line)
+		return blameLoadedMsg{line: line, blame: b, err: err}
+	}
+}
+
+func (m Model) handleBlameLoaded(msg blameLoadedMsg) (tea.Model, tea.Cmd) {
+	m.statusMsg = blameStatus(msg, time.Now())
+	return m, nil
+}
+
+// blameStatus formats a blame result for the status bar, e.g.
+// "L42: a1b2c3d alice, 3d ago — fix parser".
+func blameStatus(msg blameLoadedMsg, now time.Time) string {
+	prefix := fmt.Sprintf("L%d: ", msg.line)
+	b := msg.blame
+	switch {
+	case msg.err != nil:
+		return prefix + "blame failed: " + firstLine(msg.err.Error())
+	case b.Uncommitted():
+		return prefix + "not committed yet"
+	}
+	return fmt.Sprintf("%s%s %s, %s — %s", prefix, b.Hash[:min(7, len(b.Hash))], b.Author, relativeAge(now.Sub(b.Time)), b.Summary)
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+func TestRemovedRow(t *testing.T) {
+	t.Parallel()
+	parsed := ParseDiff("@@ -1,3 +1,2 @@\n a\n-b\n+B\n c\n")
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "f.txt"}}})
+	m.diffOldNums = removedLineNums(parsed)
+	m.viewport.Height = 10
+	if got := m.removedRow(); got != 2 {
+		t.Errorf("first removed row on screen = %d, want 2", got)
+	}
+	m.viewport.Height = 2
+	if got := m.removedRow(); got != -1 {
+		t.Errorf("removed line below the screen: row = %d, want -1", got)
+	}
+	result, cmd := m.blameRemoved()
+	if rm := result.(Model); cmd != nil || rm.statusMsg != "no removed line to blame" {
+		t.Errorf("nothing to blame: cmd=%v status=%q", cmd != nil, rm.statusMsg)
+	}
+}
+
+func TestBlameStatus(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		msg  blameLoadedMsg
+		want string
+	}{
+		{"committed", blameLoadedMsg{line: 42, blame: git.BlameLine{Hash: "a1b2c3d4e5", Author: "alice", Time: now.Add(-72 * time.Hour), Summary: "fix parser"}},
+			"L42: a1b2c3d alice, 3d ago — fix parser"},
+		{"uncommitted", blameLoadedMsg{line: 7, blame: git.BlameLine{Hash: "0000000000"}}, "L7: not committed yet"},
+		{"error", blameLoadedMsg{line: 1, err: errors.New("fatal: no such path\nmore")}, "L1: blame failed: fatal: no such path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := blameStatus(tt.msg, now); got != tt.want {
+				t.Errorf("blameStatus = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// errNoClipboardTool means none of the clipboard tools is on PATH.
+var errNoClipboardTool = errors.New("no clipboard tool found")
+
+// clipboardTools are tried in order; the first one found on PATH wins.
+func clipboardTools() [][]string {
+	return [][]string{
+		{"pbcopy"},
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+		{"clip.exe"},
+	}
+}
+
+// pasteTools read the clipboard, mirroring clipboardTools.
+func pasteTools() [][]string {
+	return [][]string{
+		{"pbpaste"},
+		{"wl-paste", "--no-newline"},
+		{"xclip", "-selection", "clipboard", "-o"},
+		{"xsel", "--clipboard", "--output"},
+		{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+	}
+}
+
+// readClipboard returns the clipboard text. Unlike copying there is no
+// OSC 52 fallback: few terminals answer clipboard queries.
+func readClipboard() (string, error) {
+	for _, tool := range pasteTools() {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		out, err := exec.Command(tool[0], tool[1:]...).Output()
+		return string(out), err
+	}
+	return "", errNoClipboardTool
+}
+
+// pasteCmd reads the clipboard asynchronously.
+func pasteCmd() tea.Cmd {
+	return func() tea.Msg {
+		text, err := readClipboard()
+		return clipboardPastedMsg{text: text, err: err}
+	}
+}
+
+// singleLine collapses pasted text for the one-line commit input: each
+// line is trimmed and blank lines are dropped, so "subject\n\nbody"
+// becomes "subject body".
+func singleLine(text string) string {
+	var parts []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// copyToClipboard copies text using a system clipboard tool.
+func copyToClipboard(text string) error {
+	for _, tool := range clipboardTools() {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(tool[0], tool[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+	return errNoClipboardTool
+}
+
+// copiedMsg copies text and reports label in the status bar. Without a
+// clipboard tool the text is handed back to be copied with the OSC 52
+// escape sequence (works over SSH in most modern terminals). That has to go
+// out with a rendered frame: writing it from a command would interleave
+// with the renderer's output.
+func copiedMsg(text, label string) clipboardDoneMsg {
+	err := copyToClipboard(text)
+	if errors.Is(err, errNoClipboardTool) {
+		return clipboardDoneMsg{label: label, osc52: text}
+	}
+	return clipboardDoneMsg{label: label, err: err}
+}
+
+// copyCmd copies text asynchronously and reports label in the status bar.
+func copyCmd(text, label string) tea.Cmd {
+	return func() tea.Msg {
+		return copiedMsg(text, label)
+	}
+}
+
+// osc52Hold is how long a view keeps the OSC 52 sequence, enough for the
+// renderer to flush a frame carrying it. Dropping it afterwards keeps
+// later redraws from copying the text again.
+const osc52Hold = 100 * time.Millisecond
+
+// clearOSC52Cmd drops the pending OSC 52 copy of text once osc52Hold is up.
+func clearOSC52Cmd(text string) tea.Cmd {
+	if text == "" {
+		return nil
+	}
+	return tea.Tick(osc52Hold, func(time.Time) tea.Msg {
+		return osc52ClearedMsg{text: text}
+	})
+}
+
+// withOSC52 prefixes view with the sequence copying text, if any. It takes
+// no cells, so the layout is unchanged.
+func withOSC52(text, view string) string {
+	if text == "" {
+		return view
+	}
+	seq := osc52.New(text)
+	if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+	return seq.String() + view
+}
+
+// selectedFilePath returns the selected file's path relative to the
+// directory differ was started in, or absolute when abs is set.
+func (m Model) selectedFilePath(abs bool) string {
+	if len(m.files) == 0 || m.cursor >= len(m.files) {
+		return ""
+	}
+	path := m.files[m.cursor].change.Path
+	if m.repo == nil {
+		return path
+	}
+	if abs {
+		return m.repo.AbsPath(path)
+	}
+	return m.repo.RelPath(path)
+}
+
+func (m Model) copyPath(abs bool) (tea.Model, tea.Cmd) {
+	path := m.selectedFilePath(abs)
+	if path == "" {
+		return m, nil
+	}
+	return m, copyCmd(path, "copied path")
+}
+
+// copyDiffMarkdown copies the selected file's raw unified diff as a fenced
+// ```diff block, for pasting into issues and chat. It is a line diff even
+// when the view shows word diffs, so the patch stays applicable.
+func (m Model) copyDiffMarkdown() (tea.Model, tea.Cmd) {
+	if m.cursor >= len(m.files) {
+		return m, nil
+	}
+	f := m.files[m.cursor]
+	repo := m.repo.WithWordDiff(false)
+	ref := m.ref
+	return m, func() tea.Msg {
+		var raw string
+		var err error
+		if f.untracked {
+			raw, err = repo.ReadFileContent(f.change.Path)
+			raw = newFilePatch(f.change.Path, raw)
+		} else {
+			raw, err = repo.DiffFile(f.change.Path, f.change.Staged, ref)
+		}
+		if err != nil {
+			return clipboardDoneMsg{err: err}
+		}
+		if strings.TrimSpace(raw) == "" {
+			return clipboardDoneMsg{label: "no diff to copy"}
+		}
+		return copiedMsg(fencedDiff(raw), "copied diff as markdown")
+	}
+}
+
+// fencedDiff wraps a unified diff in a markdown code fence, lengthening the
+// fence if the diff itself contains one.
+func fencedDiff(raw string) string {
+	fence := "```"
+	for strings.Contains(raw, fence) {
+		fence += "`"
+	}
+	return fence + "diff\n" + strings.TrimRight(raw, "\n") + "\n" + fence + "\n"
+}
+
+// newFilePatch renders an untracked file's content as the diff that adding
+// it would produce.
+func newFilePatch(path, content string) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- /dev/null\n+++ b/%s\n@@ -0,0 +1,%d @@\n", path, len(lines))
+	for _, line := range lines {
+		b.WriteString("+" + line + "\n")
+	}
+	return b.String()
+}
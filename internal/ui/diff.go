@@ -26,18 +26,33 @@ type DiffLine struct {
 	Content string
 	OldNum  int // -1 if N/A
 	NewNum  int // -1 if N/A
+
+	NoNewline bool // followed by "\ No newline at end of file"
+
+	Words []WordSpan // word diffs only: changed runs inside Content
+
+	Selected bool // inside the diff view's line selection
 }
 
 // ParsedDiff is the result of parsing a raw unified diff.
 type ParsedDiff struct {
-	Lines  []DiffLine
-	Binary bool
+	Lines     []DiffLine
+	Binary    bool
+	Truncated bool // Lines stop at the parse limit
 }
 
+// maxDiffLines is the default parse limit, overridden by max_diff_lines.
 const maxDiffLines = 10000
 
-// ParseDiff parses raw unified diff output into structured lines.
+// ParseDiff parses raw unified diff output into structured lines, keeping
+// at most maxDiffLines.
 func ParseDiff(raw string) ParsedDiff {
+	return ParseDiffLimit(raw, maxDiffLines)
+}
+
+// ParseDiffLimit is ParseDiff with a custom line limit; limit <= 0 parses
+// the whole diff. A cut diff is marked Truncated.
+func ParseDiffLimit(raw string, limit int) ParsedDiff {
 	if strings.Contains(raw, "Binary files") && strings.Contains(raw, "differ") {
 		return ParsedDiff{Binary: true}
 	}
@@ -46,12 +61,12 @@ func ParseDiff(raw string) ParsedDiff {
 	oldNum, newNum := 0, 0
 
 	for _, line := range strings.Split(raw, "\n") {
-		if len(lines) >= maxDiffLines {
-			lines = append(lines, DiffLine{
-				Type: LineHunkHeader, Content: fmt.Sprintf("… truncated (%d+ lines)", maxDiffLines),
-				OldNum: -1, NewNum: -1,
-			})
-			break
+		if limit > 0 && len(lines) >= limit {
+			return ParsedDiff{Lines: lines, Truncated: true}
+		}
+		if strings.HasPrefix(line, `\`) && len(lines) > 0 {
+			lines[len(lines)-1].NoNewline = true
+			continue
 		}
 		dl := parseDiffLine(line, &oldNum, &newNum)
 		if dl != nil {
@@ -145,8 +160,69 @@ func parseHunkHeader(line string, oldNum, newNum *int) {
 
 const lineNumWidth = 4
 
+// gutterWidth returns the columns the line-number gutter takes, including
+// the space after it, for a Config.Gutter mode ("both", "old", "new" or
+// "none"; empty means "both").
+func gutterWidth(mode string) int {
+	switch mode {
+	case "none":
+		return 0
+	case "old", "new":
+		return lineNumWidth + 1
+	default:
+		return lineNumWidth*2 + 2
+	}
+}
+
+// codeWidth is the room left for the +/- marker and code after the gutter.
+func codeWidth(width int, mode string) int {
+	return width - gutterWidth(mode) - 1
+}
+
+// gutterNums formats the line numbers shown for mode; -1 leaves a column
+// blank.
+func gutterNums(oldNum, newNum int, mode string) string {
+	switch mode {
+	case "none":
+		return ""
+	case "old":
+		return fmtLineNum(oldNum)
+	case "new":
+		return fmtLineNum(newNum)
+	default:
+		return fmtLineNum(oldNum) + " " + fmtLineNum(newNum)
+	}
+}
+
 // RenderDiff renders parsed diff lines into a styled string.
 func RenderDiff(parsed ParsedDiff, filename string, styles Styles, t theme.Theme, width int) string {
+	return RenderDiffRelative(parsed, filename, styles, t, width, -1, RenderOptions{})
+}
+
+// RenderOptions are the config switches of the diff renderers.
+type RenderOptions struct {
+	WhitespaceErrors bool // mark whitespace errors on added lines
+}
+
+// RenderDiffRelative renders like RenderDiff, but numbers code lines by their
+// distance from the cursor line (vim-style). A negative cursor keeps absolute numbers.
+func RenderDiffRelative(parsed ParsedDiff, filename string, styles Styles, t theme.Theme, width, cursor int, opts RenderOptions) string {
+	if parsed.Binary {
+		return RenderBinaryFile(styles, width)
+	}
+	initChromaStyle(t.ChromaStyle)
+
+	var b strings.Builder
+	for i, dl := range parsed.Lines {
+		b.WriteString(renderDiffLine(dl, filename, styles, t, width, relDistance(i, cursor), opts))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// RenderDiffCompact renders a dense diff: no gutter or background padding,
+// just +/-/space prefixed, highlighted lines and hunk headers.
+func RenderDiffCompact(parsed ParsedDiff, filename string, styles Styles, t theme.Theme, width int) string {
 	if parsed.Binary {
 		return RenderBinaryFile(styles, width)
 	}
@@ -154,23 +230,121 @@ func RenderDiff(parsed ParsedDiff, filename string, styles Styles, t theme.Theme
 
 	var b strings.Builder
 	for _, dl := range parsed.Lines {
-		b.WriteString(renderDiffLine(dl, filename, styles, t, width))
+		switch dl.Type {
+		case LineHunkHeader:
+			b.WriteString(styles.DiffHunkHeader.Render("@@ " + dl.Content))
+		case LineAdded:
+			b.WriteString(styles.DiffAdded.Render("+") + highlightLine(dl.Content, filename, selectedBg(dl, t, t.AddedBg)))
+		case LineRemoved:
+			b.WriteString(styles.DiffRemoved.Render("-") + highlightLine(dl.Content, filename, selectedBg(dl, t, t.RemovedBg)))
+		default:
+			b.WriteString(" " + highlightContent(dl, filename, selectedBg(dl, t, ""), styles, RenderOptions{}))
+		}
+		b.WriteString(noNewlineMarker(dl, styles))
 		b.WriteByte('\n')
 	}
 	return b.String()
 }
 
-func renderDiffLine(dl DiffLine, filename string, styles Styles, t theme.Theme, width int) string {
+// selectedBg is the background for dl's code: bg, or the selection color
+// when dl is selected.
+func selectedBg(dl DiffLine, t theme.Theme, bg string) string {
+	if dl.Selected {
+		return t.SelectedBg
+	}
+	return bg
+}
+
+// newFileDiff turns file content into an all-added diff.
+func newFileDiff(content string) ParsedDiff {
+	var lines []DiffLine
+	for i, line := range strings.Split(content, "\n") {
+		lines = append(lines, DiffLine{Type: LineAdded, Content: line, OldNum: -1, NewNum: i + 1})
+	}
+	return ParsedDiff{Lines: lines}
+}
+
+// ParseNewFile is ParseDiff for the content of an untracked file: every
+// line added, binaries flagged and long files cut at the same line limit.
+func ParseNewFile(content string) ParsedDiff {
+	if isBinary([]byte(content[:min(len(content), binarySniffLen)])) {
+		return ParsedDiff{Binary: true}
+	}
+	shown, cut := firstLines(strings.TrimSuffix(content, "\n"), maxDiffLines)
+	parsed := newFileDiff(shown)
+	parsed.Truncated = cut
+	return parsed
+}
+
+// deletedFileDiff is newFileDiff for a removed file: every line of the old
+// content as removed.
+func deletedFileDiff(content string) ParsedDiff {
+	var lines []DiffLine
+	for i, line := range strings.Split(content, "\n") {
+		lines = append(lines, DiffLine{Type: LineRemoved, Content: line, OldNum: i + 1, NewNum: -1})
+	}
+	return ParsedDiff{Lines: lines}
+}
+
+// gapMarker is the hunk line changesOnly puts where context was dropped.
+const gapMarker = "…"
+
+// changesOnly drops the context lines of a diff, keeping added and removed
+// lines under their hunk headers with their real line numbers. Where context
+// separated two runs of changes, a "…" hunk line marks the gap.
+func changesOnly(parsed ParsedDiff) ParsedDiff {
+	out := parsed
+	out.Lines = nil
+	changed, skipped := false, false
+	for _, dl := range parsed.Lines {
+		switch {
+		case dl.Type == LineContext && dl.Words == nil:
+			skipped = changed
+			continue
+		case dl.Type == LineHunkHeader:
+			changed, skipped = false, false
+		default:
+			if skipped {
+				out.Lines = append(out.Lines, DiffLine{Type: LineHunkHeader, Content: gapMarker, OldNum: -1, NewNum: -1})
+				skipped = false
+			}
+			changed = true
+		}
+		out.Lines = append(out.Lines, dl)
+	}
+	return out
+}
+
+// relDistance returns the distance of line i from the cursor, or -1 when
+// relative numbering is off.
+func relDistance(i, cursor int) int {
+	if cursor < 0 {
+		return -1
+	}
+	if i < cursor {
+		return cursor - i
+	}
+	return i - cursor
+}
+
+func renderDiffLine(dl DiffLine, filename string, styles Styles, t theme.Theme, width, rel int, opts RenderOptions) string {
 	switch dl.Type {
 	case LineHunkHeader:
 		return renderHunkLine(dl, styles, width)
 	default:
-		return renderCodeLine(dl, filename, styles, t, width)
+		return renderCodeLine(dl, filename, styles, t, width, rel, opts)
 	}
 }
 
 func renderHunkLine(dl DiffLine, styles Styles, width int) string {
-	prefix := styles.DiffLineNum.Render("    ···  ")
+	prefix := ""
+	switch styles.Gutter {
+	case "none":
+	case "old", "new":
+		prefix = styles.DiffLineNum.Render(" ···")
+	default:
+		prefix = styles.DiffLineNum.Render("    ···  ")
+	}
 	text := dl.Content
 	if text != "" {
 		text = " " + text
@@ -178,10 +352,7 @@ func renderHunkLine(dl DiffLine, styles Styles, width int) string {
 	return prefix + styles.DiffHunkHeader.Render(text)
 }
 
-func renderCodeLine(dl DiffLine, filename string, styles Styles, t theme.Theme, width int) string {
-	oldNum := fmtLineNum(dl.OldNum)
-	newNum := fmtLineNum(dl.NewNum)
-
+func renderCodeLine(dl DiffLine, filename string, styles Styles, t theme.Theme, width, rel int, opts RenderOptions) string {
 	indicator := " "
 	var bgColor string
 	var numStyle lipgloss.Style
@@ -205,22 +376,151 @@ func renderCodeLine(dl DiffLine, filename string, styles Styles, t theme.Theme,
 		indStyle = styles.DiffContext
 		bgStyle = lipgloss.NewStyle()
 	}
+	mark := " "
+	if dl.Selected {
+		bgColor = t.SelectedBg
+		indStyle = indStyle.Background(lipgloss.Color(t.SelectedBg))
+		bgStyle = styles.DiffSelectedBg
+		if styles.Monochrome {
+			mark = ">"
+		}
+	}
 
-	nums := numStyle.Render(oldNum + " " + newNum)
+	nums := renderGutter(dl, numStyle, styles, rel)
 
-	// Syntax highlight the content
-	highlighted := highlightLine(dl.Content, filename, bgColor)
+	// Syntax highlight the content, cut to the panel
+	dl, tail := fitCode(dl, codeWidth(width, styles.Gutter)-2, styles, indStyle)
+	highlighted := highlightContent(dl, filename, bgColor, styles, opts) + tail
 
 	// Build: colored indicator + highlighted content + bg padding to fill width
-	codeWidth := width - lineNumWidth*2 - 3 // nums + spaces
-	prefix := indStyle.Render(indicator + " ")
+	prefix := indStyle.Render(indicator + mark)
 	contentWidth := lipgloss.Width(prefix) + lipgloss.Width(highlighted)
 	padding := ""
-	if pad := codeWidth - contentWidth; pad > 0 {
+	if pad := codeWidth(width, styles.Gutter) - contentWidth; pad > 0 {
 		padding = bgStyle.Render(strings.Repeat(" ", pad))
 	}
 
-	return nums + " " + prefix + highlighted + padding
+	return nums + prefix + highlighted + padding
+}
+
+// highlightContent syntax-highlights a code line. Like git's default
+// wsErrorHighlight, whitespace errors are only marked on added lines.
+// Word-diff lines show their changed runs instead of syntax colors.
+func highlightContent(dl DiffLine, filename, bgColor string, styles Styles, opts RenderOptions) string {
+	if len(dl.Words) > 0 {
+		return renderWords(dl, styles)
+	}
+	if !opts.WhitespaceErrors || dl.Type != LineAdded {
+		return highlightLine(dl.Content, filename, bgColor)
+	}
+	return markWhitespaceErrors(dl.Content, filename, bgColor, styles)
+}
+
+// markWhitespaceErrors highlights the code between the whitespace errors,
+// then wraps the errors themselves in the error style so syntax colors
+// don't cover them.
+func markWhitespaceErrors(content, filename, bgColor string, styles Styles) string {
+	lead, body, trail := splitWhitespaceErrors(content)
+	var b strings.Builder
+	if lead != "" {
+		b.WriteString(styles.WhitespaceError.Render(lead))
+	}
+	b.WriteString(highlightLine(body, filename, bgColor))
+	if trail != "" {
+		b.WriteString(styles.WhitespaceError.Render(trail))
+	}
+	return b.String()
+}
+
+// splitWhitespaceErrors splits content the way git's default whitespace
+// rules see it: indentation with a space before a tab (space-before-tab) up
+// to its last tab, the rest of the line, and trailing whitespace. Spaces
+// after the tabs, for alignment, are not an error.
+func splitWhitespaceErrors(content string) (lead, body, trail string) {
+	body = strings.TrimRight(content, " \t")
+	trail = content[len(body):]
+	code := strings.TrimLeft(body, " \t")
+	indent := body[:len(body)-len(code)]
+	if strings.Contains(indent, " \t") {
+		n := strings.LastIndexByte(indent, '\t') + 1
+		return indent[:n], body[n:], trail
+	}
+	return "", body, trail
+}
+
+// RenderTruncationBanner renders the notice shown below a diff cut at limit
+// lines, with an optional hint on how to see the rest.
+func RenderTruncationBanner(limit int, hint string, styles Styles, width int) string {
+	text := fmt.Sprintf(" Diff truncated at %d lines", limit)
+	if hint != "" {
+		text += " — " + hint
+	}
+	return styles.TruncatedBanner.Width(width).Render(text) + "\n"
+}
+
+// noNewlineMarker flags a line that lacks a trailing newline in its file.
+func noNewlineMarker(dl DiffLine, styles Styles) string {
+	if !dl.NoNewline {
+		return ""
+	}
+	return styles.DiffLineNum.Render(" ↵̸ (no newline)")
+}
+
+// overflowMarker ends a code line cut to fit the diff panel.
+const overflowMarker = "›"
+
+// fitCode cuts dl's content to width cells and returns what follows it:
+// the no-newline note when everything fits, overflowMarker (in markStyle)
+// when the content had to be cut, and nothing when only the note didn't fit.
+// Cutting before highlighting also spares chroma minified one-liners.
+func fitCode(dl DiffLine, width int, styles Styles, markStyle lipgloss.Style) (DiffLine, string) {
+	if styles.Monochrome {
+		dl = plainWords(dl)
+	}
+	note := noNewlineMarker(dl, styles)
+	contentW := lipgloss.Width(dl.Content)
+	switch {
+	case contentW+lipgloss.Width(note) <= width:
+		return dl, note
+	case contentW <= width:
+		return dl, ""
+	case width < 1:
+		dl.Content = ""
+		return dl, ""
+	}
+	dl.Content = clipCells(dl.Content, width-1)
+	return dl, markStyle.Render(overflowMarker)
+}
+
+// clipCells returns the longest prefix of s at most width cells wide.
+func clipCells(s string, width int) string {
+	w := 0
+	for i, r := range s {
+		rw := lipgloss.Width(string(r))
+		if w+rw > width {
+			return s[:i]
+		}
+		w += rw
+	}
+	return s
+}
+
+// renderGutter renders the line-number columns picked by styles.Gutter,
+// followed by a separator space. rel > 0 shows the distance from the cursor
+// instead; rel == 0 marks the cursor line itself.
+func renderGutter(dl DiffLine, numStyle lipgloss.Style, styles Styles, rel int) string {
+	if styles.Gutter == "none" {
+		return ""
+	}
+	switch {
+	case rel > 0 && styles.Gutter == "old":
+		return numStyle.Render(fmtLineNum(rel)) + " "
+	case rel > 0:
+		return numStyle.Render(gutterNums(-1, rel, styles.Gutter)) + " "
+	case rel == 0:
+		return styles.Accent.Render(gutterNums(dl.OldNum, dl.NewNum, styles.Gutter)) + " "
+	}
+	return numStyle.Render(gutterNums(dl.OldNum, dl.NewNum, styles.Gutter)) + " "
 }
 
 func fmtLineNum(n int) string {
@@ -235,19 +535,43 @@ func RenderNewFile(content, filename string, styles Styles, t theme.Theme, width
 	initChromaStyle(t.ChromaStyle)
 
 	var b strings.Builder
-	codeWidth := width - lineNumWidth*2 - 3
+	codeW := codeWidth(width, styles.Gutter)
 
 	for i, line := range strings.Split(content, "\n") {
-		num := i + 1
-		nums := styles.DiffLineNumAdded.Render("     " + fmt.Sprintf("%4d", num))
-		highlighted := highlightLine(line, filename, t.AddedBg)
+		nums := renderGutter(DiffLine{OldNum: -1, NewNum: i + 1}, styles.DiffLineNumAdded, styles, -1)
+		dl, tail := fitCode(DiffLine{Content: line}, codeW-2, styles, styles.DiffAdded)
+		highlighted := highlightLine(dl.Content, filename, t.AddedBg) + tail
 		prefix := styles.DiffAdded.Render("+ ")
 		contentWidth := lipgloss.Width(prefix) + lipgloss.Width(highlighted)
 		padding := ""
-		if pad := codeWidth - contentWidth; pad > 0 {
+		if pad := codeW - contentWidth; pad > 0 {
 			padding = styles.DiffAddedBg.Render(strings.Repeat(" ", pad))
 		}
-		b.WriteString(nums + " " + prefix + highlighted + padding)
+		b.WriteString(nums + prefix + highlighted + padding)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// RenderDeletedFile renders the old content of a deleted file with all
+// lines as removed, mirroring RenderNewFile.
+func RenderDeletedFile(content, filename string, styles Styles, t theme.Theme, width int) string {
+	initChromaStyle(t.ChromaStyle)
+
+	var b strings.Builder
+	codeW := codeWidth(width, styles.Gutter)
+
+	for i, line := range strings.Split(content, "\n") {
+		nums := renderGutter(DiffLine{OldNum: i + 1, NewNum: -1}, styles.DiffLineNumRemoved, styles, -1)
+		dl, tail := fitCode(DiffLine{Content: line}, codeW-2, styles, styles.DiffRemoved)
+		highlighted := highlightLine(dl.Content, filename, t.RemovedBg) + tail
+		prefix := styles.DiffRemoved.Render("- ")
+		contentWidth := lipgloss.Width(prefix) + lipgloss.Width(highlighted)
+		padding := ""
+		if pad := codeW - contentWidth; pad > 0 {
+			padding = styles.DiffRemovedBg.Render(strings.Repeat(" ", pad))
+		}
+		b.WriteString(nums + prefix + highlighted + padding)
 		b.WriteByte('\n')
 	}
 	return b.String()
@@ -281,44 +605,106 @@ func PairLines(lines []DiffLine) []SplitLine {
 		case LineContext:
 			result = append(result, SplitLine{Left: &dl, Right: &dl})
 			i++
-		case LineRemoved:
-			// Collect contiguous removed, then contiguous added
+		case LineRemoved, LineAdded:
+			// Collect the whole change block, removals and additions in
+			// any order, up to the next context line or hunk.
 			var removed, added []DiffLine
-			for i < len(lines) && lines[i].Type == LineRemoved {
-				removed = append(removed, lines[i])
+			for i < len(lines) && (lines[i].Type == LineRemoved || lines[i].Type == LineAdded) {
+				if lines[i].Type == LineRemoved {
+					removed = append(removed, lines[i])
+				} else {
+					added = append(added, lines[i])
+				}
 				i++
 			}
-			for i < len(lines) && lines[i].Type == LineAdded {
-				added = append(added, lines[i])
-				i++
+			result = append(result, alignChanged(removed, added)...)
+		default:
+			i++
+		}
+	}
+	return result
+}
+
+// maxAlignCells caps the LCS table of one change block; bigger blocks
+// fall back to positional pairing.
+const maxAlignCells = 1 << 20
+
+// alignChanged pairs the removed and added lines of one change block. Lines
+// with the same content on both sides (moved or reordered code) share a row,
+// found as a longest common subsequence; the lines between those anchors
+// pair up positionally, with blank padding where one side runs out.
+func alignChanged(removed, added []DiffLine) []SplitLine {
+	anchors := append(lcsAnchors(removed, added), [2]int{len(removed), len(added)})
+	var result []SplitLine
+	ri, ai := 0, 0
+	for _, a := range anchors {
+		for ri < a[0] || ai < a[1] {
+			var l, r *DiffLine
+			if ri < a[0] {
+				l = &removed[ri]
+				ri++
 			}
-			maxLen := len(removed)
-			if len(added) > maxLen {
-				maxLen = len(added)
+			if ai < a[1] {
+				r = &added[ai]
+				ai++
 			}
-			for j := 0; j < maxLen; j++ {
-				var l, r *DiffLine
-				if j < len(removed) {
-					l = &removed[j]
-				}
-				if j < len(added) {
-					r = &added[j]
-				}
-				result = append(result, SplitLine{Left: l, Right: r})
+			result = append(result, SplitLine{Left: l, Right: r})
+		}
+		if ri < len(removed) && ai < len(added) {
+			result = append(result, SplitLine{Left: &removed[ri], Right: &added[ai]})
+			ri++
+			ai++
+		}
+	}
+	return result
+}
+
+// lcsAnchors returns index pairs of a longest common subsequence of the
+// removed and added contents. Blank lines never anchor, since they match
+// everywhere and would scatter the alignment.
+func lcsAnchors(removed, added []DiffLine) [][2]int {
+	n, m := len(removed), len(added)
+	if n == 0 || m == 0 || (n+1)*(m+1) > maxAlignCells {
+		return nil
+	}
+	same := func(i, j int) bool {
+		return removed[i].Content == added[j].Content && strings.TrimSpace(removed[i].Content) != ""
+	}
+	// dp[i][j] is the LCS length of removed[i:] and added[j:].
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case same(i, j):
+				dp[i][j] = dp[i+1][j+1] + 1
+			case dp[i+1][j] >= dp[i][j+1]:
+				dp[i][j] = dp[i+1][j]
+			default:
+				dp[i][j] = dp[i][j+1]
 			}
-		case LineAdded:
-			// Orphan added (no preceding removed)
-			result = append(result, SplitLine{Right: &dl})
+		}
+	}
+	var anchors [][2]int
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case same(i, j):
+			anchors = append(anchors, [2]int{i, j})
 			i++
-		default:
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
 			i++
+		default:
+			j++
 		}
 	}
-	return result
+	return anchors
 }
 
 // RenderSplitDiff renders parsed diff in side-by-side layout.
-func RenderSplitDiff(parsed ParsedDiff, filename string, styles Styles, t theme.Theme, width int) string {
+func RenderSplitDiff(parsed ParsedDiff, filename string, styles Styles, t theme.Theme, width int, opts RenderOptions) string {
 	if parsed.Binary {
 		return RenderBinaryFile(styles, width)
 	}
@@ -335,8 +721,8 @@ func RenderSplitDiff(parsed ParsedDiff, filename string, styles Styles, t theme.
 			b.WriteByte('\n')
 			continue
 		}
-		left := renderSplitSide(sl.Left, filename, styles, t, panelW, true)
-		right := renderSplitSide(sl.Right, filename, styles, t, panelW, false)
+		left := renderSplitSide(sl.Left, filename, styles, t, panelW, true, opts)
+		right := renderSplitSide(sl.Right, filename, styles, t, panelW, false, opts)
 		b.WriteString(left)
 		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(t.BorderFg)).Render("│"))
 		b.WriteString(right)
@@ -346,15 +732,15 @@ func RenderSplitDiff(parsed ParsedDiff, filename string, styles Styles, t theme.
 }
 
 // RenderNewFileSplit renders untracked file content in split layout (all-added on right).
-func RenderNewFileSplit(content, filename string, styles Styles, t theme.Theme, width int) string {
+func RenderNewFileSplit(content, filename string, styles Styles, t theme.Theme, width int, opts RenderOptions) string {
 	initChromaStyle(t.ChromaStyle)
 
 	panelW := (width - 1) / 2
 	var b strings.Builder
 	for i, line := range strings.Split(content, "\n") {
 		dl := DiffLine{Type: LineAdded, Content: line, OldNum: -1, NewNum: i + 1}
-		left := renderSplitSide(nil, filename, styles, t, panelW, true)
-		right := renderSplitSide(&dl, filename, styles, t, panelW, false)
+		left := renderSplitSide(nil, filename, styles, t, panelW, true, opts)
+		right := renderSplitSide(&dl, filename, styles, t, panelW, false, opts)
 		b.WriteString(left)
 		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(t.BorderFg)).Render("│"))
 		b.WriteString(right)
@@ -365,13 +751,17 @@ func RenderNewFileSplit(content, filename string, styles Styles, t theme.Theme,
 
 const splitLineNumWidth = 4
 
-func renderSplitSide(dl *DiffLine, filename string, styles Styles, t theme.Theme, panelW int, isLeft bool) string {
+func renderSplitSide(dl *DiffLine, filename string, styles Styles, t theme.Theme, panelW int, isLeft bool, opts RenderOptions) string {
 	if dl == nil {
 		if panelW > 0 {
 			return strings.Repeat(" ", panelW)
 		}
 		return ""
 	}
+	if len(dl.Words) > 0 {
+		side := wordSide(*dl, isLeft)
+		dl = &side
+	}
 
 	// Pick line number
 	num := dl.OldNum
@@ -407,10 +797,11 @@ func renderSplitSide(dl *DiffLine, filename string, styles Styles, t theme.Theme
 	}
 
 	nums := numStyle.Render(numStr)
-	highlighted := highlightLine(dl.Content, filename, bgColor)
+	codeWidth := max(0, panelW-splitLineNumWidth-3)
+	fitted, tail := fitCode(*dl, codeWidth-2, styles, indStyle)
+	highlighted := highlightContent(fitted, filename, bgColor, styles, opts) + tail
 	prefix := indStyle.Render(indicator + " ")
 
-	codeWidth := max(0, panelW-splitLineNumWidth-3)
 	contentWidth := lipgloss.Width(prefix) + lipgloss.Width(highlighted)
 	padding := ""
 	if pad := codeWidth - contentWidth; pad > 0 {
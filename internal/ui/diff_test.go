@@ -1,9 +1,11 @@
 package ui
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/charmbracelet/lipgloss"
 	"github.com/jansmrcka/differ/internal/theme"
 )
 
@@ -119,6 +121,66 @@ func TestPairLines_Empty(t *testing.T) {
 	}
 }
 
+// pairContents renders pairs as "left|right" with "·" for blank padding.
+func pairContents(pairs []SplitLine) []string {
+	out := make([]string, len(pairs))
+	for i, p := range pairs {
+		l, r := "·", "·"
+		if p.Left != nil {
+			l = p.Left.Content
+		}
+		if p.Right != nil {
+			r = p.Right.Content
+		}
+		out[i] = l + "|" + r
+	}
+	return out
+}
+
+func TestPairLines_Aligned(t *testing.T) {
+	rm := func(c string) DiffLine { return DiffLine{Type: LineRemoved, Content: c, NewNum: -1} }
+	add := func(c string) DiffLine { return DiffLine{Type: LineAdded, Content: c, OldNum: -1} }
+	tests := []struct {
+		name  string
+		lines []DiffLine
+		want  []string
+	}{
+		{
+			"moved_block",
+			[]DiffLine{rm("a"), rm("b"), rm("c"), rm("x"), add("x"), add("a"), add("b"), add("c")},
+			[]string{"·|x", "a|a", "b|b", "c|c", "x|·"},
+		},
+		{
+			"reordered_lines",
+			[]DiffLine{rm("one"), rm("two"), rm("three"), add("three"), add("one"), add("two")},
+			[]string{"·|three", "one|one", "two|two", "three|·"},
+		},
+		{
+			"edit_between_anchors",
+			[]DiffLine{rm("keep1"), rm("old"), rm("keep2"), add("keep1"), add("new"), add("extra"), add("keep2")},
+			[]string{"keep1|keep1", "old|new", "·|extra", "keep2|keep2"},
+		},
+		{
+			"interleaved",
+			[]DiffLine{rm("a"), add("A"), rm("b"), add("B")},
+			[]string{"a|A", "b|B"},
+		},
+		{
+			"blank_lines_do_not_anchor",
+			[]DiffLine{rm("x"), rm(""), add("y"), add("z"), add("")},
+			[]string{"x|y", "|z", "·|"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pairContents(PairLines(tt.lines))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pairs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func testStyles() (Styles, theme.Theme) {
 	th := theme.Themes["dark"]
 	return NewStyles(th), th
@@ -129,7 +191,7 @@ func TestRenderSplitDiff_ContainsSeparator(t *testing.T) {
 		{Type: LineContext, Content: "hello", OldNum: 1, NewNum: 1},
 	}}
 	styles, th := testStyles()
-	result := RenderSplitDiff(parsed, "test.go", styles, th, 100)
+	result := RenderSplitDiff(parsed, "test.go", styles, th, 100, RenderOptions{})
 	if !strings.Contains(result, "│") {
 		t.Error("split diff should contain │ separator")
 	}
@@ -138,7 +200,7 @@ func TestRenderSplitDiff_ContainsSeparator(t *testing.T) {
 func TestRenderSplitDiff_Binary(t *testing.T) {
 	parsed := ParsedDiff{Binary: true}
 	styles, th := testStyles()
-	result := RenderSplitDiff(parsed, "test.bin", styles, th, 100)
+	result := RenderSplitDiff(parsed, "test.bin", styles, th, 100, RenderOptions{})
 	if !strings.Contains(result, "Binary") {
 		t.Error("binary file should show binary message")
 	}
@@ -146,7 +208,7 @@ func TestRenderSplitDiff_Binary(t *testing.T) {
 
 func TestRenderNewFileSplit_ContainsSeparator(t *testing.T) {
 	styles, th := testStyles()
-	result := RenderNewFileSplit("line1\nline2", "test.go", styles, th, 100)
+	result := RenderNewFileSplit("line1\nline2", "test.go", styles, th, 100, RenderOptions{})
 	if !strings.Contains(result, "│") {
 		t.Error("split new file should contain │ separator")
 	}
@@ -154,7 +216,7 @@ func TestRenderNewFileSplit_ContainsSeparator(t *testing.T) {
 
 func TestRenderSplitSide_Nil(t *testing.T) {
 	styles, th := testStyles()
-	result := renderSplitSide(nil, "test.go", styles, th, 40, true)
+	result := renderSplitSide(nil, "test.go", styles, th, 40, true, RenderOptions{})
 	if len(result) == 0 {
 		t.Error("nil side should produce padding, not empty")
 	}
@@ -167,7 +229,7 @@ func TestRenderSplitSide_Nil(t *testing.T) {
 func TestRenderSplitSide_Added(t *testing.T) {
 	styles, th := testStyles()
 	dl := &DiffLine{Type: LineAdded, Content: "new line", OldNum: -1, NewNum: 5}
-	result := renderSplitSide(dl, "test.go", styles, th, 50, false)
+	result := renderSplitSide(dl, "test.go", styles, th, 50, false, RenderOptions{})
 	if len(result) == 0 {
 		t.Error("added line should produce output")
 	}
@@ -176,7 +238,7 @@ func TestRenderSplitSide_Added(t *testing.T) {
 func TestRenderSplitSide_Removed(t *testing.T) {
 	styles, th := testStyles()
 	dl := &DiffLine{Type: LineRemoved, Content: "old line", OldNum: 3, NewNum: -1}
-	result := renderSplitSide(dl, "test.go", styles, th, 50, true)
+	result := renderSplitSide(dl, "test.go", styles, th, 50, true, RenderOptions{})
 	if len(result) == 0 {
 		t.Error("removed line should produce output")
 	}
@@ -186,7 +248,7 @@ func TestRenderSplitSide_ZeroWidth(t *testing.T) {
 	styles, th := testStyles()
 	dl := &DiffLine{Type: LineContext, Content: "x", OldNum: 1, NewNum: 1}
 	// Should not panic with tiny panelW
-	result := renderSplitSide(dl, "test.go", styles, th, 5, true)
+	result := renderSplitSide(dl, "test.go", styles, th, 5, true, RenderOptions{})
 	if len(result) == 0 {
 		t.Error("should produce some output even with tiny width")
 	}
@@ -310,6 +372,30 @@ index abc..def 100644
 	}
 }
 
+func TestParseDiff_NoNewlineMarker(t *testing.T) {
+	t.Parallel()
+	raw := "@@ -1,2 +1,2 @@\n keep\n-old\n\\ No newline at end of file\n+new\n"
+	parsed := ParseDiff(raw)
+	if len(parsed.Lines) != 4 {
+		t.Fatalf("expected 4 lines (marker folded), got %d", len(parsed.Lines))
+	}
+	removed, added := parsed.Lines[2], parsed.Lines[3]
+	if removed.Type != LineRemoved || !removed.NoNewline {
+		t.Errorf("removed line should carry NoNewline: %+v", removed)
+	}
+	if added.NoNewline {
+		t.Error("added line should not carry NoNewline")
+	}
+
+	styles, th := testStyles()
+	if out := RenderDiff(parsed, "f.txt", styles, th, 100); strings.Count(out, "(no newline)") != 1 {
+		t.Errorf("unified render should show one marker:\n%s", out)
+	}
+	if out := RenderSplitDiff(parsed, "f.txt", styles, th, 120, RenderOptions{}); strings.Count(out, "(no newline)") != 1 {
+		t.Errorf("split render should show one marker:\n%s", out)
+	}
+}
+
 func TestParseDiff_BinaryFile(t *testing.T) {
 	t.Parallel()
 	raw := "Binary files a/img.png and b/img.png differ"
@@ -371,21 +457,35 @@ func TestParseDiff_Empty(t *testing.T) {
 
 func TestParseDiff_Truncation(t *testing.T) {
 	t.Parallel()
-	// Build a diff that exceeds maxDiffLines
+	const limit = 50
 	var b strings.Builder
-	b.WriteString("@@ -1,20000 +1,20000 @@\n")
-	for range maxDiffLines + 10 {
+	b.WriteString("@@ -1,200 +1,200 @@\n")
+	for range limit + 10 {
 		b.WriteString("+line\n")
 	}
-	parsed := ParseDiff(b.String())
-	// Should be capped at maxDiffLines+1 (the truncation marker)
-	if len(parsed.Lines) > maxDiffLines+2 {
-		t.Errorf("expected truncation, got %d lines", len(parsed.Lines))
+	parsed := ParseDiffLimit(b.String(), limit)
+	if len(parsed.Lines) != limit || !parsed.Truncated {
+		t.Errorf("got %d lines, truncated=%v; want %d, true", len(parsed.Lines), parsed.Truncated, limit)
+	}
+
+	full := ParseDiffLimit(b.String(), 0)
+	if len(full.Lines) != limit+11 || full.Truncated {
+		t.Errorf("limit 0: got %d lines, truncated=%v; want all %d", len(full.Lines), full.Truncated, limit+11)
+	}
+	if ParseDiff(b.String()).Truncated {
+		t.Error("default limit should not cut a short diff")
+	}
+}
+
+func TestRenderTruncationBanner(t *testing.T) {
+	t.Parallel()
+	styles, _ := testStyles()
+	got := RenderTruncationBanner(10000, "press X for full view", styles, 80)
+	if !strings.Contains(got, "Diff truncated at 10000 lines — press X for full view") {
+		t.Errorf("banner = %q", got)
 	}
-	// Last line should be the truncation marker
-	last := parsed.Lines[len(parsed.Lines)-1]
-	if last.Type != LineHunkHeader || !strings.Contains(last.Content, "truncated") {
-		t.Errorf("expected truncation marker, got %+v", last)
+	if got := RenderTruncationBanner(5, "", styles, 80); strings.Contains(got, "—") {
+		t.Errorf("banner without hint = %q", got)
 	}
 }
 
@@ -414,6 +514,162 @@ func TestRenderDiff_Basic(t *testing.T) {
 	}
 }
 
+func TestRenderDiffRelative_NumbersByDistance(t *testing.T) {
+	t.Parallel()
+	parsed := ParsedDiff{Lines: []DiffLine{
+		{Type: LineContext, Content: "a", OldNum: 40, NewNum: 40},
+		{Type: LineContext, Content: "b", OldNum: 41, NewNum: 41},
+		{Type: LineAdded, Content: "c", OldNum: -1, NewNum: 42},
+		{Type: LineContext, Content: "d", OldNum: 42, NewNum: 43},
+	}}
+	styles, th := testStyles()
+	lines := strings.Split(RenderDiffRelative(parsed, "test.txt", styles, th, 100, 1, RenderOptions{}), "\n")
+	if !strings.Contains(lines[1], "41") {
+		t.Errorf("cursor line should keep absolute number, got %q", lines[1])
+	}
+	if strings.Contains(lines[0], "40") || !strings.Contains(lines[0], "   1") {
+		t.Errorf("line above cursor should show distance 1, got %q", lines[0])
+	}
+	if strings.Contains(lines[3], "43") || !strings.Contains(lines[3], "   2") {
+		t.Errorf("line two below cursor should show distance 2, got %q", lines[3])
+	}
+}
+
+func TestChangesOnly(t *testing.T) {
+	t.Parallel()
+	parsed := ParsedDiff{Lines: []DiffLine{
+		{Type: LineHunkHeader, Content: "-10,7 +10,7", OldNum: -1, NewNum: -1},
+		{Type: LineContext, Content: "a", OldNum: 10, NewNum: 10},
+		{Type: LineRemoved, Content: "b", OldNum: 11, NewNum: -1},
+		{Type: LineAdded, Content: "B", OldNum: -1, NewNum: 11},
+		{Type: LineContext, Content: "c", OldNum: 12, NewNum: 12},
+		{Type: LineContext, Content: "d", OldNum: 13, NewNum: 13},
+		{Type: LineAdded, Content: "e", OldNum: -1, NewNum: 14},
+		{Type: LineContext, Content: "f", OldNum: 14, NewNum: 15},
+		{Type: LineHunkHeader, Content: "-40,3 +41,3", OldNum: -1, NewNum: -1},
+		{Type: LineContext, Content: "x", OldNum: 40, NewNum: 41},
+		{Type: LineRemoved, Content: "y", OldNum: 41, NewNum: -1},
+	}}
+	got := changesOnly(parsed).Lines
+	want := []string{"-10,7 +10,7", "b", "B", "…", "e", "-40,3 +41,3", "y"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines %+v, want %v", len(got), got, want)
+	}
+	for i, w := range want {
+		if got[i].Content != w {
+			t.Errorf("line %d = %q, want %q", i, got[i].Content, w)
+		}
+	}
+	if got[4].NewNum != 14 || got[6].OldNum != 41 {
+		t.Errorf("kept lines should keep their numbers: %+v %+v", got[4], got[6])
+	}
+}
+
+func TestRenderDiff_LongLineClipped(t *testing.T) {
+	t.Parallel()
+	long := strings.Repeat("x", 5000)
+	parsed := ParsedDiff{Lines: []DiffLine{
+		{Type: LineRemoved, Content: "short", OldNum: 1, NewNum: -1},
+		{Type: LineAdded, Content: long, OldNum: -1, NewNum: 1, NoNewline: true},
+		{Type: LineContext, Content: long, OldNum: 2, NewNum: 2},
+	}}
+	styles, th := testStyles()
+	renders := map[string]string{
+		"unified": RenderDiff(parsed, "min.js", styles, th, 80),
+		"split":   RenderSplitDiff(parsed, "min.js", styles, th, 80, RenderOptions{}),
+		"new":     RenderNewFile("short\n"+long, "min.js", styles, th, 80),
+		"deleted": RenderDeletedFile("short\n"+long, "min.js", styles, th, 80),
+	}
+	for name, out := range renders {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			rows := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+			want := lipgloss.Width(rows[0])
+			for i, row := range rows {
+				if w := lipgloss.Width(row); w > 80 || w != want {
+					t.Errorf("row %d width = %d, want %d (<= 80)", i, w, want)
+				}
+			}
+			if !strings.Contains(rows[1], overflowMarker) {
+				t.Errorf("clipped row should end in %q: %q", overflowMarker, rows[1])
+			}
+			if strings.Contains(out, "no newline") {
+				t.Error("no-newline note should be dropped from a clipped row")
+			}
+		})
+	}
+}
+
+func TestFencedDiff_KeepsLongLines(t *testing.T) {
+	t.Parallel()
+	long := strings.Repeat("x", 5000)
+	out := fencedDiff(newFilePatch("f.txt", "short\n"+long+"\n"))
+	if strings.Contains(out, overflowMarker) {
+		t.Errorf("exported diff should not clip lines, got %q", overflowMarker)
+	}
+	if !strings.Contains(out, "\n+"+long+"\n") {
+		t.Error("exported diff should keep the whole long line")
+	}
+}
+
+func TestRenderDiff_GutterModes(t *testing.T) {
+	t.Parallel()
+	parsed := ParsedDiff{Lines: []DiffLine{
+		{Type: LineHunkHeader, Content: "func main()", OldNum: -1, NewNum: -1},
+		{Type: LineRemoved, Content: "old", OldNum: 17, NewNum: -1},
+		{Type: LineAdded, Content: "new", OldNum: -1, NewNum: 23},
+	}}
+	tests := []struct {
+		gutter    string
+		codeWidth int
+		shows     []string
+		hides     []string
+	}{
+		{"", 69, []string{"17", "23"}, nil},
+		{"both", 69, []string{"17", "23"}, nil},
+		{"old", 74, []string{"17"}, []string{"23"}},
+		{"new", 74, []string{"23"}, []string{"17"}},
+		{"none", 79, nil, []string{"17", "23"}},
+	}
+	for _, tt := range tests {
+		t.Run("gutter_"+tt.gutter, func(t *testing.T) {
+			t.Parallel()
+			if got := codeWidth(80, tt.gutter); got != tt.codeWidth {
+				t.Errorf("codeWidth = %d, want %d", got, tt.codeWidth)
+			}
+			styles, th := testStyles()
+			styles.Gutter = tt.gutter
+			out := strings.TrimSuffix(RenderDiff(parsed, "test.txt", styles, th, 80), "\n")
+			for i, line := range strings.Split(out, "\n")[1:] {
+				if w := lipgloss.Width(line); w != 79 {
+					t.Errorf("line %d width = %d, want 79", i+1, w)
+				}
+			}
+			for _, s := range tt.shows {
+				if !strings.Contains(out, s) {
+					t.Errorf("gutter %q should show %s", tt.gutter, s)
+				}
+			}
+			for _, s := range tt.hides {
+				if strings.Contains(out, s) {
+					t.Errorf("gutter %q should hide %s", tt.gutter, s)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderDiffRelative_NegativeCursorIsAbsolute(t *testing.T) {
+	t.Parallel()
+	parsed := ParsedDiff{Lines: []DiffLine{
+		{Type: LineContext, Content: "a", OldNum: 40, NewNum: 40},
+	}}
+	styles, th := testStyles()
+	if got, want := RenderDiffRelative(parsed, "t.txt", styles, th, 100, -1, RenderOptions{}), RenderDiff(parsed, "t.txt", styles, th, 100); got != want {
+		t.Errorf("negative cursor should match RenderDiff\ngot  %q\nwant %q", got, want)
+	}
+}
+
 func TestRenderDiff_Binary(t *testing.T) {
 	t.Parallel()
 	parsed := ParsedDiff{Binary: true}
@@ -437,3 +693,115 @@ func TestRenderNewFile_Basic(t *testing.T) {
 		t.Errorf("expected 3 lines, got %d", len(lines))
 	}
 }
+
+func TestParseNewFile(t *testing.T) {
+	t.Parallel()
+	parsed := ParseNewFile("a\nb\n")
+	if len(parsed.Lines) != 2 || parsed.Lines[1].Type != LineAdded || parsed.Lines[1].NewNum != 2 || parsed.Truncated {
+		t.Errorf("small file = %+v", parsed)
+	}
+	if parsed = ParseNewFile(strings.Repeat("x\n", maxDiffLines+5)); len(parsed.Lines) != maxDiffLines || !parsed.Truncated {
+		t.Errorf("long file: %d lines truncated=%v, want %d and true", len(parsed.Lines), parsed.Truncated, maxDiffLines)
+	}
+	if parsed = ParseNewFile("\x00bin"); !parsed.Binary || parsed.Lines != nil {
+		t.Errorf("binary file = %+v", parsed)
+	}
+}
+
+func TestRenderDeletedFile(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	result := RenderDeletedFile("line1\nline2", "test.go", styles, th, 100)
+	lines := strings.Split(strings.TrimRight(result, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if !strings.Contains(lines[1], "   2") || !strings.Contains(lines[1], "- ") || !strings.Contains(lines[1], "line2") {
+		t.Errorf("line should show the old number and a removed marker, got %q", lines[1])
+	}
+	parsed := deletedFileDiff("a\nb")
+	if len(parsed.Lines) != 2 || parsed.Lines[1].Type != LineRemoved || parsed.Lines[1].OldNum != 2 || parsed.Lines[1].NewNum != -1 {
+		t.Errorf("deletedFileDiff = %+v", parsed.Lines)
+	}
+}
+
+func TestRenderHexDiff_Rows(t *testing.T) {
+	t.Parallel()
+	styles, _ := testStyles()
+	old := []byte("0123456789abcdefXY")
+	cur := []byte("0123456789abcdefXZ!")
+	lines := strings.Split(strings.TrimRight(RenderHexDiff(old, cur, styles), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(lines))
+	}
+	if !strings.HasPrefix(lines[1], "00000010") {
+		t.Errorf("second row offset wrong: %q", lines[1])
+	}
+	if !strings.Contains(lines[0], "30313233 34353637") {
+		t.Errorf("first row hex wrong: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "5859") || !strings.Contains(lines[1], "585a21") {
+		t.Errorf("second row should show both sides: %q", lines[1])
+	}
+}
+
+func TestRenderDiffCompact(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	parsed := ParseDiff("@@ -1,2 +1,2 @@ func main() {\n ctx\n-old\n+new\n")
+	out := RenderDiffCompact(parsed, "main.go", styles, th, 80)
+	want := []string{"@@ func main() {", " ctx", "-old", "+new"}
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), out)
+	}
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("line %d=%q, want %q (no gutter or padding)", i, lines[i], w)
+		}
+	}
+}
+
+func TestSplitWhitespaceErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, in, lead, body, trail string
+	}{
+		{"clean", "\tx := 1", "", "\tx := 1", ""},
+		{"trailing spaces", "x := 1  ", "", "x := 1", "  "},
+		{"trailing tab", "x\t", "", "x", "\t"},
+		{"space before tab", " \tx", " \t", "x", ""},
+		{"space before tabs", "\t \t\tx", "\t \t\t", "x", ""},
+		{"spaces after tab", "\t  x", "", "\t  x", ""},
+		{"space before tab then alignment", " \t  x", " \t", "  x", ""},
+		{"blank with spaces", "   ", "", "", "   "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			lead, body, trail := splitWhitespaceErrors(tt.in)
+			if lead != tt.lead || body != tt.body || trail != tt.trail {
+				t.Errorf("got (%q, %q, %q), want (%q, %q, %q)", lead, body, trail, tt.lead, tt.body, tt.trail)
+			}
+		})
+	}
+}
+
+func TestHighlightContent_WhitespaceErrorsOnAddedOnly(t *testing.T) {
+	t.Parallel()
+	styles, _ := testStyles()
+	styles.WhitespaceError = styles.WhitespaceError.SetString("!")
+	opts := RenderOptions{WhitespaceErrors: true}
+	added := highlightContent(DiffLine{Type: LineAdded, Content: "x "}, "a.txt", "", styles, opts)
+	if !strings.Contains(added, "!") {
+		t.Errorf("added line should mark trailing whitespace: %q", added)
+	}
+	ctx := highlightContent(DiffLine{Type: LineContext, Content: "x "}, "a.txt", "", styles, opts)
+	if strings.Contains(ctx, "!") {
+		t.Errorf("context line should not be marked: %q", ctx)
+	}
+	off := highlightContent(DiffLine{Type: LineAdded, Content: "x "}, "a.txt", "", styles, RenderOptions{})
+	if strings.Contains(off, "!") {
+		t.Errorf("marking is off by default: %q", off)
+	}
+}
@@ -0,0 +1,44 @@
+package ui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// External diff/merge tools: hand the selected file to git difftool or git
+// mergetool, suspending the TUI until the tool exits.
+
+func (m Model) launchDiffTool() (tea.Model, tea.Cmd) {
+	if m.cursor >= len(m.files) {
+		return m, nil
+	}
+	f := m.files[m.cursor]
+	if f.untracked {
+		m.statusMsg = "untracked file: nothing to compare"
+		return m, nil
+	}
+	cmd := m.repo.DiffToolCmd(f.change.Path, f.change.Staged, m.ref, m.cfg.DiffTool)
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return externalToolDoneMsg{tool: "difftool", err: err}
+	})
+}
+
+func (m Model) launchMergeTool() (tea.Model, tea.Cmd) {
+	if m.reviewOnly || m.stagedOnly || m.ref != "" || m.cursor >= len(m.files) {
+		return m, nil
+	}
+	cmd := m.repo.MergeToolCmd(m.files[m.cursor].change.Path, m.cfg.MergeTool)
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return externalToolDoneMsg{tool: "mergetool", err: err}
+	})
+}
+
+// handleExternalToolDone reloads files and the diff, since a merge tool
+// (or an editable difftool) may have changed the working tree.
+func (m Model) handleExternalToolDone(msg externalToolDoneMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.statusMsg = msg.tool + " failed: " + msg.err.Error()
+	} else {
+		m.statusMsg = msg.tool + " done"
+	}
+	m.lastDiffContent = ""
+	m.prevCurs = -1
+	return m, tea.Batch(m.reloadFilesCmd(true), m.loadDiffCmd(false))
+}
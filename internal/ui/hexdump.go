@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+// hexRowBytes is the number of bytes shown per hexdump row.
+const hexRowBytes = 16
+
+// RenderHexDiff renders old and new bytes as side-by-side hexdumps in
+// 16-byte rows. Bytes that differ are highlighted on both sides.
+func RenderHexDiff(old, cur []byte, styles Styles) string {
+	n := max(len(old), len(cur))
+	var b strings.Builder
+	for off := 0; off < n; off += hexRowBytes {
+		b.WriteString(styles.DiffLineNum.Render(fmt.Sprintf("%08x", off)))
+		b.WriteString("  ")
+		b.WriteString(hexRow(old, cur, off, styles, true))
+		b.WriteString(styles.HelpDesc.Render(" │ "))
+		b.WriteString(hexRow(cur, old, off, styles, false))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// hexRow renders one row of data starting at off, grouped in 4-byte words.
+// Bytes differing from other are styled as removed (old side) or added.
+func hexRow(data, other []byte, off int, styles Styles, oldSide bool) string {
+	diffStyle := styles.DiffAdded
+	if oldSide {
+		diffStyle = styles.DiffRemoved
+	}
+	var b strings.Builder
+	for i := off; i < off+hexRowBytes; i++ {
+		if i > off && (i-off)%4 == 0 {
+			b.WriteByte(' ')
+		}
+		if i >= len(data) {
+			b.WriteString("  ")
+			continue
+		}
+		cell := fmt.Sprintf("%02x", data[i])
+		if i >= len(other) || other[i] != data[i] {
+			b.WriteString(diffStyle.Render(cell))
+		} else {
+			b.WriteString(cell)
+		}
+	}
+	return b.String()
+}
+
+// loadHexDiff reads the old and new versions of a binary file and renders
+// them as a hexdump. Old is ref, HEAD (staged) or the index; new is the
+// index (staged) or the working tree. Above maxBytes the plain binary
+// banner is shown instead.
+func loadHexDiff(repo *git.Repo, f fileItem, ref string, maxBytes int, styles Styles, width int) string {
+	path := f.change.Path
+	var old, cur []byte
+	if !f.untracked {
+		oldRef := ref
+		if oldRef == "" && f.change.Staged {
+			oldRef = "HEAD"
+		}
+		old, _ = repo.ShowBlob(oldRef, path) // missing on added files
+	}
+	if f.change.Staged {
+		cur, _ = repo.ShowBlob("", path)
+	} else if raw, err := repo.ReadFileContent(path); err == nil {
+		cur = []byte(raw)
+	}
+	if max(len(old), len(cur)) > maxBytes {
+		return RenderBinaryFile(styles, width) + "\n" +
+			styles.HelpDesc.Render(fmt.Sprintf("  larger than hexdump_max_bytes (%d)", maxBytes))
+	}
+	return RenderHexDiff(old, cur, styles)
+}
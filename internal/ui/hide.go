@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// hiddenBy reports whether p matches one of the hide_patterns. Patterns
+// match the full repo-relative path; a pattern without a slash also matches
+// the file name at any depth, like .gitignore.
+func hiddenBy(patterns []string, p string) bool {
+	for _, pat := range patterns {
+		if ok, _ := path.Match(pat, p); ok {
+			return true
+		}
+		if !strings.Contains(pat, "/") {
+			if ok, _ := path.Match(pat, path.Base(p)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// partitionHidden splits files into those shown and those hidden by patterns.
+func partitionHidden(files []fileItem, patterns []string) (shown, hidden []fileItem) {
+	if len(patterns) == 0 {
+		return files, nil
+	}
+	for _, f := range files {
+		if hiddenBy(patterns, f.change.Path) {
+			hidden = append(hidden, f)
+		} else {
+			shown = append(shown, f)
+		}
+	}
+	return shown, hidden
+}
+
+// toggleHidden reveals files matching hide_patterns, or hides them again.
+func (m Model) toggleHidden() (tea.Model, tea.Cmd) {
+	if len(m.cfg.HidePatterns) == 0 {
+		m.statusMsg = "no hide_patterns configured"
+		return m, nil
+	}
+	m.showHidden = !m.showHidden
+	return m, m.reloadFilesCmd(true)
+}
+
+// hiddenItem is the status bar note for files kept out of the list.
+func (m Model) hiddenItem() string {
+	if len(m.hiddenFiles) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d hidden (H)", len(m.hiddenFiles))
+}
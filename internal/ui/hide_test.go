@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+func TestHiddenBy(t *testing.T) {
+	t.Parallel()
+	patterns := []string{"package-lock.json", "*.pb.go", "vendor/*"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"package-lock.json", true},
+		{"web/package-lock.json", true},
+		{"api/v1/user.pb.go", true},
+		{"vendor/lib.go", true},
+		{"internal/vendor/lib.go", false},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		if got := hiddenBy(patterns, tt.path); got != tt.want {
+			t.Errorf("hiddenBy(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHandleFilesRefreshed_HidesPatterns(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.cfg.HidePatterns = []string{"*.lock"}
+	files := []fileItem{
+		{change: git.FileChange{Path: "main.go"}},
+		{change: git.FileChange{Path: "Cargo.lock"}},
+	}
+
+	result, _ := m.handleFilesRefreshed(filesRefreshedMsg{files: files})
+	rm := result.(Model)
+	if len(rm.files) != 1 || rm.files[0].change.Path != "main.go" {
+		t.Fatalf("files = %+v, want main.go only", rm.files)
+	}
+	if rm.hiddenItem() != "1 hidden (H)" {
+		t.Errorf("hiddenItem = %q", rm.hiddenItem())
+	}
+
+	rm.showHidden = true
+	result, _ = rm.handleFilesRefreshed(filesRefreshedMsg{files: files})
+	if rm = result.(Model); len(rm.files) != 2 || rm.hiddenItem() != "" {
+		t.Errorf("revealed: files=%d hiddenItem=%q", len(rm.files), rm.hiddenItem())
+	}
+}
@@ -12,23 +12,95 @@ import (
 )
 
 var (
-	lexerCache   sync.Map // ext -> chroma.Lexer
-	chromaStyle  *chroma.Style
-	chromaStyleMu sync.Once
+	lexerCache    sync.Map // ext -> chroma.Lexer
+	analysedCache sync.Map // path -> chroma.Lexer, for names Match can't place
+	chromaStyleMu sync.RWMutex
+	chromaStyle   *chroma.Style
+	chromaName    string
 )
 
-// initChromaStyle initializes the chroma style (call once).
+// initChromaStyle selects the chroma style. Cheap when the name is unchanged,
+// so renderers call it every time; a theme switch swaps the style.
 func initChromaStyle(styleName string) {
-	chromaStyleMu.Do(func() {
-		chromaStyle = styles.Get(styleName)
-		if chromaStyle == nil {
-			chromaStyle = styles.Get("monokai")
-		}
-	})
+	chromaStyleMu.Lock()
+	defer chromaStyleMu.Unlock()
+	if chromaStyle != nil && chromaName == styleName {
+		return
+	}
+	chromaName = styleName
+	chromaStyle = styles.Get(styleName)
+	if chromaStyle == nil {
+		chromaStyle = styles.Get("monokai")
+	}
 }
 
-// getLexer returns a cached Chroma lexer for the given filename.
+func currentChromaStyle() *chroma.Style {
+	chromaStyleMu.RLock()
+	defer chromaStyleMu.RUnlock()
+	return chromaStyle
+}
+
+// headLines is how much of a file analyseLexer looks at.
+const headLines = 20
+
+// getLexer returns a cached Chroma lexer for the given filename. When the
+// name alone doesn't identify a language (e.g. an extensionless script), it
+// is the lexer analyseLexer found for the path, if any.
 func getLexer(filename string) chroma.Lexer {
+	lexer := matchLexer(filename)
+	if lexer != lexers.Fallback {
+		return lexer
+	}
+	if cached, ok := analysedCache.Load(filename); ok {
+		return cached.(chroma.Lexer)
+	}
+	return lexer
+}
+
+// analyseLexer picks the lexer for a filename that doesn't identify a
+// language from head, the start of the file. A hit is cached per path; a
+// miss is not, so a file that gains a shebang is highlighted on reload.
+func analyseLexer(filename, head string) {
+	if head == "" || lexerKnown(filename) {
+		return
+	}
+	if analysed := lexers.Analyse(head); analysed != nil {
+		analysedCache.Store(filename, chroma.Coalesce(analysed))
+	}
+}
+
+// lexerKnown reports whether getLexer has a real lexer for filename.
+func lexerKnown(filename string) bool {
+	return getLexer(filename) != lexers.Fallback
+}
+
+// diffHead returns the first lines of the file a diff shows when its first
+// hunk starts at line 1 of the new side, else "".
+func diffHead(parsed ParsedDiff) string {
+	var b strings.Builder
+	next := 1
+	for _, dl := range parsed.Lines {
+		if dl.Type == LineRemoved || (dl.Type == LineHunkHeader && next == 1) {
+			continue
+		}
+		if dl.NewNum != next || next > headLines {
+			break
+		}
+		b.WriteString(dl.Content + "\n")
+		next++
+	}
+	return b.String()
+}
+
+// fileHead returns the first headLines lines of content.
+func fileHead(content string) string {
+	lines := strings.SplitAfterN(content, "\n", headLines+1)
+	return strings.Join(lines[:min(len(lines), headLines)], "")
+}
+
+// matchLexer returns the filename-matched lexer, cached by extension, or the
+// shared Fallback lexer when nothing matches.
+func matchLexer(filename string) chroma.Lexer {
 	ext := filepath.Ext(filename)
 	if ext == "" {
 		ext = filepath.Base(filename)
@@ -41,8 +113,9 @@ func getLexer(filename string) chroma.Lexer {
 	lexer := lexers.Match(filename)
 	if lexer == nil {
 		lexer = lexers.Fallback
+	} else {
+		lexer = chroma.Coalesce(lexer)
 	}
-	lexer = chroma.Coalesce(lexer)
 	lexerCache.Store(ext, lexer)
 	return lexer
 }
@@ -50,7 +123,8 @@ func getLexer(filename string) chroma.Lexer {
 // highlightLine applies syntax highlighting to a code line.
 // It applies Chroma foreground colors but preserves the background from bgColor.
 func highlightLine(content, filename, bgColor string) string {
-	if chromaStyle == nil || content == "" {
+	style := currentChromaStyle()
+	if style == nil || content == "" {
 		return content
 	}
 
@@ -62,7 +136,7 @@ func highlightLine(content, filename, bgColor string) string {
 
 	var b strings.Builder
 	for _, token := range iterator.Tokens() {
-		entry := chromaStyle.Get(token.Type)
+		entry := style.Get(token.Type)
 		fg := tokenForeground(entry)
 		if fg != "" {
 			style := lipgloss.NewStyle().Foreground(lipgloss.Color(fg))
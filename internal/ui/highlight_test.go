@@ -43,3 +43,42 @@ func TestHighlightLine_GoCode(t *testing.T) {
 		t.Error("expected non-empty highlighted output")
 	}
 }
+
+func TestAnalyseLexer_ShebangScript(t *testing.T) {
+	t.Parallel()
+	if lexerKnown("scripts/shebang-deploy") {
+		t.Fatal("an extensionless name should need analysis")
+	}
+	analyseLexer("scripts/shebang-deploy", "#!/bin/bash\necho hi\n")
+	if got := getLexer("scripts/shebang-deploy").Config().Name; got != "Bash" {
+		t.Errorf("lexer = %q, want Bash", got)
+	}
+
+	// A miss isn't cached: the file can still be analysed later.
+	analyseLexer("scripts/shebang-later", "plain words\n")
+	if lexerKnown("scripts/shebang-later") {
+		t.Error("a miss should leave the fallback lexer")
+	}
+	analyseLexer("scripts/shebang-later", "#!/bin/sh\necho hi\n")
+	if got := getLexer("scripts/shebang-later").Config().Name; got != "Bash" {
+		t.Errorf("lexer after a miss = %q, want Bash", got)
+	}
+}
+
+func TestDiffHead(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, raw, want string
+	}{
+		{"from_line_1", "@@ -1,2 +1,3 @@\n-#!/bin/sh\n+#!/bin/bash\n set -e\n+echo hi\n@@ -9,1 +10,1 @@\n-x\n+y\n", "#!/bin/bash\nset -e\necho hi\n"},
+		{"later_hunk", "@@ -5,1 +5,1 @@\n-a\n+b\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := diffHead(ParseDiff(tt.raw)); got != tt.want {
+				t.Errorf("diffHead = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
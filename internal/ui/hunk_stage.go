@@ -0,0 +1,452 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+// Hunk and line staging: ]/[ step through the diff's hunks and s stages
+// the current one, or unstages it when the diff shown is the staged one.
+// J/K select lines instead, and s then stages only the selected changes.
+// Either way s first shows the patch it built; s or y applies it.
+
+// currentHunk returns the index in diffHunks of the hunk s acts on: the one
+// holding the diff-line cursor when the gutter is relative, else the hunk
+// picked with ]/[ while its header is on screen, else the hunk being read
+// at the top of the view. -1 if the diff has no hunks.
+func (m Model) currentHunk() int {
+	if len(m.diffHunks) == 0 {
+		return -1
+	}
+	row := m.viewport.YOffset
+	if m.relativeGutterActive() {
+		row = m.diffCursor
+	} else if m.pickedHunkVisible() {
+		return m.hunkCursor
+	}
+	return m.hunkAt(row)
+}
+
+// hunkAt returns the index in diffHunks of the hunk holding rendered row,
+// counting rows above the first header as part of it.
+func (m Model) hunkAt(row int) int {
+	cur := 0
+	for i, h := range m.diffHunks {
+		if h.line > row {
+			break
+		}
+		cur = i
+	}
+	return cur
+}
+
+// pickedHunkVisible reports whether a hunk was picked with ]/[ and its
+// header is still on screen.
+func (m Model) pickedHunkVisible() bool {
+	if !m.hunkPicked || m.hunkCursor >= len(m.diffHunks) {
+		return false
+	}
+	line := m.diffHunks[m.hunkCursor].line
+	return line >= m.viewport.YOffset && line < m.viewport.YOffset+m.viewport.Height
+}
+
+// jumpHunk moves to the header of the next (delta 1) or previous (delta -1)
+// hunk. With a line cursor inside a hunk, [ first goes to its own header.
+func (m Model) jumpHunk(delta int) (tea.Model, tea.Cmd) {
+	cur := m.currentHunk()
+	if cur < 0 {
+		return m, nil
+	}
+	next := cur + delta
+	if delta < 0 && m.relativeGutterActive() && m.diffCursor > m.diffHunks[cur].line {
+		next = cur
+	}
+	next = min(max(next, 0), len(m.diffHunks)-1)
+	m.hunkCursor, m.hunkPicked = next, true
+	line := m.diffHunks[next].line
+	if m.relativeGutterActive() {
+		return m.moveDiffCursor(line - m.diffCursor)
+	}
+	m.viewport.SetYOffset(line)
+	return m, nil
+}
+
+// stageHunk stages the current hunk of an unstaged diff, or unstages it
+// from a staged one. Whole-file diffs without hunks are refused with a hint.
+func (m Model) stageHunk() (tea.Model, tea.Cmd) {
+	if m.reviewOnly || m.stagedOnly || m.ref != "" || len(m.files) == 0 {
+		return m, nil
+	}
+	if refusal := m.partialStageRefusal(); refusal != "" {
+		m.statusMsg = refusal
+		return m, nil
+	}
+	f := m.files[m.cursor]
+	idx := m.currentHunk()
+	if idx < 0 {
+		m.statusMsg = "no hunk to stage"
+		return m, nil
+	}
+	verb := "stage"
+	if f.change.Staged {
+		verb = "unstage"
+	}
+	m.hunkCursor = idx
+	// Word diffs can't be applied; the hunks are the same in a line diff.
+	repo := m.repo.WithWordDiff(false)
+	plan := stagePlan{
+		path:   f.change.Path,
+		staged: f.change.Staged,
+		what:   fmt.Sprintf("%s hunk %d of %d", verb, idx+1, len(m.diffHunks)),
+		offset: m.viewport.YOffset,
+	}
+	return m, func() tea.Msg {
+		hunk, err := hunkAt(repo, plan.path, plan.staged, idx)
+		plan.hunk = &hunk
+		return stagePreviewMsg{plan: plan, err: err}
+	}
+}
+
+// partialStageRefusal says why the selected file can't be staged in parts,
+// or is "" when it can.
+func (m Model) partialStageRefusal() string {
+	f := m.files[m.cursor]
+	switch {
+	case f.untracked:
+		return "untracked file has no hunks: stage it whole with tab"
+	case f.change.Binary:
+		return "binary file has no hunks: stage it whole with tab"
+	case m.fullFile:
+		return "full file view is one hunk: press f to stage by hunk"
+	}
+	return ""
+}
+
+// hunkAt returns the idx-th hunk of path's unstaged or staged diff, as git
+// has the diff now.
+func hunkAt(repo *git.Repo, path string, staged bool, idx int) (git.ParsedHunk, error) {
+	raw, err := repo.DiffFile(path, staged, "")
+	if err != nil {
+		return git.ParsedHunk{}, err
+	}
+	hunks := git.ParseHunks(raw)
+	if idx >= len(hunks) {
+		return git.ParsedHunk{}, fmt.Errorf("hunk %d is gone, the diff changed", idx+1)
+	}
+	return hunks[idx], nil
+}
+
+// stagePlan is a hunk or line selection about to be staged or unstaged.
+// Its patch is shown for confirmation before it touches the index, and the
+// patch shown is the one applied.
+type stagePlan struct {
+	path    string
+	staged  bool            // unstage: the patch comes from the staged diff
+	hunk    *git.ParsedHunk // s on a hunk
+	patches []string        // s on a line selection, applied bottom-up
+	what    string          // e.g. "stage hunk 2 of 3", for the card title
+	offset  int             // diff scroll to go back to
+}
+
+// patch is the unified diff the plan applies.
+func (p stagePlan) patch() string {
+	if p.hunk != nil {
+		return git.FilePatch(p.path, *p.hunk)
+	}
+	return strings.Join(p.patches, "")
+}
+
+// apply stages or unstages the plan's changes in the index.
+func (p stagePlan) apply(repo *git.Repo) error {
+	if p.hunk != nil {
+		if p.staged {
+			return repo.UnstageHunk(p.path, *p.hunk)
+		}
+		return repo.StageHunk(p.path, *p.hunk)
+	}
+	for i := len(p.patches) - 1; i >= 0; i-- {
+		if err := repo.ApplyPartialPatch(p.patches[i], true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleStagePreview shows the patch s built in the diff panel. Like an
+// explanation, it is dropped if the diff view moved to another file first.
+func (m Model) handleStagePreview(msg stagePreviewMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.statusMsg = "stage failed: " + firstLine(msg.err.Error())
+		return m, nil
+	}
+	if m.mode != modeDiff || m.cursor >= len(m.files) || m.files[m.cursor].change.Path != msg.plan.path {
+		m.statusMsg = msg.plan.what + " dropped: moved on before the patch was ready"
+		return m, nil
+	}
+	m.mode = modeStagePreview
+	m.stagePlan = &msg.plan
+	m.statusMsg = ""
+	styles := m.styles
+	styles.Gutter = m.cfg.Gutter
+	m.viewport.SetContent(RenderDiff(ParseDiff(msg.plan.patch()), msg.plan.path, styles, m.theme, m.diffContentWidth()))
+	m.viewport.GotoTop()
+	return m, nil
+}
+
+func (m Model) updateStagePreviewMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "s", "y", "enter":
+		return m.applyStagePlan()
+	case "esc", "n", "q":
+		m = m.closeStagePreview()
+		return m, m.rerenderDiffCmd()
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// closeStagePreview goes back to the diff where it was left.
+func (m Model) closeStagePreview() Model {
+	if m.stagePlan != nil {
+		m.viewport.YOffset = m.stagePlan.offset // kept by the diff's SetContent
+	}
+	m.stagePlan = nil
+	m.mode = modeDiff
+	m.lastDiffContent = ""
+	return m
+}
+
+// applyStagePlan applies the previewed patch and refreshes the file list,
+// keeping the file selected on the side its hunks are taken from.
+func (m Model) applyStagePlan() (tea.Model, tea.Cmd) {
+	if m.stagePlan == nil {
+		return m, nil
+	}
+	plan := *m.stagePlan
+	m = m.closeStagePreview()
+	m.selecting = false
+	m.statusMsg = pastTense(plan.what)
+	repo := m.repo
+	return m, func() tea.Msg {
+		err := plan.apply(repo)
+		msg := m.buildRefreshedFiles()
+		msg.err = err
+		msg.follow = plan.path
+		msg.hunkPath, msg.hunkStaged = plan.path, plan.staged
+		return msg
+	}
+}
+
+// pastTense turns a plan's "stage ..." or "unstage ..." into the status
+// shown once it is applied.
+func pastTense(what string) string {
+	verb, rest, _ := strings.Cut(what, " ")
+	return verb + "d " + rest
+}
+
+// restoreHunk goes back to the position of the hunk just staged, which now
+// holds the hunk after it, so a file's hunks can be taken in turn.
+func (m Model) restoreHunk() (tea.Model, tea.Cmd) {
+	m.hunkRestore = false
+	if len(m.diffHunks) == 0 {
+		return m, nil
+	}
+	m.hunkCursor, m.hunkPicked = min(m.hunkCursor, len(m.diffHunks)-1), true
+	line := m.diffHunks[m.hunkCursor].line
+	if m.relativeGutterActive() {
+		return m.moveDiffCursor(line)
+	}
+	m.viewport.SetYOffset(line)
+	return m, nil
+}
+
+// addedLineNums is removedLineNums for added lines: each parsed line's new
+// line number when it is added, -1 otherwise.
+func addedLineNums(parsed ParsedDiff) []int {
+	if parsed.Binary {
+		return nil
+	}
+	nums := make([]int, len(parsed.Lines))
+	for i, dl := range parsed.Lines {
+		nums[i] = -1
+		if dl.Type == LineAdded {
+			nums[i] = dl.NewNum
+		}
+	}
+	return nums
+}
+
+// markSelected returns parsed with lines lo through hi flagged as selected,
+// for the renderer to highlight. A negative lo marks nothing. parsed itself
+// is left as is, since it is kept for re-rendering.
+func markSelected(parsed ParsedDiff, lo, hi int) ParsedDiff {
+	if lo < 0 {
+		return parsed
+	}
+	parsed.Lines = slices.Clone(parsed.Lines)
+	for i := lo; i <= hi && i < len(parsed.Lines); i++ {
+		parsed.Lines[i].Selected = true
+	}
+	return parsed
+}
+
+// selRange returns the selected rows in order.
+func (m Model) selRange() (lo, hi int) {
+	return min(m.selAnchor, m.selEnd), max(m.selAnchor, m.selEnd)
+}
+
+// selectionStart is the row a new selection starts on: the diff-line cursor
+// when the gutter is relative, else the first changed line on screen. -1 if
+// there is none.
+func (m Model) selectionStart() int {
+	if m.relativeGutterActive() {
+		return m.diffCursor
+	}
+	end := min(m.viewport.YOffset+m.viewport.Height, len(m.diffKinds))
+	for i := m.viewport.YOffset; i < end; i++ {
+		if m.diffKinds[i] == LineAdded || m.diffKinds[i] == LineRemoved {
+			return i
+		}
+	}
+	return -1
+}
+
+// extendSelection starts a line selection, or moves its free end by delta
+// rows and keeps that end on screen.
+func (m Model) extendSelection(delta int) (tea.Model, tea.Cmd) {
+	if m.reviewOnly || m.stagedOnly || m.ref != "" || len(m.files) == 0 {
+		return m, nil
+	}
+	if refusal := m.partialStageRefusal(); refusal != "" {
+		m.statusMsg = refusal
+		return m, nil
+	}
+	switch {
+	case m.cfg.WordDiff:
+		m.statusMsg = "word diff has no lines to select: press w for a line diff"
+		return m, nil
+	case m.diffKinds == nil:
+		m.statusMsg = "line selection needs the unified or compact view"
+		return m, nil
+	}
+	if !m.selecting {
+		row := m.selectionStart()
+		if row < 0 {
+			m.statusMsg = "no changed line on screen to select"
+			return m, nil
+		}
+		m.selecting, m.selAnchor, m.selEnd = true, row, row
+	} else {
+		m.selEnd = min(max(m.selEnd+delta, 0), len(m.diffKinds)-1)
+	}
+	if m.selEnd < m.viewport.YOffset {
+		m.viewport.SetYOffset(m.selEnd)
+	} else if h := m.viewport.Height; h > 0 && m.selEnd >= m.viewport.YOffset+h {
+		m.viewport.SetYOffset(m.selEnd - h + 1)
+	}
+	if m.relativeGutterActive() {
+		m.diffCursor = m.selEnd
+	}
+	lo, hi := m.selRange()
+	verb := "stage"
+	if m.files[m.cursor].change.Staged {
+		verb = "unstage"
+	}
+	m.statusMsg = fmt.Sprintf("%s selected: s to %s, esc to clear", lineCount(hi-lo+1), verb)
+	return m, m.rerenderDiffCmd()
+}
+
+// stageLines stages the changed lines in the selection, or unstages them
+// from a staged diff. Context lines in it are ignored; a selection of only
+// context does nothing.
+func (m Model) stageLines() (tea.Model, tea.Cmd) {
+	if m.reviewOnly || m.stagedOnly || m.ref != "" || len(m.files) == 0 {
+		return m, nil
+	}
+	removed, added := map[int]bool{}, map[int]bool{}
+	lo, hi := m.selRange()
+	for row := lo; row <= hi; row++ {
+		if row < len(m.diffOldNums) && m.diffOldNums[row] > 0 {
+			removed[m.diffOldNums[row]] = true
+		}
+		if row < len(m.diffNewNums) && m.diffNewNums[row] > 0 {
+			added[m.diffNewNums[row]] = true
+		}
+	}
+	if len(removed) == 0 && len(added) == 0 {
+		m.statusMsg = "no changed lines selected"
+		return m, nil
+	}
+	f := m.files[m.cursor]
+	verb := "stage"
+	if f.change.Staged {
+		verb = "unstage"
+	}
+	m.hunkCursor = m.hunkAt(lo)
+	repo := m.repo.WithWordDiff(false) // the line numbers are a line diff's
+	plan := stagePlan{
+		path:   f.change.Path,
+		staged: f.change.Staged,
+		what:   verb + " " + lineCount(len(removed)+len(added)),
+		offset: m.viewport.YOffset,
+	}
+	return m, func() tea.Msg {
+		patches, err := linePatches(repo, plan.path, plan.staged, removed, added)
+		plan.patches = patches
+		return stagePreviewMsg{plan: plan, err: err}
+	}
+}
+
+// lineCount is n with "line" or "lines".
+func lineCount(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", n)
+}
+
+// linePatches builds the patches that stage the chosen lines of path's
+// unstaged diff, or unstage them from its staged diff: removals by old line
+// number, additions by new. Each hunk they touch becomes its own patch, to
+// be applied bottom-up so the line numbers of the hunks above still hold.
+func linePatches(repo *git.Repo, path string, staged bool, removed, added map[int]bool) ([]string, error) {
+	raw, err := repo.DiffFile(path, staged, "")
+	if err != nil {
+		return nil, err
+	}
+	keep := func(isRemoved bool, num int) bool {
+		if isRemoved {
+			return removed[num]
+		}
+		return added[num]
+	}
+	var patches []string
+	for _, h := range git.ParseHunks(raw) {
+		if p, ok := git.PartialHunk(h, keep, staged); ok {
+			patches = append(patches, git.FilePatch(path, p))
+		}
+	}
+	if len(patches) == 0 {
+		return nil, errors.New("the selected lines are gone, the diff changed")
+	}
+	return patches, nil
+}
+
+// indexOfChange returns the file with path p on the staged or unstaged
+// side, or -1.
+func indexOfChange(files []fileItem, p string, staged bool) int {
+	for i, f := range files {
+		if p != "" && f.change.Path == p && f.change.Staged == staged && !f.untracked {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,189 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+func TestCurrentHunkAndJump(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	m.viewport = viewport.New(40, 4)
+	m.viewport.SetContent(strings.Repeat("row\n", 30))
+	m.diffHunks = []hunkHeader{{line: 0, text: "first"}, {line: 6, text: "second"}, {line: 8, text: "third"}}
+
+	m.viewport.SetYOffset(7)
+	if got := m.currentHunk(); got != 1 {
+		t.Errorf("top row inside hunk 2: current = %d, want 1", got)
+	}
+	next, _ := m.jumpHunk(1)
+	m = next.(Model)
+	if got := m.currentHunk(); got != 2 || m.viewport.YOffset != 8 {
+		t.Errorf("] from hunk 2: current = %d offset = %d, want 2 and 8", got, m.viewport.YOffset)
+	}
+	if view := m.diffViewportView(); !strings.Contains(view, "▸ @@ third") {
+		t.Errorf("picked hunk should be marked: %q", view)
+	}
+	next, _ = m.jumpHunk(1)
+	if got := next.(Model).currentHunk(); got != 2 {
+		t.Errorf("] past the last hunk: current = %d, want 2", got)
+	}
+	next, _ = m.jumpHunk(-1)
+	m = next.(Model)
+	if got := m.currentHunk(); got != 1 {
+		t.Errorf("[ from hunk 3: current = %d, want 1", got)
+	}
+
+	// Scrolling the picked header away falls back to the hunk at the top.
+	m.viewport.SetYOffset(0)
+	if got := m.currentHunk(); got != 0 {
+		t.Errorf("picked header off screen: current = %d, want 0", got)
+	}
+	m.diffHunks = nil
+	if got := m.currentHunk(); got != -1 {
+		t.Errorf("no hunks: current = %d, want -1", got)
+	}
+}
+
+func TestStageHunk_Refused(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		file     fileItem
+		fullFile bool
+		want     string
+	}{
+		{"untracked", fileItem{change: git.FileChange{Path: "new.go"}, untracked: true}, false, "untracked file has no hunks: stage it whole with tab"},
+		{"binary", fileItem{change: git.FileChange{Path: "logo.png", Binary: true}}, false, "binary file has no hunks: stage it whole with tab"},
+		{"full_file", fileItem{change: git.FileChange{Path: "a.go"}}, true, "full file view is one hunk: press f to stage by hunk"},
+		{"no_hunks", fileItem{change: git.FileChange{Path: "gone.go", Status: git.StatusDeleted}}, false, "no hunk to stage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := newTestModel(t, []fileItem{tt.file})
+			m.mode = modeDiff
+			m.fullFile = tt.fullFile
+			result, cmd := m.stageHunk()
+			if rm := result.(Model); cmd != nil || rm.statusMsg != tt.want {
+				t.Errorf("cmd=%v status=%q, want %q", cmd != nil, rm.statusMsg, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleFilesRefreshed_KeepsHunkSide(t *testing.T) {
+	t.Parallel()
+	staged := fileItem{change: git.FileChange{Path: "a.go", Staged: true}}
+	unstaged := fileItem{change: git.FileChange{Path: "a.go"}}
+	m := newTestModel(t, []fileItem{unstaged})
+	m.mode = modeDiff
+
+	result, _ := m.handleFilesRefreshed(filesRefreshedMsg{files: []fileItem{staged, unstaged}, follow: "a.go", hunkPath: "a.go"})
+	rm := result.(Model)
+	if rm.cursor != 1 || !rm.hunkRestore {
+		t.Errorf("partly staged: cursor = %d restore = %v, want the unstaged entry", rm.cursor, rm.hunkRestore)
+	}
+	result, _ = rm.handleFilesRefreshed(filesRefreshedMsg{files: []fileItem{staged}, follow: "a.go", hunkPath: "a.go"})
+	rm = result.(Model)
+	if rm.cursor != 0 || rm.hunkRestore {
+		t.Errorf("fully staged: cursor = %d restore = %v, want the staged entry", rm.cursor, rm.hunkRestore)
+	}
+}
+
+func TestLineSelection(t *testing.T) {
+	t.Parallel()
+	parsed := ParseDiff("@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n")
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "f.txt"}}})
+	m.mode = modeDiff
+	m.viewport = viewport.New(40, 10)
+	m.viewport.SetContent(strings.Repeat("row\n", len(parsed.Lines)))
+	m.diffKinds = lineKinds(parsed)
+	m.diffOldNums = removedLineNums(parsed)
+	m.diffNewNums = addedLineNums(parsed)
+
+	next, _ := m.extendSelection(1)
+	m = next.(Model)
+	if lo, hi := m.selRange(); !m.selecting || lo != 2 || hi != 2 {
+		t.Fatalf("J starts on the first change: selecting=%v rows %d-%d, want 2-2", m.selecting, lo, hi)
+	}
+	next, _ = m.extendSelection(1)
+	m = next.(Model)
+	if lo, hi := m.selRange(); lo != 2 || hi != 3 || m.statusMsg != "2 lines selected: s to stage, esc to clear" {
+		t.Errorf("J again: rows %d-%d status %q", lo, hi, m.statusMsg)
+	}
+
+	// A selection of context lines only stages nothing.
+	ctx := m
+	ctx.selAnchor, ctx.selEnd = 1, 1
+	result, cmd := ctx.stageLines()
+	if rm := result.(Model); cmd != nil || rm.statusMsg != "no changed lines selected" {
+		t.Errorf("context only: cmd=%v status=%q", cmd != nil, rm.statusMsg)
+	}
+
+	result, _ = m.updateDiffMode(tea.KeyMsg{Type: tea.KeyEsc})
+	if rm := result.(Model); rm.selecting || rm.mode != modeDiff {
+		t.Errorf("esc should clear the selection and stay in the diff: selecting=%v mode=%v", rm.selecting, rm.mode)
+	}
+
+	m.selecting = false
+	m.cfg.WordDiff = true
+	result, _ = m.extendSelection(1)
+	if rm := result.(Model); rm.selecting || rm.statusMsg != "word diff has no lines to select: press w for a line diff" {
+		t.Errorf("word diff: selecting=%v status=%q", rm.selecting, rm.statusMsg)
+	}
+}
+
+func TestRenderDiff_SelectedLines(t *testing.T) {
+	t.Parallel()
+	parsed := ParseDiff("@@ -1,2 +1,2 @@\n-b\n+B\n")
+	parsed = markSelected(parsed, 2, 2)
+	styles, th := testStyles()
+	styles.Monochrome = true
+	rows := strings.Split(RenderDiff(parsed, "f.txt", styles, th, 60), "\n")
+	if strings.Contains(rows[1], "->") || !strings.Contains(rows[2], "+>") {
+		t.Errorf("only the selected row should carry the marker:\n%s\n%s", rows[1], rows[2])
+	}
+}
+
+func TestStagePreview(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	m.mode = modeDiff
+	m.viewport = viewport.New(60, 10)
+	hunk := git.ParsedHunk{Header: "@@ -1 +1 @@", Lines: []string{"-old line", "+new line"}}
+	preview := stagePreviewMsg{plan: stagePlan{path: "a.go", hunk: &hunk, what: "stage hunk 1 of 1", offset: 3}}
+
+	result, _ := m.handleStagePreview(preview)
+	rm := result.(Model)
+	if rm.mode != modeStagePreview || rm.diffCardTitle() != "stage hunk 1 of 1: a.go" {
+		t.Fatalf("mode=%v title=%q, want the preview", rm.mode, rm.diffCardTitle())
+	}
+	if view := rm.viewport.View(); !strings.Contains(view, "new line") || !strings.Contains(view, "old line") {
+		t.Errorf("preview should show the patch:\n%s", view)
+	}
+
+	result, _ = rm.updateStagePreviewMode(tea.KeyMsg{Type: tea.KeyEsc})
+	if back := result.(Model); back.mode != modeDiff || back.stagePlan != nil || back.viewport.YOffset != 3 {
+		t.Errorf("esc: mode=%v plan=%v offset=%d, want the diff at offset 3", back.mode, back.stagePlan != nil, back.viewport.YOffset)
+	}
+
+	result, cmd := rm.updateStagePreviewMode(runeKey('s'))
+	if applied := result.(Model); cmd == nil || applied.mode != modeDiff || applied.stagePlan != nil || applied.statusMsg != "staged hunk 1 of 1" {
+		t.Errorf("s: cmd=%v mode=%v status=%q", cmd != nil, applied.mode, applied.statusMsg)
+	}
+
+	// A preview for a file the view has left is not shown.
+	preview.plan.path = "b.go"
+	result, _ = m.handleStagePreview(preview)
+	if rm := result.(Model); rm.mode != modeDiff || !strings.Contains(rm.statusMsg, "dropped") {
+		t.Errorf("stale preview: mode=%v status=%q", rm.mode, rm.statusMsg)
+	}
+	if got := pastTense("unstage 2 lines"); got != "unstaged 2 lines" {
+		t.Errorf("pastTense = %q", got)
+	}
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"path"
+	"strings"
+
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+// Nerd Font glyphs for the file list, used when icons is "nerdfont".
+
+// nerdStatusIcons are the octicon diff glyphs for each file status.
+var nerdStatusIcons = map[git.FileStatus]string{
+	git.StatusModified:  "", // nf-oct-diff_modified
+	git.StatusAdded:     "", // nf-oct-diff_added
+	git.StatusDeleted:   "", // nf-oct-diff_removed
+	git.StatusRenamed:   "", // nf-oct-diff_renamed
+	git.StatusUntracked: "", // nf-oct-question
+}
+
+// nerdFileIcons maps lower-case extensions to devicon/seti glyphs.
+var nerdFileIcons = map[string]string{
+	".go":   "",
+	".js":   "",
+	".ts":   "",
+	".py":   "",
+	".rs":   "",
+	".rb":   "",
+	".java": "",
+	".c":    "",
+	".h":    "",
+	".sh":   "",
+	".md":   "",
+	".json": "",
+	".yaml": "",
+	".yml":  "",
+	".toml": "",
+	".html": "",
+	".css":  "",
+}
+
+// nerdDefaultFileIcon is the generic file glyph (nf-fa-file).
+const nerdDefaultFileIcon = ""
+
+// statusIcon is the status column glyph: the git letter in ascii mode.
+func statusIcon(icons string, s git.FileStatus) string {
+	if icons == "nerdfont" {
+		if icon, ok := nerdStatusIcons[s]; ok {
+			return icon
+		}
+	}
+	return string(s)
+}
+
+// fileIcon is the file type glyph shown before the name, followed by a
+// space; empty in ascii mode.
+func fileIcon(icons, p string) string {
+	if icons != "nerdfont" {
+		return ""
+	}
+	if icon, ok := nerdFileIcons[strings.ToLower(path.Ext(p))]; ok {
+		return icon + " "
+	}
+	return nerdDefaultFileIcon + " "
+}
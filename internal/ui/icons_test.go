@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+func TestStatusIcon(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		icons  string
+		status git.FileStatus
+		want   string
+	}{
+		{"ascii", git.StatusModified, "M"},
+		{"", git.StatusUntracked, "?"},
+		{"nerdfont", git.StatusModified, nerdStatusIcons[git.StatusModified]},
+		{"nerdfont", git.StatusCopied, "C"},
+	}
+	for _, tt := range tests {
+		if got := statusIcon(tt.icons, tt.status); got != tt.want {
+			t.Errorf("statusIcon(%q, %q) = %q, want %q", tt.icons, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestFileIcon(t *testing.T) {
+	t.Parallel()
+	if got := fileIcon("ascii", "main.go"); got != "" {
+		t.Errorf("ascii should have no file icon, got %q", got)
+	}
+	if got := fileIcon("nerdfont", "cmd/MAIN.GO"); got != nerdFileIcons[".go"]+" " {
+		t.Errorf("extension lookup should ignore case, got %q", got)
+	}
+	if got := fileIcon("nerdfont", "Makefile"); got != nerdDefaultFileIcon+" " {
+		t.Errorf("unknown type should use the default icon, got %q", got)
+	}
+}
@@ -1,9 +1,12 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -20,6 +23,7 @@ const (
 
 type logLoadedMsg struct {
 	commits []git.Commit
+	err     error
 }
 
 type logDiffLoadedMsg struct {
@@ -27,9 +31,26 @@ type logDiffLoadedMsg struct {
 	hash    string
 }
 
+type logJumpResolvedMsg struct {
+	hash string
+	err  error
+}
+
+type logFixupDoneMsg struct {
+	target string // commit the fixup is for
+	hash   string
+	err    error
+}
+
+type logRebaseDoneMsg struct {
+	conflict bool
+	err      error
+}
+
 // LogModel is the Bubble Tea model for the commit log browser.
 type LogModel struct {
 	repo     *git.Repo
+	opts     git.LogOptions
 	styles   Styles
 	theme    theme.Theme
 	commits  []git.Commit
@@ -39,18 +60,60 @@ type LogModel struct {
 	width    int
 	height   int
 	ready    bool
+
+	jumping   bool
+	jumpInput textinput.Model
+	jumped    *git.Commit // commit shown in diff mode that is not in the list
+	statusMsg string
+	osc52     string // text being copied with OSC 52 by the next frames
+
+	squashConfirm bool // A pressed once; a second A starts the rebase
+	rebasing      bool
+	reselect      string // hash to move the cursor to after the next reload
+
+	marked    string       // hash marked with m as one end of a range diff
+	rangePair []git.Commit // older, newer while a range diff is shown
+
+	OpenCommit string // hash to review in the file list after quitting
 }
 
 // NewLogModel creates the log browser model.
 func NewLogModel(repo *git.Repo, styles Styles, t theme.Theme) LogModel {
-	return LogModel{repo: repo, styles: styles, theme: t}
+	ji := textinput.New()
+	ji.Placeholder = "hash or ref..."
+	ji.CharLimit = 100
+	return LogModel{repo: repo, styles: styles, theme: t, jumpInput: ji}
+}
+
+// SelectCommit puts the cursor on hash once the log has loaded.
+func (m *LogModel) SelectCommit(hash string) {
+	m.reselect = hash
+}
+
+// shallowBoundaryHint explains why a boundary commit has no diff.
+const shallowBoundaryHint = "shallow clone boundary: the parent commit was not fetched (git fetch --unshallow)"
+
+// ReviewFailed shows in the status bar why the commit picked with o could
+// not be opened in the file list.
+func (m *LogModel) ReviewFailed(err error) {
+	if errors.Is(err, git.ErrShallowBoundary) {
+		m.statusMsg = shallowBoundaryHint
+		return
+	}
+	m.statusMsg = "Error: " + firstLine(err.Error())
+}
+
+// SetLogOptions scopes the commits loaded by Init.
+func (m *LogModel) SetLogOptions(opts git.LogOptions) {
+	m.opts = opts
 }
 
 func (m LogModel) Init() tea.Cmd {
 	repo := m.repo
+	opts := m.opts
 	return func() tea.Msg {
-		commits, _ := repo.Log(100)
-		return logLoadedMsg{commits: commits}
+		commits, err := repo.LogFiltered(opts)
+		return logLoadedMsg{commits: commits, err: err}
 	}
 }
 
@@ -63,11 +126,39 @@ func (m LogModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.ready = true
 	case logLoadedMsg:
 		m.commits = msg.commits
+		m.cursor = m.reloadedCursor()
+		m.reselect = ""
+		if m.markedIndex() < 0 {
+			m.marked = ""
+		}
+		if msg.err != nil {
+			m.statusMsg = "Error: " + firstLine(msg.err.Error())
+		}
 	case logDiffLoadedMsg:
 		m.viewport.SetContent(msg.content)
 		m.viewport.GotoTop()
 		m.mode = logModeDiff
+	case logJumpResolvedMsg:
+		return m.handleJumpResolved(msg)
+	case logFixupDoneMsg:
+		return m.handleFixupDone(msg)
+	case logRebaseDoneMsg:
+		return m.handleRebaseDone(msg)
+	case clipboardDoneMsg:
+		m.statusMsg = msg.label
+		if msg.err != nil {
+			m.statusMsg = "copy failed: " + msg.err.Error()
+		}
+		m.osc52 = msg.osc52
+		return m, clearOSC52Cmd(msg.osc52)
+	case osc52ClearedMsg:
+		if m.osc52 == msg.text {
+			m.osc52 = ""
+		}
 	case tea.KeyMsg:
+		if m.jumping {
+			return m.updateJump(msg)
+		}
 		switch m.mode {
 		case logModeList:
 			return m.updateList(msg)
@@ -79,6 +170,11 @@ func (m LogModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m LogModel) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.statusMsg = ""
+	if msg.String() == "A" {
+		return m.autosquash()
+	}
+	m.squashConfirm = false
 	switch msg.String() {
 	case "q", "ctrl+c":
 		return m, tea.Quit
@@ -94,20 +190,251 @@ func (m LogModel) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.cursor = 0
 	case "G":
 		m.cursor = max(0, len(m.commits)-1)
+	case ":":
+		m.jumping = true
+		m.jumpInput.SetValue("")
+		return m, m.jumpInput.Focus()
+	case "f":
+		return m.fixup()
+	case "y", "Y":
+		return m.copyCommitMarkdown(msg.String() == "Y")
+	case "F":
+		m.opts.FirstParent = !m.opts.FirstParent
+		return m.reloadFiltered()
+	case "M":
+		m.opts.Merges, m.opts.NoMerges = cycleMergeFilter(m.opts.Merges, m.opts.NoMerges)
+		return m.reloadFiltered()
 	case "enter":
 		if len(m.commits) > 0 {
-			return m, m.loadCommitDiff()
+			return m, m.loadCommitDiff(m.commits[m.cursor])
 		}
+	case "o":
+		if len(m.commits) > 0 {
+			m.OpenCommit = m.commits[m.cursor].Hash
+			return m, tea.Quit
+		}
+	case "m":
+		return m.toggleMark(), nil
+	case "d":
+		return m.rangeDiff()
+	case "esc":
+		m.marked = ""
 	}
 	return m, nil
 }
 
+// markedIndex returns the list index of the marked commit, or -1.
+func (m LogModel) markedIndex() int {
+	for i, c := range m.commits {
+		if m.marked != "" && c.Hash == m.marked {
+			return i
+		}
+	}
+	return -1
+}
+
+// toggleMark marks the selected commit as one end of a range diff, or
+// clears the mark when it is already on it.
+func (m LogModel) toggleMark() LogModel {
+	if len(m.commits) == 0 {
+		return m
+	}
+	if c := m.commits[m.cursor]; c.Hash != m.marked {
+		m.marked = c.Hash
+		m.statusMsg = "marked " + c.Short + ": move to another commit and press d"
+	} else {
+		m.marked = ""
+	}
+	return m
+}
+
+// rangeDiff shows the cumulative diff between the marked commit and the
+// selected one, older first regardless of which was marked.
+func (m LogModel) rangeDiff() (tea.Model, tea.Cmd) {
+	mi := m.markedIndex()
+	if mi < 0 {
+		m.statusMsg = "mark a commit with m first"
+		return m, nil
+	}
+	if mi == m.cursor {
+		m.statusMsg = "move to another commit to compare"
+		return m, nil
+	}
+	older, newer := m.commits[mi], m.commits[m.cursor]
+	if mi < m.cursor { // the log lists newest first
+		older, newer = newer, older
+	}
+	m.rangePair = []git.Commit{older, newer}
+	return m, m.loadRangeDiff(older, newer)
+}
+
+// reloadFiltered reloads the log after a filter change, keeping the
+// selected commit if it is still listed.
+func (m LogModel) reloadFiltered() (tea.Model, tea.Cmd) {
+	if len(m.commits) > 0 {
+		m.reselect = m.commits[m.cursor].Hash
+	}
+	return m, m.Init()
+}
+
+// cycleMergeFilter steps all commits → no merges → merges only → all.
+func cycleMergeFilter(merges, noMerges bool) (bool, bool) {
+	switch {
+	case merges:
+		return false, false
+	case noMerges:
+		return true, false
+	}
+	return false, true
+}
+
+// logFilterLabel describes the active history filters for the status bar.
+func logFilterLabel(opts git.LogOptions) string {
+	var parts []string
+	if opts.FirstParent {
+		parts = append(parts, "first-parent")
+	}
+	if opts.Merges {
+		parts = append(parts, "merges only")
+	}
+	if opts.NoMerges {
+		parts = append(parts, "no merges")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " · " + strings.Join(parts, " · ")
+}
+
+func (m LogModel) updateJump(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.jumping = false
+		m.jumpInput.Blur()
+		return m, nil
+	case "enter":
+		ref := strings.TrimSpace(m.jumpInput.Value())
+		m.jumping = false
+		m.jumpInput.Blur()
+		if ref == "" {
+			return m, nil
+		}
+		repo := m.repo
+		return m, func() tea.Msg {
+			hash, err := repo.ResolveRef(ref)
+			return logJumpResolvedMsg{hash: hash, err: err}
+		}
+	}
+	var cmd tea.Cmd
+	m.jumpInput, cmd = m.jumpInput.Update(msg)
+	return m, cmd
+}
+
+// handleJumpResolved moves the cursor to a loaded commit, or shows the diff
+// of a commit outside the loaded range directly.
+func (m LogModel) handleJumpResolved(msg logJumpResolvedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.statusMsg = "Error: " + firstLine(msg.err.Error())
+		return m, nil
+	}
+	for i, c := range m.commits {
+		if c.Hash == msg.hash {
+			m.cursor = i
+			return m, nil
+		}
+	}
+	c := git.Commit{Hash: msg.hash, Short: msg.hash[:min(7, len(msg.hash))]}
+	m.jumped = &c
+	return m, m.loadCommitDiff(c)
+}
+
+// reloadedCursor keeps the cursor on the reselect commit after a reload,
+// or clamps it to the new list.
+func (m LogModel) reloadedCursor() int {
+	for i, c := range m.commits {
+		if m.reselect != "" && c.Hash == m.reselect {
+			return i
+		}
+	}
+	return min(m.cursor, max(0, len(m.commits)-1))
+}
+
+// fixup commits the staged changes as a fixup for the selected commit.
+func (m LogModel) fixup() (tea.Model, tea.Cmd) {
+	if len(m.commits) == 0 || m.rebasing {
+		return m, nil
+	}
+	repo := m.repo
+	target := m.commits[m.cursor].Hash
+	m.statusMsg = "creating fixup..."
+	return m, func() tea.Msg {
+		hash, err := repo.CommitFixup(target)
+		return logFixupDoneMsg{target: target, hash: hash, err: err}
+	}
+}
+
+// autosquash asks for confirmation, then rebases from the selected commit
+// folding in its fixup commits.
+func (m LogModel) autosquash() (tea.Model, tea.Cmd) {
+	if len(m.commits) == 0 || m.rebasing {
+		return m, nil
+	}
+	c := m.commits[m.cursor]
+	if !m.squashConfirm {
+		m.squashConfirm = true
+		m.statusMsg = "press A again to rebase --autosquash onto " + c.Short
+		return m, nil
+	}
+	m.squashConfirm = false
+	m.rebasing = true
+	m.statusMsg = "rebasing..."
+	repo := m.repo
+	return m, func() tea.Msg {
+		err := repo.RebaseAutosquash(c.Hash)
+		return logRebaseDoneMsg{conflict: err != nil && repo.RebaseInProgress(), err: err}
+	}
+}
+
+func (m LogModel) handleFixupDone(msg logFixupDoneMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.statusMsg = "fixup failed: " + firstLine(msg.err.Error())
+		return m, nil
+	}
+	m.statusMsg = "created fixup " + msg.hash + " (A to autosquash)"
+	m.reselect = msg.target
+	return m, m.Init()
+}
+
+func (m LogModel) handleRebaseDone(msg logRebaseDoneMsg) (tea.Model, tea.Cmd) {
+	m.rebasing = false
+	switch {
+	case msg.conflict:
+		m.statusMsg = "rebase stopped on conflicts: resolve, then git rebase --continue (or --abort)"
+	case msg.err != nil:
+		m.statusMsg = "rebase failed: " + firstLine(msg.err.Error())
+		return m, nil
+	default:
+		m.statusMsg = "autosquash done"
+	}
+	return m, m.Init()
+}
+
+// firstLine returns s up to its first newline.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
 func (m LogModel) updateDiff(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "q", "ctrl+c":
 		return m, tea.Quit
 	case "esc":
 		m.mode = logModeList
+		m.jumped = nil
+		m.rangePair = nil
 		return m, nil
 	}
 	var cmd tea.Cmd
@@ -115,21 +442,55 @@ func (m LogModel) updateDiff(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m LogModel) loadCommitDiff() tea.Cmd {
-	commit := m.commits[m.cursor]
+func (m LogModel) loadCommitDiff(commit git.Commit) tea.Cmd {
 	repo := m.repo
 	styles := m.styles
 	t := m.theme
 	width := m.width
+	bodyW := m.viewport.Width
 
 	return func() tea.Msg {
+		// The subject is in the card title; the body (the "why") goes on top.
+		body, _ := repo.CommitBody(commit.Hash)
+		header := renderCommitBody(body, styles, bodyW)
 		raw, err := repo.CommitDiff(commit.Hash)
+		if errors.Is(err, git.ErrShallowBoundary) {
+			return logDiffLoadedMsg{content: header + styles.HelpDesc.Render(shallowBoundaryHint), hash: commit.Hash}
+		}
 		if err != nil {
-			return logDiffLoadedMsg{content: "Error: " + err.Error(), hash: commit.Hash}
+			return logDiffLoadedMsg{content: header + "Error: " + err.Error(), hash: commit.Hash}
 		}
 		// Guess filename from diff headers for syntax highlighting
 		content := renderCommitDiff(raw, styles, t, width)
-		return logDiffLoadedMsg{content: content, hash: commit.Hash}
+		return logDiffLoadedMsg{content: header + content, hash: commit.Hash}
+	}
+}
+
+// renderCommitBody renders a commit message body as a block wrapped to
+// width, followed by a blank line. An empty body renders nothing.
+func renderCommitBody(body string, styles Styles, width int) string {
+	if body == "" {
+		return ""
+	}
+	// Width excludes the left border.
+	return styles.CommitBody.Width(max(width-1, 1)).Render(body) + "\n\n"
+}
+
+func (m LogModel) loadRangeDiff(older, newer git.Commit) tea.Cmd {
+	repo := m.repo
+	styles := m.styles
+	t := m.theme
+	width := m.width
+
+	return func() tea.Msg {
+		raw, err := repo.RangeDiff(older.Hash, newer.Hash)
+		if err != nil {
+			return logDiffLoadedMsg{content: "Error: " + err.Error(), hash: newer.Hash}
+		}
+		if raw == "" {
+			return logDiffLoadedMsg{content: styles.HelpDesc.Render("no differences"), hash: newer.Hash}
+		}
+		return logDiffLoadedMsg{content: renderCommitDiff(raw, styles, t, width), hash: newer.Hash}
 	}
 }
 
@@ -146,8 +507,7 @@ func renderCommitDiff(raw string, styles Styles, t theme.Theme, width int) strin
 		if strings.HasPrefix(line, "diff --git") {
 			// Flush previous file
 			if len(currentLines) > 0 {
-				parsed := ParseDiff(strings.Join(currentLines, "\n"))
-				b.WriteString(RenderDiff(parsed, currentFile, styles, t, width))
+				b.WriteString(renderCommitFile(currentLines, currentFile, styles, t, width))
 			}
 			currentFile = extractFilename(line)
 			// Add file separator
@@ -160,12 +520,22 @@ func renderCommitDiff(raw string, styles Styles, t theme.Theme, width int) strin
 	}
 	// Flush last file
 	if len(currentLines) > 0 {
-		parsed := ParseDiff(strings.Join(currentLines, "\n"))
-		b.WriteString(RenderDiff(parsed, currentFile, styles, t, width))
+		b.WriteString(renderCommitFile(currentLines, currentFile, styles, t, width))
 	}
 	return b.String()
 }
 
+// renderCommitFile renders one file's section of a commit diff.
+func renderCommitFile(lines []string, filename string, styles Styles, t theme.Theme, width int) string {
+	parsed := ParseDiff(strings.Join(lines, "\n"))
+	analyseLexer(filename, diffHead(parsed))
+	out := RenderDiff(parsed, filename, styles, t, width)
+	if parsed.Truncated {
+		out += RenderTruncationBanner(maxDiffLines, "", styles, width)
+	}
+	return out
+}
+
 // extractFilename pulls the b/ path from "diff --git a/foo b/foo".
 func extractFilename(diffHeader string) string {
 	parts := strings.SplitN(diffHeader, " b/", 2)
@@ -182,9 +552,9 @@ func (m LogModel) View() string {
 
 	switch m.mode {
 	case logModeDiff:
-		return m.viewDiff()
+		return withOSC52(m.osc52, m.viewDiff())
 	default:
-		return m.viewList()
+		return withOSC52(m.osc52, m.viewList())
 	}
 }
 
@@ -205,27 +575,87 @@ func (m LogModel) viewList() string {
 	}
 
 	card := renderCard(m.theme, "Commits", b.String(), true, cardW, contentH)
-	status := m.styles.StatusBar.Width(m.width).Render(
-		fmt.Sprintf(" %d commits", len(m.commits)))
+	label := fmt.Sprintf(" %d commits%s", len(m.commits), logFilterLabel(m.opts))
+	if mi := m.markedIndex(); mi >= 0 {
+		label += " · marked " + m.commits[mi].Short
+	}
+	status := m.styles.StatusBar.Width(m.width).Render(label)
+	switch {
+	case m.jumping:
+		status = m.styles.StatusBar.Width(m.width).Render(" : " + m.jumpInput.View())
+	case m.statusMsg != "":
+		status = m.styles.StatusBar.Width(m.width).Render(" " + m.statusMsg)
+	}
 	help := m.renderLogHelp(false)
 	return lipgloss.JoinVertical(lipgloss.Left, card, status, help)
 }
 
 func (m LogModel) renderCommitLine(c git.Commit, selected bool) string {
 	hash := m.styles.Accent.Render(c.Short)
+	if m.marked != "" && c.Hash == m.marked {
+		hash = m.styles.StagedIcon.Render(c.Short)
+	}
 	date := m.styles.HelpDesc.Render(c.Date)
-	line := fmt.Sprintf("%s  %s  %s", hash, c.Subject, date)
+	author := m.authorStyle(c.Author)
+	badge := author.Bold(true).Render(authorInitials(c.Author))
+	line := fmt.Sprintf("%s %s  %s  %s %s", hash, badge, c.Subject, author.Render(c.Author), date)
 	if selected {
-		return m.styles.FileSelected.Width(m.width).Render(line)
+		return renderSelectedRow(m.styles, line, m.width)
 	}
 	return lipgloss.NewStyle().Width(m.width).Render(line)
 }
 
+// authorStyle picks a stable color for an author from the theme palette.
+func (m LogModel) authorStyle(name string) lipgloss.Style {
+	if len(m.styles.Authors) == 0 {
+		return m.styles.HelpDesc
+	}
+	return m.styles.Authors[authorIndex(name, len(m.styles.Authors))]
+}
+
+// authorIndex deterministically maps an author name to one of n colors.
+func authorIndex(name string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return int(h.Sum32() % uint32(n))
+}
+
+// authorInitials returns a two-letter badge: "Jan Smrcka" → "JS", "alice" → "AL".
+func authorInitials(name string) string {
+	words := strings.Fields(name)
+	switch {
+	case len(words) == 0:
+		return "??"
+	case len(words) == 1:
+		r := []rune(words[0])
+		if len(r) == 1 {
+			return strings.ToUpper(string(r[0])) + " "
+		}
+		return strings.ToUpper(string(r[:2]))
+	}
+	first := []rune(words[0])
+	last := []rune(words[len(words)-1])
+	return strings.ToUpper(string(first[0]) + string(last[0]))
+}
+
 func (m LogModel) viewDiff() string {
 	contentH := m.height - 4
 	cardW := m.width - 2
 
-	c := m.commits[m.cursor]
+	if len(m.rangePair) == 2 {
+		older, newer := m.rangePair[0], m.rangePair[1]
+		card := renderCard(m.theme, older.Short+".."+newer.Short, m.viewport.View(), true, cardW, contentH)
+		status := m.styles.StatusBar.Width(m.width).Render(truncateEnd(
+			fmt.Sprintf(" comparing %s %s → %s %s", older.Short, older.Subject, newer.Short, newer.Subject), m.width))
+		return lipgloss.JoinVertical(lipgloss.Left, card, status, m.renderLogHelp(true))
+	}
+
+	var c git.Commit
+	if m.jumped != nil {
+		c = *m.jumped
+	} else {
+		c = m.commits[m.cursor]
+	}
 	title := c.Short + " " + c.Subject
 	card := renderCard(m.theme, title, m.viewport.View(), true, cardW, contentH)
 	status := m.styles.StatusBar.Width(m.width).Render(
@@ -247,6 +677,14 @@ func (m LogModel) renderLogHelp(inDiff bool) string {
 		pairs = []struct{ key, desc string }{
 			{"j/k", "navigate"},
 			{"enter", "view diff"},
+			{"o", "review files"},
+			{"m/d", "mark/range diff"},
+			{":", "jump to hash"},
+			{"f", "fixup"},
+			{"y/Y", "copy markdown"},
+			{"A", "autosquash"},
+			{"F", "first-parent"},
+			{"M", "merges"},
 			{"q", "quit"},
 		}
 	}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+// formatCommitMarkdown renders a commit as a changelog bullet:
+// "- subject (abc1234)".
+func formatCommitMarkdown(c git.Commit) string {
+	return fmt.Sprintf("- %s (%s)", c.Subject, c.Short)
+}
+
+// formatCommitMarkdownBlock renders a commit with its author, date and
+// body, for pasting into PR descriptions.
+func formatCommitMarkdownBlock(c git.Commit, body string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n`%s` · %s · %s\n", c.Subject, c.Short, c.Author, c.Date)
+	if body != "" {
+		b.WriteString("\n" + body + "\n")
+	}
+	return b.String()
+}
+
+// copyCommitMarkdown copies the selected commit as a markdown bullet, or
+// with rich set as a block including the message body.
+func (m LogModel) copyCommitMarkdown(rich bool) (tea.Model, tea.Cmd) {
+	if len(m.commits) == 0 {
+		return m, nil
+	}
+	c := m.commits[m.cursor]
+	if !rich {
+		return m, copyCmd(formatCommitMarkdown(c), "copied "+c.Short+" as markdown")
+	}
+	repo := m.repo
+	return m, func() tea.Msg {
+		body, err := repo.CommitBody(c.Hash)
+		if err != nil {
+			return clipboardDoneMsg{err: err}
+		}
+		text := formatCommitMarkdownBlock(c, body)
+		return copiedMsg(text, "copied "+c.Short+" with message")
+	}
+}
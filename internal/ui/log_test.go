@@ -1,6 +1,14 @@
 package ui
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jansmrcka/differ/internal/git"
+)
 
 func TestExtractFilename(t *testing.T) {
 	t.Parallel()
@@ -25,3 +33,244 @@ func TestExtractFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthorInitials(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name, want string
+	}{
+		{"Jan Smrcka", "JS"},
+		{"Ada King Lovelace", "AL"},
+		{"alice", "AL"},
+		{"x", "X "},
+		{"Žofie Černá", "ŽČ"},
+		{"", "??"},
+	}
+	for _, tt := range tests {
+		if got := authorInitials(tt.name); got != tt.want {
+			t.Errorf("authorInitials(%q)=%q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorIndex_Stable(t *testing.T) {
+	t.Parallel()
+	a := authorIndex("Jan Smrcka", 8)
+	if a < 0 || a >= 8 {
+		t.Fatalf("index %d out of range", a)
+	}
+	for i := 0; i < 5; i++ {
+		if got := authorIndex("Jan Smrcka", 8); got != a {
+			t.Errorf("authorIndex not stable: %d vs %d", got, a)
+		}
+	}
+}
+
+func TestRenderCommitLine_ShowsAuthorBadge(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	m := NewLogModel(nil, styles, th)
+	m.width = 120
+	line := m.renderCommitLine(git.Commit{Short: "abc123", Author: "Jan Smrcka", Date: "2h ago", Subject: "fix bug"}, false)
+	for _, want := range []string{"abc123", "JS", "fix bug", "Jan Smrcka", "2h ago"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("commit line missing %q: %q", want, line)
+		}
+	}
+}
+
+func TestLogJumpResolved(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	m := NewLogModel(nil, styles, th)
+	m.commits = []git.Commit{{Hash: "aaa111"}, {Hash: "bbb222"}, {Hash: "ccc333"}}
+
+	res, cmd := m.handleJumpResolved(logJumpResolvedMsg{hash: "ccc333"})
+	lm := res.(LogModel)
+	if lm.cursor != 2 || cmd != nil || lm.jumped != nil {
+		t.Errorf("loaded commit: cursor=%d jumped=%v cmd=%v", lm.cursor, lm.jumped, cmd != nil)
+	}
+
+	res, cmd = m.handleJumpResolved(logJumpResolvedMsg{hash: "ddd4445566"})
+	lm = res.(LogModel)
+	if lm.jumped == nil || lm.jumped.Short != "ddd4445" || cmd == nil {
+		t.Errorf("unloaded commit should load its diff, jumped=%v", lm.jumped)
+	}
+
+	res, _ = m.handleJumpResolved(logJumpResolvedMsg{err: errors.New("error: short object ID ab is ambiguous\nhint: x")})
+	lm = res.(LogModel)
+	if lm.statusMsg != "Error: error: short object ID ab is ambiguous" {
+		t.Errorf("statusMsg=%q", lm.statusMsg)
+	}
+}
+
+func TestLogAutosquash_RequiresConfirm(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	m := NewLogModel(nil, styles, th)
+	m.commits = []git.Commit{{Hash: "aaa111", Short: "aaa111"}}
+
+	res, cmd := m.Update(runeKey('A'))
+	lm := res.(LogModel)
+	if cmd != nil || !lm.squashConfirm || !strings.Contains(lm.statusMsg, "press A again") {
+		t.Fatalf("first A should ask to confirm, status=%q", lm.statusMsg)
+	}
+	res, cmd = lm.Update(runeKey('A'))
+	lm = res.(LogModel)
+	if cmd == nil || !lm.rebasing || lm.statusMsg != "rebasing..." {
+		t.Errorf("second A should start rebase, status=%q", lm.statusMsg)
+	}
+
+	res, _ = m.Update(runeKey('A'))
+	res, _ = res.(LogModel).Update(runeKey('j'))
+	if res.(LogModel).squashConfirm {
+		t.Error("another key should cancel the confirmation")
+	}
+}
+
+func TestLogRebaseDone_Conflict(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	m := NewLogModel(nil, styles, th)
+	m.rebasing = true
+	res, _ := m.handleRebaseDone(logRebaseDoneMsg{conflict: true, err: errors.New("CONFLICT")})
+	lm := res.(LogModel)
+	if lm.rebasing || !strings.Contains(lm.statusMsg, "git rebase --continue") {
+		t.Errorf("rebasing=%v status=%q", lm.rebasing, lm.statusMsg)
+	}
+}
+
+func TestLogLoaded_ReselectsFixupTarget(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	m := NewLogModel(nil, styles, th)
+	m.reselect = "bbb222"
+	res, _ := m.Update(logLoadedMsg{commits: []git.Commit{{Hash: "fff000"}, {Hash: "aaa111"}, {Hash: "bbb222"}}})
+	if lm := res.(LogModel); lm.cursor != 2 || lm.reselect != "" {
+		t.Errorf("cursor=%d reselect=%q, want 2 and cleared", lm.cursor, lm.reselect)
+	}
+}
+
+func TestLogOpenCommit(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	m := NewLogModel(nil, styles, th)
+	if _, cmd := m.Update(runeKey('o')); cmd != nil {
+		t.Error("o with no commits should do nothing")
+	}
+	m.commits = []git.Commit{{Hash: "aaa111"}, {Hash: "bbb222"}}
+	m.cursor = 1
+	res, cmd := m.Update(runeKey('o'))
+	if lm := res.(LogModel); lm.OpenCommit != "bbb222" || cmd == nil {
+		t.Errorf("OpenCommit=%q cmd=%v, want bbb222 and quit", lm.OpenCommit, cmd != nil)
+	}
+}
+
+func TestLogReviewFailed(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	m := NewLogModel(nil, styles, th)
+	m.ReviewFailed(git.ErrShallowBoundary)
+	if m.statusMsg != shallowBoundaryHint {
+		t.Errorf("boundary: status = %q", m.statusMsg)
+	}
+	m.ReviewFailed(errors.New("exit status 128\nmore"))
+	if m.statusMsg != "Error: exit status 128" {
+		t.Errorf("other error: status = %q", m.statusMsg)
+	}
+}
+
+func TestLogRangeDiff(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	m := NewLogModel(nil, styles, th)
+	m.commits = []git.Commit{
+		{Hash: "ccc333", Short: "ccc333"},
+		{Hash: "bbb222", Short: "bbb222"},
+		{Hash: "aaa111", Short: "aaa111"},
+	}
+	send := func(m LogModel, r rune) (LogModel, tea.Cmd) {
+		res, cmd := m.Update(runeKey(r))
+		return res.(LogModel), cmd
+	}
+
+	if lm, cmd := send(m, 'd'); cmd != nil || lm.statusMsg != "mark a commit with m first" {
+		t.Errorf("d without a mark: cmd=%v status=%q", cmd != nil, lm.statusMsg)
+	}
+	m, _ = send(m, 'm')
+	if m.marked != "ccc333" {
+		t.Fatalf("marked=%q, want ccc333", m.marked)
+	}
+	if _, cmd := send(m, 'd'); cmd != nil {
+		t.Error("d on the marked commit itself should not diff")
+	}
+	m.cursor = 2
+	m, cmd := send(m, 'd')
+	if cmd == nil || len(m.rangePair) != 2 || m.rangePair[0].Hash != "aaa111" || m.rangePair[1].Hash != "ccc333" {
+		t.Errorf("d should diff older..newer, got pair %+v", m.rangePair)
+	}
+
+	m.cursor = 0
+	if m, _ = send(m, 'm'); m.marked != "" {
+		t.Errorf("m on the marked commit should clear the mark, got %q", m.marked)
+	}
+}
+
+func TestLogFilterToggles(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	m := NewLogModel(nil, styles, th)
+	m.commits = []git.Commit{{Hash: "aaa111", Short: "aaa111"}}
+
+	res, cmd := m.Update(runeKey('F'))
+	lm := res.(LogModel)
+	if cmd == nil || !lm.opts.FirstParent || lm.reselect != "aaa111" {
+		t.Fatalf("F should reload with first-parent, opts=%+v reselect=%q", lm.opts, lm.reselect)
+	}
+
+	want := []string{" · first-parent · no merges", " · first-parent · merges only", " · first-parent"}
+	for _, w := range want {
+		res, _ = lm.Update(runeKey('M'))
+		lm = res.(LogModel)
+		if got := logFilterLabel(lm.opts); got != w {
+			t.Errorf("label = %q, want %q", got, w)
+		}
+	}
+}
+
+func TestFormatCommitMarkdown(t *testing.T) {
+	t.Parallel()
+	c := git.Commit{Short: "abc1234", Subject: "Fix login redirect", Author: "Alice", Date: "2 days ago"}
+	if got := formatCommitMarkdown(c); got != "- Fix login redirect (abc1234)" {
+		t.Errorf("bullet = %q", got)
+	}
+	want := "### Fix login redirect\n\n`abc1234` · Alice · 2 days ago\n\nKeeps the return URL.\n"
+	if got := formatCommitMarkdownBlock(c, "Keeps the return URL."); got != want {
+		t.Errorf("block = %q, want %q", got, want)
+	}
+	if got := formatCommitMarkdownBlock(c, ""); strings.Contains(got, "\n\n\n") {
+		t.Errorf("empty body should not leave blank lines: %q", got)
+	}
+}
+
+func TestRenderCommitBody(t *testing.T) {
+	t.Parallel()
+	styles, _ := testStyles()
+	if got := renderCommitBody("", styles, 40); got != "" {
+		t.Errorf("empty body should render nothing, got %q", got)
+	}
+	body := "Why: " + strings.Repeat("the old cache never expired ", 5) + "\n\nRefs: #12"
+	out := renderCommitBody(body, styles, 40)
+	lines := strings.Split(strings.TrimSuffix(out, "\n\n"), "\n")
+	if len(lines) < 5 {
+		t.Fatalf("long paragraph should wrap, got %d lines: %q", len(lines), lines)
+	}
+	for i, line := range lines {
+		if w := lipgloss.Width(line); w > 40 {
+			t.Errorf("line %d width = %d, want <= 40", i, w)
+		}
+	}
+	if !strings.Contains(out, "Refs: #12") {
+		t.Error("later paragraphs should be kept")
+	}
+}
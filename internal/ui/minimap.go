@@ -0,0 +1,86 @@
+package ui
+
+import "strings"
+
+// minimapWidth is the column reserved right of the diff for the minimap.
+const minimapWidth = 1
+
+// lineKinds returns the type of each rendered line of a unified or compact
+// diff, which print one line per parsed line.
+func lineKinds(parsed ParsedDiff) []DiffLineType {
+	if parsed.Binary {
+		return nil
+	}
+	kinds := make([]DiffLineType, len(parsed.Lines))
+	for i, dl := range parsed.Lines {
+		kinds[i] = dl.Type
+		if dl.Words != nil {
+			kinds[i] = LineAdded // a word-diff line with changes in it
+		}
+	}
+	return kinds
+}
+
+// renderMinimap renders the minimap column for the current diff, or a blank
+// column when the diff fits on screen or its lines can't be mapped.
+func (m Model) renderMinimap(height int) string {
+	kinds := m.diffKinds
+	if m.panelOverlay() || m.mode == modeSettings || m.mode == modeActions {
+		kinds = nil
+	}
+	return renderMinimap(kinds, height, m.viewport.YOffset, m.viewport.Height, m.styles)
+}
+
+// renderMinimap draws a one-column overview of a diff scaled to height.
+// Each row covers a slice of lines and shows whether it holds removals,
+// additions or only context; rows inside the visible window are heavier.
+func renderMinimap(kinds []DiffLineType, height, offset, visible int, styles Styles) string {
+	if height <= 0 {
+		return ""
+	}
+	n := len(kinds)
+	rows := make([]string, height)
+	for row := range rows {
+		if n <= visible {
+			rows[row] = " "
+			continue
+		}
+		start := row * n / height
+		end := max(start+1, (row+1)*n/height)
+		inWindow := start < offset+visible && end > offset
+		rows[row] = minimapCell(kinds[start:min(end, n)], inWindow, styles)
+	}
+	return strings.Join(rows, "\n")
+}
+
+// minimapCell renders one minimap row; removals win over additions so a
+// mixed slice still reads as changed.
+func minimapCell(kinds []DiffLineType, inWindow bool, styles Styles) string {
+	added, removed := false, false
+	for _, k := range kinds {
+		added = added || k == LineAdded
+		removed = removed || k == LineRemoved
+	}
+	switch {
+	case removed:
+		return styles.StatusDeleted.Render(minimapGlyph(true, inWindow))
+	case added:
+		return styles.StatusAdded.Render(minimapGlyph(true, inWindow))
+	default:
+		return styles.DiffLineNum.Render(minimapGlyph(false, inWindow))
+	}
+}
+
+// minimapGlyph keeps the window and changes visible without colors.
+func minimapGlyph(changed, inWindow bool) string {
+	switch {
+	case changed && inWindow:
+		return "█"
+	case changed:
+		return "▌"
+	case inWindow:
+		return "┃"
+	default:
+		return "│"
+	}
+}
@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMinimap(t *testing.T) {
+	t.Parallel()
+	styles, _ := testStyles()
+	kinds := []DiffLineType{
+		LineHunkHeader, LineContext, LineAdded, LineContext,
+		LineContext, LineContext, LineRemoved, LineAdded,
+	}
+	tests := []struct {
+		name            string
+		kinds           []DiffLineType
+		offset, visible int
+		want            []string
+	}{
+		{"fits on screen", kinds, 0, 8, []string{" ", " ", " ", " "}},
+		{"window at top", kinds, 0, 2, []string{"┃", "▌", "│", "▌"}},
+		{"window at bottom", kinds, 6, 2, []string{"│", "▌", "│", "█"}},
+		{"window in middle", kinds, 2, 4, []string{"│", "█", "┃", "▌"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := strings.Split(renderMinimap(tt.kinds, 4, tt.offset, tt.visible, styles), "\n")
+			if strings.Join(got, "") != strings.Join(tt.want, "") {
+				t.Errorf("minimap = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineKinds_Binary(t *testing.T) {
+	t.Parallel()
+	if kinds := lineKinds(ParsedDiff{Binary: true}); kinds != nil {
+		t.Errorf("binary diff kinds = %v, want nil", kinds)
+	}
+}
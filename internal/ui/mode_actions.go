@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+// Actions mode: a menu of what can be done with the selected file, each
+// entry run by its mnemonic or by enter. Entries dispatch to the same
+// handlers as the file list keys.
+
+// fileAction is one menu entry. key is both the mnemonic and the file list
+// key that does the same thing.
+type fileAction struct {
+	key   string
+	label string
+	run   func(m Model) (tea.Model, tea.Cmd)
+}
+
+// fileActions lists the actions that apply to the selected file, so a
+// staged file offers unstage rather than stage and read-only views offer
+// no staging at all.
+func (m Model) fileActions() []fileAction {
+	if m.cursor >= len(m.files) {
+		return nil
+	}
+	f := m.files[m.cursor]
+	editable := !m.reviewOnly && !m.stagedOnly && m.ref == ""
+	var actions []fileAction
+	add := func(key, label string, run func(Model) (tea.Model, tea.Cmd)) {
+		if m.stagingOnly && stagingOnlyBlocks(modeFileList, key) {
+			return
+		}
+		actions = append(actions, fileAction{key: key, label: label, run: run})
+	}
+
+	add("enter", "view diff", func(m Model) (tea.Model, tea.Cmd) {
+		m.mode = modeDiff
+		if m.cfg.RelativeLineNums {
+			return m, m.loadDiffCmd(false)
+		}
+		return m, nil
+	})
+	if editable {
+		label := "stage"
+		if f.change.Staged {
+			label = "unstage"
+		}
+		add("tab", label, Model.toggleStage)
+	}
+	if f.change.Status != git.StatusDeleted {
+		add("e", "open in editor", func(m Model) (tea.Model, tea.Cmd) {
+			m.SelectedFile = f.change.Path
+			return m, tea.Quit
+		})
+	}
+	add("y", "copy path", func(m Model) (tea.Model, tea.Cmd) { return m.copyPath(false) })
+	add("Y", "copy absolute path", func(m Model) (tea.Model, tea.Cmd) { return m.copyPath(true) })
+	add("C", "copy diff as markdown", Model.copyDiffMarkdown)
+	add("i", "explain with AI", Model.explain)
+	if !f.untracked {
+		add("T", "open in diff tool", Model.launchDiffTool)
+	}
+	if editable && !f.untracked {
+		add("M", "open in merge tool", Model.launchMergeTool)
+	}
+	return actions
+}
+
+func (m Model) enterActionsMode() (tea.Model, tea.Cmd) {
+	if m.cursor >= len(m.files) {
+		return m, nil
+	}
+	m.mode = modeActions
+	m.actionsCursor = 0
+	return m, nil
+}
+
+func (m Model) updateActionsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	actions := m.fileActions()
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", " ":
+		m.mode = modeFileList
+		return m, nil
+	case "j", "down":
+		m.actionsCursor = min(m.actionsCursor+1, len(actions)-1)
+		return m, nil
+	case "k", "up":
+		m.actionsCursor = max(m.actionsCursor-1, 0)
+		return m, nil
+	case "enter":
+		if m.actionsCursor < len(actions) {
+			return m.runAction(actions[m.actionsCursor])
+		}
+		return m, nil
+	}
+	for _, a := range actions {
+		if a.key == msg.String() && a.key != "enter" {
+			return m.runAction(a)
+		}
+	}
+	return m, nil
+}
+
+// runAction closes the menu and runs a from the file list, as if its key
+// had been pressed there.
+func (m Model) runAction(a fileAction) (tea.Model, tea.Cmd) {
+	m.mode = modeFileList
+	return a.run(m)
+}
+
+func (m Model) renderActions() string {
+	actions := m.fileActions()
+	keyW := 0
+	for _, a := range actions {
+		keyW = max(keyW, len(a.key))
+	}
+	var b strings.Builder
+	for i, a := range actions {
+		line := fmt.Sprintf(" %*s  %s", keyW, a.key, a.label)
+		if i == m.actionsCursor {
+			b.WriteString(renderSelectedRow(m.styles, line, m.diffWidth()))
+		} else {
+			b.WriteString(m.styles.HelpKey.Render(fmt.Sprintf(" %*s", keyW, a.key)) + "  " + m.styles.FileItem.Render(a.label))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
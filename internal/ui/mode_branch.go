@@ -5,6 +5,7 @@ import (
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/git"
 )
 
 // Branch picker/create mode state transitions and actions.
@@ -30,15 +31,36 @@ func filterBranches(branches []string, query string) []string {
 	return out
 }
 
+// mergedSet returns branches merged into HEAD; failures just hide the marker.
+func mergedSet(repo *git.Repo) map[string]bool {
+	merged, err := repo.MergedBranches("")
+	if err != nil {
+		return nil
+	}
+	set := make(map[string]bool, len(merged))
+	for _, b := range merged {
+		set[b] = true
+	}
+	return set
+}
+
 func (m Model) enterBranchMode() (tea.Model, tea.Cmd) {
+	if m.reviewOnly {
+		return m, nil
+	}
 	repo := m.repo
 	return m, func() tea.Msg {
-		branches, err := repo.ListBranches()
-		if err != nil {
-			return branchesLoadedMsg{err: err}
+		msg := branchesLoadedMsg{current: repo.BranchName(), merged: mergedSet(repo)}
+		if detailed, err := repo.ListBranchesDetailed(); err == nil && len(detailed) > 0 {
+			msg.details = make(map[string]git.BranchInfo, len(detailed))
+			for _, b := range detailed {
+				msg.branches = append(msg.branches, b.Name)
+				msg.details[b.Name] = b
+			}
+			return msg
 		}
-		current := repo.BranchName()
-		return branchesLoadedMsg{branches: branches, current: current}
+		msg.branches, msg.err = repo.ListBranches()
+		return msg
 	}
 }
 
@@ -46,9 +68,12 @@ func (m Model) updateBranchMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.branchCreating {
 		return m.updateBranchCreateMode(msg)
 	}
+	stashSwitch := m.stashSwitch
+	m.stashSwitch = ""
 	switch msg.String() {
-	case "ctrl+n":
+	case "ctrl+n", "ctrl+t":
 		m.branchCreating = true
+		m.branchDetaching = msg.String() == "ctrl+t"
 		m.branchInput.Reset()
 		m.branchInput.Focus()
 		m.branchFilter.Blur()
@@ -85,12 +110,28 @@ func (m Model) updateBranchMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		selected := list[m.branchCursor]
-		m.branchFilter.Blur()
 		if selected == m.currentBranch {
+			m.branchFilter.Blur()
 			m.mode = modeFileList
 			return m, nil
 		}
 		repo := m.repo
+		if selected == stashSwitch {
+			m.branchFilter.Blur()
+			m.statusMsg = "stashing..."
+			return m, func() tea.Msg {
+				if err := repo.Stash("differ: before switching to " + selected); err != nil {
+					return branchSwitchedMsg{err: err}
+				}
+				return branchSwitchedMsg{err: repo.CheckoutBranch(selected), stashed: true}
+			}
+		}
+		if m.treeDirty() {
+			m.stashSwitch = selected
+			m.statusMsg = "working tree dirty — enter again to stash and switch to " + selected
+			return m, nil
+		}
+		m.branchFilter.Blur()
 		return m, func() tea.Msg {
 			return branchSwitchedMsg{err: repo.CheckoutBranch(selected)}
 		}
@@ -110,15 +151,26 @@ func (m Model) updateBranchCreateMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "esc", "ctrl+c":
 		m.branchCreating = false
+		m.branchDetaching = false
 		m.branchInput.Reset()
 		m.branchFilter.Focus()
 		return m, nil
 	case "enter":
 		name := strings.TrimSpace(m.branchInput.Value())
+		if name == "" && m.branchDetaching {
+			m.statusMsg = "empty ref"
+			return m, nil
+		}
 		if name == "" {
 			m.statusMsg = "empty branch name"
 			return m, nil
 		}
+		if m.branchDetaching {
+			repo := m.repo
+			return m, func() tea.Msg {
+				return branchSwitchedMsg{err: repo.CheckoutDetached(name), detached: true}
+			}
+		}
 		return m, m.createBranchCmd(name)
 	}
 	var cmd tea.Cmd
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Clean mode: preview untracked files, confirm, then run git clean.
+
+func (m Model) cleanPreviewCmd() tea.Cmd {
+	repo := m.repo
+	ignored := m.cleanIgnored
+	return func() tea.Msg {
+		files, err := repo.Clean(true, true, ignored)
+		return cleanPreviewMsg{files: files, ignored: ignored, err: err}
+	}
+}
+
+// cleanCmd removes only the previewed paths: anything untracked since the
+// preview was listed is left alone.
+func (m Model) cleanCmd() tea.Cmd {
+	repo := m.repo
+	ignored := m.cleanIgnored
+	files := m.cleanFiles
+	return func() tea.Msg {
+		removed, err := repo.Clean(false, true, ignored, files...)
+		return cleanDoneMsg{removed: removed, err: err}
+	}
+}
+
+func (m Model) handleCleanPreview(msg cleanPreviewMsg) (tea.Model, tea.Cmd) {
+	// i was pressed again before this preview arrived.
+	if msg.ignored != m.cleanIgnored {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.mode = modeFileList
+		m.statusMsg = "clean preview failed: " + msg.err.Error()
+		return m, nil
+	}
+	if len(msg.files) == 0 && m.mode != modeClean {
+		m.statusMsg = "nothing to clean"
+		return m, nil
+	}
+	m.mode = modeClean
+	m.cleanFiles = msg.files
+	m.viewport.SetContent(m.renderCleanPreview())
+	m.viewport.GotoTop()
+	return m, nil
+}
+
+func (m Model) handleCleanDone(msg cleanDoneMsg) (tea.Model, tea.Cmd) {
+	m.mode = modeFileList
+	m.cleaning = false
+	m.cleanFiles = nil
+	m.prevCurs = -1
+	m.lastDiffContent = ""
+	if msg.err != nil {
+		m.statusMsg = "clean failed: " + msg.err.Error()
+		return m, m.refreshFilesCmd()
+	}
+	m.statusMsg = fmt.Sprintf("removed %d untracked", len(msg.removed))
+	return m, m.refreshFilesCmd()
+}
+
+func (m Model) updateCleanMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "n", "q":
+		m.mode = modeFileList
+		m.cleanFiles = nil
+		m.lastDiffContent = ""
+		return m, m.loadDiffCmd(true)
+	case "y":
+		if len(m.cleanFiles) == 0 || m.cleaning {
+			return m, nil
+		}
+		m.cleaning = true
+		m.statusMsg = "cleaning..."
+		return m, m.cleanCmd()
+	case "i":
+		if m.cleaning {
+			return m, nil
+		}
+		// Until the new preview arrives there is nothing y may delete.
+		m.cleanIgnored = !m.cleanIgnored
+		m.cleanFiles = nil
+		return m, m.cleanPreviewCmd()
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m Model) renderCleanPreview() string {
+	if len(m.cleanFiles) == 0 {
+		return m.styles.HelpDesc.Render("  nothing to clean")
+	}
+	var b strings.Builder
+	for _, f := range m.cleanFiles {
+		b.WriteString(m.styles.DiffRemoved.Render("- " + f))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func (m Model) cleanCardTitle() string {
+	title := fmt.Sprintf("git clean: %d to delete", len(m.cleanFiles))
+	if m.cleanIgnored {
+		title += " (incl. ignored)"
+	}
+	return title
+}
@@ -1,15 +1,71 @@
 package ui
 
-import tea "github.com/charmbracelet/bubbletea"
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/config"
+)
 
 // Diff mode key handling and viewport delegation.
 
 func (m Model) updateDiffMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.stagingOnly && stagingOnlyBlocks(m.mode, msg.String()) {
+		return m, nil
+	}
+	if p, ok := m.pending.feed(msg.String()); ok {
+		m.pending = p
+		return m, nil
+	}
+	keys := m.pending
+	m.pending = pendingKeys{}
+
 	switch msg.String() {
 	case "q", "ctrl+c":
 		return m, tea.Quit
 	case "esc", "h", "left":
+		if m.selecting && msg.String() == "esc" {
+			m.selecting = false
+			m.statusMsg = ""
+			return m, m.rerenderDiffCmd()
+		}
 		m.mode = modeFileList
+		if m.cfg.RelativeLineNums {
+			return m, m.loadDiffCmd(false)
+		}
+		return m, nil
+	case "j", "down":
+		if m.relativeGutterActive() {
+			return m.moveDiffCursor(keys.repeat())
+		}
+		m.viewport.ScrollDown(keys.repeat())
+		return m, nil
+	case "k", "up":
+		if m.relativeGutterActive() {
+			return m.moveDiffCursor(-keys.repeat())
+		}
+		m.viewport.ScrollUp(keys.repeat())
+		return m, nil
+	case "g":
+		if m.relativeGutterActive() {
+			return m.moveDiffCursor(keys.jumpTarget(m.viewport.TotalLineCount()-1, 0) - m.diffCursor)
+		}
+		if keys.count == 0 {
+			m.viewport.GotoTop()
+			return m, nil
+		}
+		m.viewport.SetYOffset(keys.jumpTarget(m.viewport.TotalLineCount()-1, 0))
+		return m, nil
+	case "G":
+		last := m.viewport.TotalLineCount() - 1
+		if m.relativeGutterActive() {
+			return m.moveDiffCursor(keys.jumpTarget(last, last) - m.diffCursor)
+		}
+		if keys.count == 0 {
+			m.viewport.GotoBottom()
+			return m, nil
+		}
+		m.viewport.SetYOffset(keys.jumpTarget(last, last))
 		return m, nil
 	case "n":
 		return m.nextFile()
@@ -20,12 +76,64 @@ func (m Model) updateDiffMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.SelectedFile = m.files[m.cursor].change.Path
 		}
 		return m, tea.Quit
+	case "ctrl+r":
+		return m.reloadConfig()
+	case "T":
+		return m.launchDiffTool()
+	case "M":
+		return m.launchMergeTool()
+	case "m":
+		m.cfg.CompactDiff = !m.cfg.CompactDiff
+		m.lastDiffContent = ""
+		return m, tea.Batch(m.loadDiffCmd(false), saveConfigCmd(m.cfg))
+	case "z":
+		m.cfg.ContextOnly = !m.cfg.ContextOnly
+		m.lastDiffContent = ""
+		return m, tea.Batch(m.loadDiffCmd(false), saveConfigCmd(m.cfg))
+	case "w":
+		return m.toggleWordDiff()
+	case "X":
+		return m.toggleUncapped()
+	case "D":
+		return m.cycleDiffAlgorithm()
+	case "f":
+		m.fullFile = !m.fullFile
+		m.lastDiffContent = ""
+		return m, m.loadDiffCmd(true)
+	case "i":
+		return m.explain()
+	case "a":
+		return m.blameRemoved()
+	case "x":
+		m.hexView = !m.hexView
+		m.lastDiffContent = ""
+		return m, m.loadDiffCmd(true)
+	case "r", "R":
+		return m.refresh(msg.String() == "R")
+	case "y", "Y":
+		return m.copyPath(msg.String() == "Y")
+	case "C":
+		return m.copyDiffMarkdown()
 	case "b":
 		return m.enterBranchMode()
 	case "tab":
 		return m.toggleStage()
+	case "s":
+		if m.selecting {
+			return m.stageLines()
+		}
+		return m.stageHunk()
+	case "J":
+		return m.extendSelection(1)
+	case "K":
+		return m.extendSelection(-1)
+	case "]":
+		return m.jumpHunk(1)
+	case "[":
+		return m.jumpHunk(-1)
 	case "v":
 		m.splitDiff = !m.splitDiff
+		m.cfg.SplitDiff = m.splitDiff
 		m.prevCurs = -1
 		m.lastDiffContent = ""
 		return m, tea.Batch(m.loadDiffCmd(true), m.saveSplitPrefCmd())
@@ -34,3 +142,59 @@ func (m Model) updateDiffMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	m.viewport, cmd = m.viewport.Update(msg)
 	return m, cmd
 }
+
+// relativeGutterActive reports whether the diff gutter is numbered relative
+// to the diff-line cursor. Split view always keeps absolute numbers.
+func (m Model) relativeGutterActive() bool {
+	split := (m.splitDiff || m.fullFile) && m.diffContentWidth() >= minSplitWidth
+	return m.cfg.RelativeLineNums && m.mode == modeDiff && !split && !m.cfg.CompactDiff
+}
+
+// moveDiffCursor moves the diff-line cursor, keeps it in view, and re-renders
+// the gutter around the new position from the cached parse.
+func (m Model) moveDiffCursor(delta int) (tea.Model, tea.Cmd) {
+	last := max(0, strings.Count(m.lastDiffContent, "\n")-1)
+	m.diffCursor = min(max(m.diffCursor+delta, 0), last)
+	if m.diffCursor < m.viewport.YOffset {
+		m.viewport.SetYOffset(m.diffCursor)
+	} else if h := m.viewport.Height; h > 0 && m.diffCursor >= m.viewport.YOffset+h {
+		m.viewport.SetYOffset(m.diffCursor - h + 1)
+	}
+	return m, m.rerenderDiffCmd()
+}
+
+// toggleUncapped lifts the max_diff_lines cap for the current file, or
+// restores it.
+func (m Model) toggleUncapped() (tea.Model, tea.Cmd) {
+	if len(m.files) == 0 {
+		return m, nil
+	}
+	path := m.files[m.cursor].change.Path
+	if m.uncappedPath == path {
+		m.uncappedPath = ""
+	} else {
+		m.uncappedPath = path
+	}
+	m.lastDiffContent = ""
+	return m, m.loadDiffCmd(false)
+}
+
+// cycleDiffAlgorithm switches to the next git diff algorithm, reloads the
+// diff with it and saves the choice.
+func (m Model) cycleDiffAlgorithm() (tea.Model, tea.Cmd) {
+	cfg := m.cfg
+	cfg.DiffAlgorithm = cycleChoice(config.DiffAlgorithms(), cfg.DiffAlgorithm, 1)
+	m = m.useConfig(cfg)
+	m.statusMsg = "diff algorithm: " + cfg.DiffAlgorithm
+	return m, tea.Batch(m.loadDiffCmd(false), saveConfigCmd(m.cfg))
+}
+
+// toggleWordDiff switches between line and git word diffs, reloads the diff
+// and saves the choice.
+func (m Model) toggleWordDiff() (tea.Model, tea.Cmd) {
+	cfg := m.cfg
+	cfg.WordDiff = !cfg.WordDiff
+	m = m.useConfig(cfg)
+	m.statusMsg = "word diff " + onOff(cfg.WordDiff)
+	return m, tea.Batch(m.loadDiffCmd(false), saveConfigCmd(m.cfg))
+}
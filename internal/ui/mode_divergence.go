@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+// Divergence mode: the branch's relationship to its upstream (merge base,
+// commits to push, commits to pull), shown in the diff panel.
+
+func (m Model) showDivergence() (tea.Model, tea.Cmd) {
+	if m.upstream.Upstream == "" {
+		m.statusMsg = "no upstream configured"
+		return m, nil
+	}
+	repo := m.repo
+	from := m.mode
+	return m, func() tea.Msg {
+		d, err := repo.UpstreamDivergence()
+		return divergenceLoadedMsg{divergence: d, from: from, err: err}
+	}
+}
+
+func (m Model) handleDivergenceLoaded(msg divergenceLoadedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.statusMsg = "upstream: " + firstLine(msg.err.Error())
+		return m, nil
+	}
+	// The user moved on while git ran; don't pull them back.
+	if m.mode != msg.from {
+		return m, nil
+	}
+	m.mode = modeDivergence
+	m.viewport.SetContent(renderDivergence(msg.divergence, m.styles))
+	m.viewport.GotoTop()
+	return m, nil
+}
+
+// renderDivergence lists the ahead and behind commits under a summary line.
+func renderDivergence(d git.Divergence, styles Styles) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, " HEAD ↑%d ↓%d %s", d.AheadCount, d.BehindCount, d.Upstream)
+	if d.MergeBase != "" {
+		b.WriteString(styles.HelpDesc.Render("  merge-base ") + styles.Accent.Render(d.MergeBase))
+	}
+	b.WriteString("\n\n")
+	writeDivergenceSection(&b, "Ahead (will be pushed)", d.Ahead, d.AheadCount, styles)
+	b.WriteByte('\n')
+	writeDivergenceSection(&b, "Behind (need pulling)", d.Behind, d.BehindCount, styles)
+	return b.String()
+}
+
+// writeDivergenceSection lists commits, noting how many of total were left
+// out when the list was cut at git.DefaultLogMax.
+func writeDivergenceSection(b *strings.Builder, title string, commits []git.Commit, total int, styles Styles) {
+	b.WriteString(" " + styles.HelpKey.Render(title) + "\n")
+	if len(commits) == 0 {
+		b.WriteString(styles.HelpDesc.Render("   none") + "\n")
+		return
+	}
+	for _, c := range commits {
+		fmt.Fprintf(b, "   %s %s %s\n", styles.Accent.Render(c.Short), c.Subject,
+			styles.HelpDesc.Render("— "+c.Author+", "+c.Date))
+	}
+	if more := total - len(commits); more > 0 {
+		b.WriteString(styles.HelpDesc.Render(fmt.Sprintf("   … and %d more", more)) + "\n")
+	}
+}
+
+func (m Model) updateDivergenceMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "esc", "U":
+		m.mode = modeFileList
+		m.lastDiffContent = ""
+		return m, m.loadDiffCmd(true)
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
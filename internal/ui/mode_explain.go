@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/config"
+)
+
+// Explain mode: an AI explanation of the current file's diff, shown in the
+// diff panel. Leaving returns to the diff.
+
+const defaultExplainPrompt = "Explain what this diff changes and why it might matter to a reviewer. Be concise:"
+
+// explain sends the selected file's diff to the explain command.
+func (m Model) explain() (tea.Model, tea.Cmd) {
+	if len(m.files) == 0 || m.explaining {
+		return m, nil
+	}
+	f := m.files[m.cursor]
+	repo := m.repo.WithWordDiff(false) // the AI reads a line diff
+	ref := m.ref
+	cfg := m.cfg
+	from := m.mode
+	m.explaining = true
+	explainCmd := func() tea.Msg {
+		var diff string
+		var err error
+		if f.untracked {
+			diff, err = repo.ReadFileContent(f.change.Path)
+		} else {
+			diff, err = repo.DiffFile(f.change.Path, f.change.Staged, ref)
+		}
+		if err != nil {
+			return explainDoneMsg{err: err}
+		}
+		if strings.TrimSpace(diff) == "" {
+			return explainDoneMsg{err: fmt.Errorf("empty diff")}
+		}
+		out, err := runAICmd(context.Background(), explainCmdLine(cfg), buildExplainPrompt(cfg, f.change.Path, diff))
+		return explainDoneMsg{path: f.change.Path, from: from, text: strings.TrimSpace(out), err: err}
+	}
+	return m, tea.Batch(explainCmd, m.spinner.Tick)
+}
+
+// explainCmdLine picks explain_cmd, falling back to the commit message command.
+func explainCmdLine(cfg config.Config) string {
+	if cfg.ExplainCmd != "" {
+		return cfg.ExplainCmd
+	}
+	return orDefault(cfg.CommitMsgCmd, defaultCommitMsgCmd)
+}
+
+func buildExplainPrompt(cfg config.Config, path, diff string) string {
+	return orDefault(cfg.ExplainPrompt, defaultExplainPrompt) + "\n\nFile: " + path + "\n\n" + truncateForPrompt(diff)
+}
+
+func (m Model) handleExplainDone(msg explainDoneMsg) (tea.Model, tea.Cmd) {
+	m.explaining = false
+	if msg.err != nil {
+		m.statusMsg = "explain failed: " + firstLine(msg.err.Error())
+		return m, nil
+	}
+	// Taking over the diff panel is only right where it was asked for.
+	if m.mode != msg.from || m.cursor >= len(m.files) || m.files[m.cursor].change.Path != msg.path {
+		m.statusMsg = "explanation of " + msg.path + " dropped: moved on before it arrived"
+		return m, nil
+	}
+	m.mode = modeExplain
+	m.explainPath = msg.path
+	m.viewport.SetContent(m.styles.Explanation.Width(m.viewport.Width - 1).Render(msg.text))
+	m.viewport.GotoTop()
+	return m, nil
+}
+
+func (m Model) updateExplainMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "esc", "h", "left":
+		m.mode = modeDiff
+		m.lastDiffContent = ""
+		return m, m.loadDiffCmd(true)
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
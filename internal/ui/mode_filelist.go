@@ -6,7 +6,10 @@ import tea "github.com/charmbracelet/bubbletea"
 
 func (m Model) updateFileListMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	m.statusMsg = ""
-	if msg.String() == "P" {
+	if m.stagingOnly && stagingOnlyBlocks(m.mode, msg.String()) {
+		return m, nil
+	}
+	if msg.String() == "P" && !m.reviewOnly {
 		if m.pushConfirm {
 			m.pushConfirm = false
 			m.statusMsg = "pushing..."
@@ -29,24 +32,32 @@ func (m Model) updateFileListMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 	m.pushConfirm = false
+	stashPull := m.stashPull
+	m.stashPull = false
+
+	if p, ok := m.pending.feed(msg.String()); ok {
+		m.pending = p
+		return m, nil
+	}
+	keys := m.pending
+	m.pending = pendingKeys{}
 
 	switch msg.String() {
 	case "q", "ctrl+c":
 		return m, tea.Quit
 	case "j", "down":
-		if m.cursor < len(m.files)-1 {
-			m.cursor++
-		}
+		m.cursor = max(0, min(m.cursor+keys.repeat(), len(m.files)-1))
 	case "k", "up":
-		if m.cursor > 0 {
-			m.cursor--
-		}
+		m.cursor = max(m.cursor-keys.repeat(), 0)
 	case "g":
-		m.cursor = 0
+		m.cursor = keys.jumpTarget(len(m.files)-1, 0)
 	case "G":
-		m.cursor = max(0, len(m.files)-1)
+		m.cursor = keys.jumpTarget(len(m.files)-1, max(0, len(m.files)-1))
 	case "enter", "l", "right":
 		m.mode = modeDiff
+		if m.cfg.RelativeLineNums {
+			return m, m.loadDiffCmd(false)
+		}
 		return m, nil
 	case "e":
 		if m.cursor < len(m.files) {
@@ -57,34 +68,120 @@ func (m Model) updateFileListMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.toggleStage()
 	case "a":
 		return m.stageAll()
+	case "ctrl+a":
+		return m.unstageAll()
+	case "A":
+		return m.toggleStageStatus()
 	case "c":
 		return m.enterCommitMode()
 	case "b":
 		return m.enterBranchMode()
 	case "v":
 		m.splitDiff = !m.splitDiff
+		m.cfg.SplitDiff = m.splitDiff
 		m.prevCurs = -1
 		m.lastDiffContent = ""
 		return m, tea.Batch(m.loadDiffCmd(true), m.saveSplitPrefCmd())
+	case ".":
+		m.cfg.ShowFullPath = !m.cfg.ShowFullPath
+		return m, saveConfigCmd(m.cfg)
+	case "o":
+		return m.openPR()
+	case "r", "R":
+		return m.refresh(msg.String() == "R")
+	case "y", "Y":
+		return m.copyPath(msg.String() == "Y")
+	case "S":
+		return m.enterSummaryMode()
+	case "U":
+		return m.showDivergence()
+	case "t":
+		return m.toggleTail()
+	case "H":
+		return m.toggleHidden()
+	case " ":
+		return m.enterActionsMode()
+	case ",":
+		return m.enterSettingsMode()
+	case "ctrl+r":
+		return m.reloadConfig()
+	case "T":
+		return m.launchDiffTool()
+	case "M":
+		return m.launchMergeTool()
+	case "X":
+		if m.reviewOnly || m.stagedOnly || m.ref != "" {
+			return m, nil
+		}
+		return m, m.cleanPreviewCmd()
 	case "F":
+		if m.reviewOnly {
+			return m, nil
+		}
 		if m.upstream.Upstream == "" {
 			m.statusMsg = "no upstream configured"
 			return m, nil
 		}
+		if stashPull {
+			m.statusMsg = "stashing and pulling..."
+			return m, m.pullCmd(true)
+		}
+		if m.treeDirty() {
+			m.stashPull = true
+			m.statusMsg = "working tree dirty — press F again to stash and pull"
+			return m, nil
+		}
 		m.statusMsg = "pulling..."
-		return m, m.pullCmd()
+		return m, m.pullCmd(false)
 	}
 	if m.cursor != m.prevCurs {
 		m.prevCurs = m.cursor
+		m = m.clampFileScroll()
 		return m, m.loadDiffCmd(true)
 	}
 	return m, nil
 }
 
+// startsGroup reports whether file i is preceded by a Staged/Changes
+// header. Headers are only shown when both kinds can be listed.
+func (m Model) startsGroup(i int) bool {
+	if m.stagedOnly || m.ref != "" {
+		return false
+	}
+	return i == 0 || m.files[i].change.Staged != m.files[i-1].change.Staged
+}
+
+// clampFileScroll scrolls the file list so the cursor row is visible,
+// keeping the group header above the first file of a group in view.
+func (m Model) clampFileScroll() Model {
+	h := m.contentHeight()
+	if h <= 0 || m.cursor >= len(m.files) {
+		return m
+	}
+	row := 0
+	for i := 0; i <= m.cursor; i++ {
+		if m.startsGroup(i) {
+			row++
+		}
+	}
+	row += m.cursor
+	top := row
+	if m.startsGroup(m.cursor) {
+		top--
+	}
+	if top < m.fileOffset {
+		m.fileOffset = top
+	} else if row >= m.fileOffset+h {
+		m.fileOffset = row - h + 1
+	}
+	return m
+}
+
 func (m Model) nextFile() (tea.Model, tea.Cmd) {
 	if m.cursor < len(m.files)-1 {
 		m.cursor++
 		m.prevCurs = m.cursor
+		m = m.clampFileScroll()
 		return m, m.loadDiffCmd(true)
 	}
 	return m, nil
@@ -94,6 +191,7 @@ func (m Model) prevFile() (tea.Model, tea.Cmd) {
 	if m.cursor > 0 {
 		m.cursor--
 		m.prevCurs = m.cursor
+		m = m.clampFileScroll()
 		return m, m.loadDiffCmd(true)
 	}
 	return m, nil
@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Hook output mode: full output of a failed commit (hooks included), shown
+// in the diff panel. Leaving returns to commit mode with the message kept.
+
+func (m Model) showHookOutput(output string) Model {
+	m.mode = modeHookOutput
+	m.statusMsg = "commit failed"
+	var b strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		b.WriteString(m.styles.DiffRemoved.Render(line))
+		b.WriteByte('\n')
+	}
+	m.viewport.SetContent(b.String())
+	m.viewport.GotoTop()
+	return m.fitViewport()
+}
+
+func (m Model) updateHookOutputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "esc", "enter":
+		m.mode = modeCommit
+		m = m.fitViewport()
+		m.lastDiffContent = ""
+		return m, tea.Batch(m.commitInput.Focus(), m.loadDiffCmd(true))
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
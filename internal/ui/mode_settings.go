@@ -0,0 +1,327 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/config"
+	"github.com/jansmrcka/differ/internal/theme"
+)
+
+// Settings mode: edit Config fields in place, saving and applying each change.
+
+// settingField maps one Config field to a widget. Fields with adjust are
+// toggles/choices/numbers changed with ←/→; fields with setText are edited
+// in a text input.
+type settingField struct {
+	label   string
+	value   func(c config.Config) string
+	adjust  func(c *config.Config, delta int)
+	setText func(c *config.Config, s string)
+}
+
+func settingFields() []settingField {
+	return []settingField{
+		{
+			label:  "Theme",
+			value:  func(c config.Config) string { return c.Theme },
+			adjust: func(c *config.Config, d int) { c.Theme = cycleChoice(themeNames(), c.Theme, d) },
+		},
+		{
+			label:  "Split diff",
+			value:  func(c config.Config) string { return onOff(c.SplitDiff) },
+			adjust: func(c *config.Config, _ int) { c.SplitDiff = !c.SplitDiff },
+		},
+		{
+			label:  "Compact diff",
+			value:  func(c config.Config) string { return onOff(c.CompactDiff) },
+			adjust: func(c *config.Config, _ int) { c.CompactDiff = !c.CompactDiff },
+		},
+		{
+			label:  "Changes only",
+			value:  func(c config.Config) string { return onOff(c.ContextOnly) },
+			adjust: func(c *config.Config, _ int) { c.ContextOnly = !c.ContextOnly },
+		},
+		{
+			label:  "Word diff",
+			value:  func(c config.Config) string { return onOff(c.WordDiff) },
+			adjust: func(c *config.Config, _ int) { c.WordDiff = !c.WordDiff },
+		},
+		{
+			label: "Diff algorithm",
+			value: func(c config.Config) string { return orDefault(c.DiffAlgorithm, "myers") },
+			adjust: func(c *config.Config, d int) {
+				c.DiffAlgorithm = cycleChoice(config.DiffAlgorithms(), c.DiffAlgorithm, d)
+			},
+		},
+		{
+			label:  "Whitespace errors",
+			value:  func(c config.Config) string { return onOff(c.ShowWhitespaceErrors) },
+			adjust: func(c *config.Config, _ int) { c.ShowWhitespaceErrors = !c.ShowWhitespaceErrors },
+		},
+		{
+			label:  "Relative line numbers",
+			value:  func(c config.Config) string { return onOff(c.RelativeLineNums) },
+			adjust: func(c *config.Config, _ int) { c.RelativeLineNums = !c.RelativeLineNums },
+		},
+		{
+			label:  "Gutter",
+			value:  func(c config.Config) string { return orDefault(c.Gutter, "both") },
+			adjust: func(c *config.Config, d int) { c.Gutter = cycleChoice(config.Gutters(), c.Gutter, d) },
+		},
+		{
+			label:  "Tab width",
+			value:  func(c config.Config) string { return strconv.Itoa(c.TabWidth) },
+			adjust: func(c *config.Config, d int) { c.TabWidth = min(max(c.TabWidth+d, 1), 16) },
+		},
+		{
+			label: "File list ratio",
+			value: func(c config.Config) string {
+				if c.FileListRatio <= 0 {
+					return "fixed"
+				}
+				return strconv.FormatFloat(c.FileListRatio, 'f', 2, 64)
+			},
+			adjust: func(c *config.Config, d int) {
+				r := float64(int(c.FileListRatio*20+0.5)+d) / 20
+				c.FileListRatio = min(max(r, 0), 0.8)
+			},
+		},
+		{
+			label:  "AI suggestions",
+			value:  func(c config.Config) string { return strconv.Itoa(max(c.CommitMsgCount, 1)) },
+			adjust: func(c *config.Config, d int) { c.CommitMsgCount = min(max(c.CommitMsgCount+d, 1), 9) },
+		},
+		{
+			label: "Commit subject limit",
+			value: func(c config.Config) string {
+				if c.CommitSubjectMax <= 0 {
+					return "off"
+				}
+				return strconv.Itoa(c.CommitSubjectMax)
+			},
+			adjust: func(c *config.Config, d int) { c.CommitSubjectMax = min(max(c.CommitSubjectMax+d, 0), 200) },
+		},
+		{
+			label:  "Default view",
+			value:  func(c config.Config) string { return orDefault(c.DefaultView, "files") },
+			adjust: func(c *config.Config, d int) { c.DefaultView = cycleChoice(config.Views(), c.DefaultView, d) },
+		},
+		{
+			label:  "Full paths in file list",
+			value:  func(c config.Config) string { return onOff(c.ShowFullPath) },
+			adjust: func(c *config.Config, _ int) { c.ShowFullPath = !c.ShowFullPath },
+		},
+		{
+			label:  "Card borders",
+			value:  func(c config.Config) string { return onOff(c.Borders) },
+			adjust: func(c *config.Config, _ int) { c.Borders = !c.Borders },
+		},
+		{
+			label:  "Icons",
+			value:  func(c config.Config) string { return orDefault(c.Icons, "ascii") },
+			adjust: func(c *config.Config, d int) { c.Icons = cycleChoice(config.IconSets(), c.Icons, d) },
+		},
+		{
+			label:  "Auto-stage on commit",
+			value:  func(c config.Config) string { return onOff(c.AutoStageOnCommit) },
+			adjust: func(c *config.Config, _ int) { c.AutoStageOnCommit = !c.AutoStageOnCommit },
+		},
+		{
+			label:   "Editor command",
+			value:   func(c config.Config) string { return orDefault(c.EditorCmd, "$EDITOR {file}") },
+			setText: func(c *config.Config, s string) { c.EditorCmd = s },
+		},
+		{
+			label:   "Diff tool",
+			value:   func(c config.Config) string { return orDefault(c.DiffTool, "git default") },
+			setText: func(c *config.Config, s string) { c.DiffTool = s },
+		},
+		{
+			label:   "Merge tool",
+			value:   func(c config.Config) string { return orDefault(c.MergeTool, "git default") },
+			setText: func(c *config.Config, s string) { c.MergeTool = s },
+		},
+		{
+			label:   "Commit message command",
+			value:   func(c config.Config) string { return orDefault(c.CommitMsgCmd, defaultCommitMsgCmd) },
+			setText: func(c *config.Config, s string) { c.CommitMsgCmd = s },
+		},
+	}
+}
+
+func themeNames() []string {
+	names := make([]string, 0, len(theme.Themes))
+	for name := range theme.Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func cycleChoice(choices []string, current string, delta int) string {
+	idx := 0
+	for i, c := range choices {
+		if c == current {
+			idx = i
+		}
+	}
+	n := len(choices)
+	return choices[((idx+delta)%n+n)%n]
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func (m Model) enterSettingsMode() (tea.Model, tea.Cmd) {
+	m.mode = modeSettings
+	m.settingsCursor = 0
+	m.settingsEditing = false
+	return m, nil
+}
+
+func (m Model) updateSettingsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.settingsEditing {
+		return m.updateSettingsInput(msg)
+	}
+	fields := settingFields()
+	f := fields[m.settingsCursor]
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", ",":
+		m.mode = modeFileList
+		return m, nil
+	case "j", "down":
+		m.settingsCursor = min(m.settingsCursor+1, len(fields)-1)
+	case "k", "up":
+		m.settingsCursor = max(m.settingsCursor-1, 0)
+	case "l", "right", "enter", " ":
+		if f.setText != nil {
+			m.settingsEditing = true
+			m.settingsInput.SetValue(f.value(m.cfg))
+			m.settingsInput.CursorEnd()
+			m.settingsInput.Focus()
+			return m, textinput.Blink
+		}
+		return m.applySetting(f, 1)
+	case "h", "left":
+		if f.adjust != nil {
+			return m.applySetting(f, -1)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) updateSettingsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.settingsEditing = false
+		m.settingsInput.Blur()
+		return m, nil
+	case "enter":
+		m.settingsEditing = false
+		m.settingsInput.Blur()
+		f := settingFields()[m.settingsCursor]
+		cfg := m.cfg
+		f.setText(&cfg, strings.TrimSpace(m.settingsInput.Value()))
+		return m.applyConfig(cfg)
+	}
+	var cmd tea.Cmd
+	m.settingsInput, cmd = m.settingsInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) applySetting(f settingField, delta int) (tea.Model, tea.Cmd) {
+	cfg := m.cfg
+	f.adjust(&cfg, delta)
+	return m.applyConfig(cfg)
+}
+
+// applyConfig makes cfg live (styles, split, layout, diff) and saves it.
+func (m Model) applyConfig(cfg config.Config) (tea.Model, tea.Cmd) {
+	m = m.useConfig(cfg)
+	return m, tea.Batch(m.loadDiffCmd(true), saveConfigCmd(cfg))
+}
+
+// reloadConfig re-reads the config file and makes it live without saving,
+// so edits made in another window take effect in place.
+func (m Model) reloadConfig() (tea.Model, tea.Cmd) {
+	m = m.useConfig(config.Load())
+	m.statusMsg = "config reloaded"
+	return m, m.loadDiffCmd(true)
+}
+
+// useConfig swaps in cfg, rebuilding styles when the theme changed and
+// invalidating the rendered diff.
+func (m Model) useConfig(cfg config.Config) Model {
+	if cfg.Theme != m.cfg.Theme {
+		if t, ok := theme.Themes[cfg.Theme]; ok {
+			mono := m.styles.Monochrome
+			m.theme = t
+			m.styles = NewStyles(t)
+			m.styles.Monochrome = mono
+		}
+	}
+	m.cfg = cfg
+	m.splitDiff = cfg.SplitDiff
+	m.tail = cfg.Tail
+	if m.repo != nil {
+		m.repo = m.repo.WithDiffAlgorithm(cfg.DiffAlgorithm).WithWordDiff(cfg.WordDiff)
+	}
+	if m.width > 0 {
+		m.fileListW = computeFileListWidth(cfg.FileListRatio, m.width)
+		m.viewport.Width = m.diffContentWidth()
+		m = m.fitViewport()
+	}
+	m.prevCurs = -1
+	m.lastDiffContent = ""
+	return m
+}
+
+func saveConfigCmd(cfg config.Config) tea.Cmd {
+	return func() tea.Msg { return savePrefDoneMsg{err: config.Save(cfg)} }
+}
+
+func (m Model) renderSettings() string {
+	fields := settingFields()
+	labelW := 0
+	for _, f := range fields {
+		labelW = max(labelW, len(f.label))
+	}
+	var b strings.Builder
+	for i, f := range fields {
+		value := f.value(m.cfg)
+		if f.adjust != nil {
+			value = "‹ " + value + " ›"
+		}
+		if m.settingsEditing && i == m.settingsCursor {
+			value = m.settingsInput.View()
+		}
+		line := fmt.Sprintf("%-*s  %s", labelW, f.label, value)
+		if i == m.settingsCursor && !m.settingsEditing {
+			b.WriteString(renderSelectedRow(m.styles, line, m.diffWidth()))
+		} else {
+			b.WriteString(m.styles.FileItem.Render(line))
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	b.WriteString(m.styles.HelpDesc.Render(" changes are saved to ~/.config/differ/config.json"))
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Summary mode: a `git diff --stat`-like overview built from file stats.
+
+func (m Model) enterSummaryMode() (tea.Model, tea.Cmd) {
+	if len(m.files) == 0 {
+		return m, nil
+	}
+	m.mode = modeSummary
+	m.viewport.SetContent(m.renderSummary())
+	m.viewport.GotoTop()
+	return m, nil
+}
+
+func (m Model) updateSummaryMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "esc", "S":
+		return m.leaveSummaryMode(modeFileList)
+	case "enter", "l", "right":
+		return m.leaveSummaryMode(modeDiff)
+	case "j", "down":
+		if m.cursor < len(m.files)-1 {
+			m.cursor++
+		}
+	case "k", "up":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	default:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+	m = m.clampFileScroll()
+	offset := m.viewport.YOffset
+	m.viewport.SetContent(m.renderSummary())
+	m.viewport.SetYOffset(offset)
+	if m.cursor < m.viewport.YOffset {
+		m.viewport.SetYOffset(m.cursor)
+	} else if h := m.viewport.Height; h > 0 && m.cursor >= m.viewport.YOffset+h {
+		m.viewport.SetYOffset(m.cursor - h + 1)
+	}
+	return m, nil
+}
+
+// leaveSummaryMode restores the diff panel for the file under the cursor.
+func (m Model) leaveSummaryMode(next viewMode) (tea.Model, tea.Cmd) {
+	m.mode = next
+	m.prevCurs = m.cursor
+	m.lastDiffContent = ""
+	return m, m.loadDiffCmd(true)
+}
+
+// renderSummary lists every file with a proportional +/- bar and a totals footer.
+func (m Model) renderSummary() string {
+	nameW, countW, maxTotal := 0, 1, 0
+	added, deleted := 0, 0
+	for _, f := range m.files {
+		nameW = max(nameW, lipgloss.Width(f.change.Path))
+		total := f.change.AddedLines + f.change.DeletedLines
+		countW = max(countW, len(fmt.Sprint(total)))
+		maxTotal = max(maxTotal, total)
+		added += f.change.AddedLines
+		deleted += f.change.DeletedLines
+	}
+	w := m.diffContentWidth()
+	nameW = min(nameW, max(10, w/2))
+	barW := max(1, w-nameW-countW-6)
+
+	var b strings.Builder
+	for i, f := range m.files {
+		line := " " + padRight(truncatePath(f.change.Path, nameW), nameW) + " | " + m.summaryStat(f, countW, barW, maxTotal)
+		if i == m.cursor {
+			line = renderSelectedRow(m.styles, line, w)
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteString(m.styles.HelpDesc.Render(fmt.Sprintf(" %d files changed, %d insertions(+), %d deletions(-)", len(m.files), added, deleted)))
+	return b.String()
+}
+
+func (m Model) summaryStat(f fileItem, countW, barW, maxTotal int) string {
+	total := f.change.AddedLines + f.change.DeletedLines
+	if total == 0 {
+		return fmt.Sprintf("%*s", countW, "Bin")
+	}
+	cells := max(1, total*barW/max(maxTotal, 1))
+	a, d := splitCells(f.change.AddedLines, f.change.DeletedLines, cells)
+	return fmt.Sprintf("%*d ", countW, total) +
+		m.styles.DiffAdded.Render(strings.Repeat("+", a)) +
+		m.styles.DiffRemoved.Render(strings.Repeat("-", d))
+}
+
+func padRight(s string, w int) string {
+	if pad := w - lipgloss.Width(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
@@ -1,9 +1,14 @@
 package ui
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -19,9 +24,20 @@ const (
 	modeDiff
 	modeCommit
 	modeBranchPicker
+	modeClean
+	modeSummary
+	modeSettings
+	modeHookOutput
+	modeExplain
+	modeDivergence
+	modeActions
+	modeStagePreview
 )
 
-const fileListWidth = 35
+const (
+	defaultFileListWidth = 35
+	minFileListWidth     = 20
+)
 const pollInterval = 2 * time.Second
 
 const (
@@ -33,31 +49,124 @@ type tickMsg time.Time
 
 type diffLoadedMsg struct {
 	content     string
+	kinds       []DiffLineType // one per rendered line, nil when lines don't map 1:1
+	oldNums     []int          // like kinds: removed lines' old line numbers, -1 elsewhere
+	newNums     []int          // like kinds: added lines' new line numbers, -1 elsewhere
+	render      *diffRender    // nil unless the diff can be re-rendered without git
+	hunks       []hunkHeader
 	index       int
+	width       int // diff panel width it was rendered for
 	resetScroll bool
+	hash        uint64 // contentHash of the diff's source
+	poll        bool   // from pollDiffCmd: reset scroll only if hash changed
 }
 
-type filesRefreshedMsg struct{ files []fileItem }
-type commitDoneMsg struct{ err error }
+type filesRefreshedMsg struct {
+	files  []fileItem
+	force  bool   // bypass filesEqual and reload the diff
+	err    error  // failed stage/unstage that preceded the refresh
+	newest string // most recently modified path, set in tail mode
+	follow string // path to keep selected after it was staged or unstaged
+
+	hunkPath   string // file a hunk was staged or unstaged from
+	hunkStaged bool   // whether hunkPath's hunk came from its staged diff
+
+	dirty bool // tracked changes, asked of git when the list can't tell
+}
+type stagePreviewMsg struct {
+	plan stagePlan
+	err  error
+}
+type autoStagedMsg struct {
+	files []fileItem
+	count int
+	err   error
+}
+type explainDoneMsg struct {
+	path string
+	from viewMode // mode the explanation was asked from
+	text string
+	err  error
+}
+type divergenceLoadedMsg struct {
+	divergence git.Divergence
+	from       viewMode // mode the view was asked from
+	err        error
+}
+type commitDoneMsg struct {
+	hash string
+	err  error
+}
 
 type commitMsgGeneratedMsg struct {
-	message string
-	err     error
+	message     string
+	suggestions []string // set when several candidates were requested
+	err         error
 }
 
 type branchesLoadedMsg struct {
 	branches []string
+	details  map[string]git.BranchInfo
+	merged   map[string]bool
 	current  string
 	err      error
 }
 
-type branchSwitchedMsg struct{ err error }
+type branchSwitchedMsg struct {
+	err      error
+	detached bool
+	stashed  bool // changes were stashed before switching
+}
 
 type upstreamStatusMsg struct{ info git.UpstreamInfo }
 type pushDoneMsg struct{ err error }
-type pullDoneMsg struct{ err error }
+type pullDoneMsg struct {
+	err     error
+	stashed bool // changes were stashed before pulling
+}
+type fetchDoneMsg struct{ err error }
+
+type externalToolDoneMsg struct {
+	tool string // "difftool" or "mergetool"
+	err  error
+}
+
+type blameLoadedMsg struct {
+	line  int // old line number that was blamed
+	blame git.BlameLine
+	err   error
+}
 type savePrefDoneMsg struct{ err error }
 
+type urlOpenedMsg struct {
+	url string
+	err error
+}
+
+type clipboardDoneMsg struct {
+	label string
+	osc52 string // text to copy with OSC 52, when no clipboard tool was found
+	err   error
+}
+
+type osc52ClearedMsg struct{ text string }
+
+type clipboardPastedMsg struct {
+	text string
+	err  error
+}
+
+type cleanPreviewMsg struct {
+	files   []string
+	ignored bool
+	err     error
+}
+
+type cleanDoneMsg struct {
+	removed []string
+	err     error
+}
+
 type branchCreatedMsg struct {
 	name string
 	err  error
@@ -72,33 +181,91 @@ type Model struct {
 	theme      theme.Theme
 	stagedOnly bool
 	ref        string
+	// hiddenFiles match hide_patterns and are kept out of files unless
+	// showHidden is on.
+	hiddenFiles []fileItem
+	showHidden  bool
+	stagingOnly bool // `differ add`: no commit, branch or remote actions
+	fullFile    bool // diff shows whole old/new files side by side
+	reviewOnly  bool // --review: navigation and viewing only
+	// compareBase is set when ref is base...HEAD from --base/compare_base.
+	compareBase string
+	// commit is set when ref is one commit's change, opened from the log.
+	commit string
 
 	mode          viewMode
+	pending       pendingKeys
 	cursor        int
+	fileOffset    int             // first visible row of the file list
+	newFiles      map[string]bool // appeared in the last refresh; cleared on keypress
 	prevCurs      int
 	viewport      viewport.Model
 	commitInput   textinput.Model
 	statusMsg     string
+	osc52         string // text being copied with OSC 52 by the next frames
 	generatingMsg bool
+	committing    bool
+	explaining    bool   // waiting for the explain command
+	fetching      bool   // fetch_on_start fetch still running
+	explainPath   string // file the shown explanation is for
+	spinner       spinner.Model
+	suggestions   []string
+	suggestionIdx int
 	splitDiff     bool
+	tail          bool   // follow the most recently modified file
+	hexView       bool   // render binary files as a hexdump
+	uncappedPath  string // file shown without the max_diff_lines cap
 	width         int
 	height        int
+	fileListW     int // 0 = defaultFileListWidth
 	ready         bool
 	SelectedFile  string
 
+	cancelGenerate context.CancelFunc // stops the running commit message command
+
 	lastDiffContent string
+	diffHash        uint64         // contentHash of the shown diff
+	diffKinds       []DiffLineType // line types of the shown diff, for the minimap
+	diffHunks       []hunkHeader   // hunk headers of the shown diff, for the sticky header
+	diffOldNums     []int          // old line number of each removed line shown, -1 elsewhere
+	diffNewNums     []int          // new line number of each added line shown, -1 elsewhere
+	diffRender      *diffRender    // parse of the shown line diff, for cursor re-renders
+	diffCursor      int            // diff-line cursor, used for the relative gutter
+	hunkCursor      int            // hunk in diffHunks picked with ]/[, when hunkPicked
+	hunkPicked      bool
+	hunkRestore     bool       // reselect hunkCursor once the diff reloads after s
+	selecting       bool       // a J/K line selection is active
+	selAnchor       int        // rendered row the selection started on
+	selEnd          int        // rendered row the selection was extended to
+	stagePlan       *stagePlan // patch s built, shown in modeStagePreview until applied
 
 	branches         []string
 	filteredBranches []string
 	branchCursor     int
 	branchOffset     int
 	currentBranch    string
+	mergedBranches   map[string]bool
+	branchDetails    map[string]git.BranchInfo
 	branchFilter     textinput.Model
 	branchCreating   bool
+	branchDetaching  bool // branchInput takes a ref to check out detached
 	branchInput      textinput.Model
+	stashSwitch      string // dirty tree: a second enter on this branch stashes, then switches
 
 	upstream    git.UpstreamInfo
 	pushConfirm bool
+	stashPull   bool // dirty tree: F again stashes, then pulls
+	dirty       bool // staged-only and ref views: tracked changes at the last refresh
+
+	cleanFiles   []string
+	cleanIgnored bool
+	cleaning     bool // git clean is running; a second y is ignored
+
+	settingsCursor  int
+	settingsEditing bool
+	settingsInput   textinput.Model
+
+	actionsCursor int
 }
 
 type fileItem struct {
@@ -107,7 +274,11 @@ type fileItem struct {
 }
 
 func NewModel(repo *git.Repo, cfg config.Config, changes []git.FileChange, untracked []string, styles Styles, t theme.Theme, stagedOnly bool, ref string) Model {
-	files := buildFileItems(repo, changes, untracked)
+	files, hidden := partitionHidden(buildFileItems(repo, changes, untracked), cfg.HidePatterns)
+	cursor := 0
+	if cfg.StartOnTextFile {
+		cursor = firstTextFile(files)
+	}
 
 	ti := textinput.New()
 	ti.Placeholder = "commit message..."
@@ -116,25 +287,40 @@ func NewModel(repo *git.Repo, cfg config.Config, changes []git.FileChange, untra
 	bf := textinput.New()
 	bf.Placeholder = "filter..."
 	bf.CharLimit = 100
-	bf.Width = fileListWidth - 8
+	bf.Width = defaultFileListWidth - 8
 
 	bi := textinput.New()
 	bi.Placeholder = "branch name..."
 	bi.CharLimit = 100
 
+	si := textinput.New()
+	si.CharLimit = 200
+
+	sp := spinner.New(spinner.WithSpinner(spinner.MiniDot))
+
+	if repo != nil {
+		repo = repo.WithWordDiff(cfg.WordDiff)
+	}
+
 	return Model{
-		repo:         repo,
-		cfg:          cfg,
-		files:        files,
-		styles:       styles,
-		theme:        t,
-		stagedOnly:   stagedOnly,
-		ref:          ref,
-		splitDiff:    cfg.SplitDiff,
-		prevCurs:     -1,
-		commitInput:  ti,
-		branchFilter: bf,
-		branchInput:  bi,
+		repo:          repo,
+		cfg:           cfg,
+		files:         files,
+		hiddenFiles:   hidden,
+		styles:        styles,
+		theme:         t,
+		stagedOnly:    stagedOnly,
+		ref:           ref,
+		splitDiff:     cfg.SplitDiff,
+		tail:          cfg.Tail,
+		fetching:      cfg.FetchOnStart && repo != nil,
+		cursor:        cursor,
+		prevCurs:      -1,
+		commitInput:   ti,
+		branchFilter:  bf,
+		branchInput:   bi,
+		settingsInput: si,
+		spinner:       sp,
 	}
 }
 
@@ -144,20 +330,45 @@ func buildFileItems(repo *git.Repo, changes []git.FileChange, untracked []string
 		files = append(files, fileItem{change: c})
 	}
 	for _, path := range untracked {
-		added := 0
+		added, binary := 0, false
 		if repo != nil {
 			raw, err := repo.ReadFileContent(path)
 			if err == nil {
 				added = countLines(raw)
+				binary = isBinary([]byte(raw[:min(len(raw), binarySniffLen)]))
 			}
 		}
-		files = append(files, fileItem{change: git.FileChange{Path: path, Status: git.StatusUntracked, AddedLines: added}, untracked: true})
+		files = append(files, fileItem{change: git.FileChange{Path: path, Status: git.StatusUntracked, AddedLines: added, Binary: binary}, untracked: true})
 	}
 	return files
 }
 
+// firstTextFile is the index of the first file with a text diff, so the
+// diff panel opens on something readable. It is 0 when every file is binary.
+func firstTextFile(files []fileItem) int {
+	for i, f := range files {
+		if !f.change.Binary {
+			return i
+		}
+	}
+	return 0
+}
+
+// binarySniffLen is how much of a file is inspected for null bytes,
+// mirroring git's own binary heuristic.
+const binarySniffLen = 8000
+
+// isBinary reports whether data looks binary (contains a null byte in its head).
+func isBinary(data []byte) bool {
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// countLines counts lines of text content; binary content counts as zero.
 func countLines(s string) int {
-	if s == "" {
+	if s == "" || isBinary([]byte(s[:min(len(s), binarySniffLen)])) {
 		return 0
 	}
 	count := strings.Count(s, "\n")
@@ -184,13 +395,108 @@ func (m *Model) StartInCommitMode() {
 	m.commitInput.Focus()
 }
 
+// SetCompareBase shows the branch's changes since it forked from base
+// (base...HEAD). Like any ref comparison the view is read-only.
+func (m *Model) SetCompareBase(base string) {
+	m.compareBase = base
+	m.ref = git.RangeRef(base, "HEAD")
+}
+
+// SetCommit reviews a single commit against its first parent (or the empty
+// tree for a root commit). The view is read-only. It fails on a shallow
+// clone's boundary commit, which has no parent to diff against.
+func (m *Model) SetCommit(hash string) error {
+	ref, err := m.repo.CommitRef(hash)
+	if err != nil {
+		return err
+	}
+	m.commit = hash
+	m.ref = ref
+	m.reviewOnly = true
+	return nil
+}
+
 func (m Model) Init() tea.Cmd {
-	cmds := []tea.Cmd{m.loadDiffCmd(true), m.fetchUpstreamStatusCmd(), tickCmd()}
+	cmds := []tea.Cmd{m.loadDiffCmd(true), m.fetchUpstreamStatusCmd(), tickCmd(), m.windowTitleCmd()}
 	if m.mode == modeCommit {
 		cmds = append(cmds, textinput.Blink)
 	}
+	if m.fetching {
+		cmds = append(cmds, m.fetchCmd())
+	}
+	if m.stagedOnly || m.ref != "" {
+		cmds = append(cmds, m.refreshFilesCmd()) // fills in dirty
+	}
 	return tea.Batch(cmds...)
 }
 
-func (m Model) contentHeight() int { return m.height - 4 }
-func (m Model) diffWidth() int     { return m.width - fileListWidth - 2 - 1 - 2 }
+// windowTitleCmd sets the terminal title to the repo and current branch.
+func (m Model) windowTitleCmd() tea.Cmd {
+	if m.repo == nil {
+		return nil
+	}
+	return tea.SetWindowTitle(windowTitle(m.repo.Dir(), m.repo.BranchName()))
+}
+
+// windowTitle formats the terminal title as "differ: <repo> [<branch>]".
+func windowTitle(dir, branch string) string {
+	return fmt.Sprintf("differ: %s [%s]", filepath.Base(dir), branch)
+}
+
+// panelOverlay reports whether the diff panel shows something other than the
+// selected file's diff, so async diff loads must not overwrite it.
+func (m Model) panelOverlay() bool {
+	return m.mode == modeClean || m.mode == modeSummary || m.mode == modeHookOutput || m.mode == modeExplain || m.mode == modeDivergence || m.mode == modeStagePreview
+}
+
+func (m Model) contentHeight() int {
+	if !m.cfg.Borders {
+		return m.height - 3 // title bar + status + help
+	}
+	return m.height - 4
+}
+
+// fitViewport sizes the diff viewport to its card, which gives up a row to
+// each commit message suggestion while they are listed.
+func (m Model) fitViewport() Model {
+	m.viewport.Height = m.contentHeight() - m.suggestionRows()
+	return m
+}
+
+func (m Model) diffWidth() int {
+	return m.width - m.fileListWidth() - m.frameWidth() - 1 - m.frameWidth()
+}
+
+// frameWidth is the columns a card's left and right borders take.
+func (m Model) frameWidth() int {
+	if !m.cfg.Borders {
+		return 0
+	}
+	return 2
+}
+
+// diffContentWidth is the viewport width: the diff panel minus the minimap.
+func (m Model) diffContentWidth() int { return m.diffWidth() - minimapWidth }
+
+// tooSmall reports whether the terminal is below the minimum size.
+func (m Model) tooSmall() bool { return m.width < minWidth || m.height < minHeight }
+
+func (m Model) fileListWidth() int {
+	if m.fileListW > 0 {
+		return m.fileListW
+	}
+	return defaultFileListWidth
+}
+
+// computeFileListWidth sizes the file panel as a fraction of the terminal.
+// A ratio outside (0, 1) keeps the fixed default width.
+func computeFileListWidth(ratio float64, termWidth int) int {
+	if ratio <= 0 || ratio >= 1 {
+		return defaultFileListWidth
+	}
+	w := int(float64(termWidth) * ratio)
+	if maxW := termWidth - 5 - minFileListWidth; w > maxW {
+		w = maxW
+	}
+	return max(w, minFileListWidth)
+}
@@ -1,15 +1,23 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/jansmrcka/differ/internal/config"
 	"github.com/jansmrcka/differ/internal/git"
 	"github.com/jansmrcka/differ/internal/theme"
+	"github.com/muesli/termenv"
 )
 
 func TestBuildFileItems(t *testing.T) {
@@ -42,6 +50,206 @@ func TestBuildFileItems(t *testing.T) {
 	}
 }
 
+func TestNewModel_StartOnTextFile(t *testing.T) {
+	t.Parallel()
+	changes := []git.FileChange{
+		{Path: "logo.png", Status: git.StatusModified, Binary: true},
+		{Path: "main.go", Status: git.StatusModified},
+	}
+	styles, th := testStyles()
+	cfg := config.Default()
+	if m := NewModel(nil, cfg, changes, nil, styles, th, false, ""); m.cursor != 1 {
+		t.Errorf("cursor = %d, want 1 (first text file)", m.cursor)
+	}
+	cfg.StartOnTextFile = false
+	if m := NewModel(nil, cfg, changes, nil, styles, th, false, ""); m.cursor != 0 {
+		t.Errorf("strict order: cursor = %d, want 0", m.cursor)
+	}
+	allBinary := []fileItem{{change: changes[0]}, {change: changes[0]}}
+	if got := firstTextFile(allBinary); got != 0 {
+		t.Errorf("all binary: firstTextFile = %d, want 0", got)
+	}
+}
+
+func TestNoChangesText(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	if got := m.noChangesText(); got != "  working tree clean" {
+		t.Errorf("default = %q", got)
+	}
+	m.stagedOnly = true
+	if got := m.noChangesText(); got != "  nothing staged" {
+		t.Errorf("staged = %q", got)
+	}
+	m.hiddenFiles = []fileItem{{change: git.FileChange{Path: "go.sum"}}}
+	if got := m.noChangesText(); !strings.Contains(got, "hide_patterns") {
+		t.Errorf("hidden = %q", got)
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"empty", nil, false},
+		{"text", []byte("package main\n"), false},
+		{"utf8", []byte("héllo wörld ✓\n"), false},
+		{"null_byte", []byte("PNG\x00\x01\x02"), true},
+		{"null_past_sniff_window", append([]byte(strings.Repeat("a", binarySniffLen)), 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := isBinary(tt.data); got != tt.want {
+				t.Errorf("isBinary()=%v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		in   string
+		want int
+	}{
+		{"empty", "", 0},
+		{"one_no_newline", "a", 1},
+		{"two_trailing_newline", "a\nb\n", 2},
+		{"binary", "a\n\x00b\nc\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := countLines(tt.in); got != tt.want {
+				t.Errorf("countLines(%q)=%d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		s    string
+		maxW int
+		want string
+	}{
+		{"pkg/a.go", 20, "pkg/a.go"},
+		{"internal/ui/render_layout.go", 16, "inter…_layout.go"},
+		{"abcdef", 2, "…f"},
+		{"日本語のファイル.go", 10, "日…ル.go"},
+	}
+	for _, tt := range tests {
+		got := truncateMiddle(tt.s, tt.maxW)
+		if got != tt.want {
+			t.Errorf("truncateMiddle(%q, %d)=%q, want %q", tt.s, tt.maxW, got, tt.want)
+		}
+		if w := lipgloss.Width(got); w > tt.maxW {
+			t.Errorf("truncateMiddle(%q, %d) width %d", tt.s, tt.maxW, w)
+		}
+	}
+}
+
+func TestTruncateSmart(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		path string
+		maxW int
+		want string
+	}{
+		{"fits", "src/api/handler.go", 18, "src/api/handler.go"},
+		{"one_short", "src/api/handler.go", 17, "src/…/handler.go"},
+		{"keeps_trailing_dirs", "src/internal/api/v2/handler.go", 22, "src/…/v2/handler.go"},
+		{"exact_boundary", "src/internal/api/v2/handler.go", 19, "src/…/v2/handler.go"},
+		{"just_below_boundary", "src/internal/api/v2/handler.go", 18, "src/…/handler.go"},
+		{"top_too_long", "a_very_long_top_level/x/handler.go", 14, "…/handler.go"},
+		{"name_too_long", "src/x/a_really_long_file_name.go", 10, "a_r…ame.go"},
+		{"two_parts_middle", "internal/render_layout.go", 16, "inter…_layout.go"},
+		{"wide_dirs", "文書/資料/設計/説明.md", 16, "文書/…/説明.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := truncateSmart(tt.path, tt.maxW)
+			if got != tt.want {
+				t.Errorf("truncateSmart(%q, %d)=%q, want %q", tt.path, tt.maxW, got, tt.want)
+			}
+			if w := lipgloss.Width(got); w > tt.maxW {
+				t.Errorf("truncateSmart(%q, %d) width %d", tt.path, tt.maxW, w)
+			}
+		})
+	}
+}
+
+func TestRenderFileList_GroupsByStage(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{
+		{change: git.FileChange{Path: "a.go", Staged: true}},
+		{change: git.FileChange{Path: "b.go"}},
+		{change: git.FileChange{Path: "c.go"}, untracked: true},
+	})
+	lines := strings.Split(m.renderFileList(10), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 2 headers + 3 files, got %d:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[0], "Staged (1)") || !strings.Contains(lines[2], "Changes (2)") {
+		t.Errorf("headers wrong: %q / %q", lines[0], lines[2])
+	}
+
+	m.cursor = 2
+	lines = strings.Split(m.renderFileList(2), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "c.go") {
+		t.Errorf("window should scroll to keep cursor visible: %q", lines)
+	}
+
+	m.stagedOnly = true
+	if list := m.renderFileList(10); strings.Contains(list, "Staged (") {
+		t.Error("staged-only view should not show group headers")
+	}
+}
+
+func TestRenderFileItem_FullPathToggle(t *testing.T) {
+	t.Parallel()
+	f := fileItem{change: git.FileChange{Path: "cmd/server/main.go", Status: git.StatusModified}}
+	m := newTestModel(t, []fileItem{f})
+	if item := m.renderFileItem(f, false); strings.Contains(item, "cmd/server") {
+		t.Errorf("basename mode should hide dirs: %q", item)
+	}
+	result, cmd := m.updateFileListMode(runeKey('.'))
+	rm := result.(Model)
+	if !rm.cfg.ShowFullPath || cmd == nil {
+		t.Fatal(". should toggle ShowFullPath and save config")
+	}
+	if item := rm.renderFileItem(f, false); !strings.Contains(item, "cmd/server/main.go") {
+		t.Errorf("full path mode should show the path: %q", item)
+	}
+}
+
+func TestFileItemName_SmartTruncation(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.cfg.ShowFullPath = true
+	f := fileItem{change: git.FileChange{Path: "src/internal/api/handler.go"}}
+	if got := m.fileItemName(f, 20); got != "src/…/api/handler.go" {
+		t.Errorf("full path = %q", got)
+	}
+	r := fileItem{change: git.FileChange{Path: "src/new/handler.go", OldPath: "src/old/handler.go"}}
+	if got := m.fileItemName(r, 35); got != "src/…/handler.go → src/…/handler.go" {
+		t.Errorf("rename = %q", got)
+	}
+
+	m.cfg.MaxNameWidth = 12
+	if item := m.renderFileItem(f, false); !strings.Contains(item, " …/handler.go ") {
+		t.Errorf("max_name_width should cap the name: %q", item)
+	}
+}
+
 func TestTruncatePath(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -115,9 +323,19 @@ func TestFilesEqual_OneEmpty(t *testing.T) {
 	}
 }
 
+func TestWindowTitle(t *testing.T) {
+	t.Parallel()
+	if got := windowTitle("/home/me/src/differ", "main"); got != "differ: differ [main]" {
+		t.Errorf("windowTitle = %q", got)
+	}
+	if cmd := newTestModel(t, nil).windowTitleCmd(); cmd != nil {
+		t.Error("windowTitleCmd without a repo should be nil")
+	}
+}
+
 func TestContentHeight(t *testing.T) {
 	t.Parallel()
-	m := Model{height: 30}
+	m := Model{height: 30, cfg: config.Default()}
 	// height - 4: cards add top+bottom border (+2), header removed (-1), net +1
 	if got := m.contentHeight(); got != 26 {
 		t.Errorf("contentHeight()=%d, want 26", got)
@@ -126,7 +344,7 @@ func TestContentHeight(t *testing.T) {
 
 func TestDiffWidth(t *testing.T) {
 	t.Parallel()
-	m := Model{width: 120}
+	m := Model{width: 120, cfg: config.Default()}
 	// width - fileListWidth(35) - 2(file card borders) - 1(gap) - 2(diff card borders)
 	want := 120 - 40
 	if got := m.diffWidth(); got != want {
@@ -134,6 +352,75 @@ func TestDiffWidth(t *testing.T) {
 	}
 }
 
+func TestBorderlessLayout(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.cfg.Borders = false
+	m.height = 30
+	if got, want := m.diffWidth(), 120-defaultFileListWidth-1; got != want {
+		t.Errorf("diffWidth()=%d, want %d", got, want)
+	}
+	if got := m.contentHeight(); got != 27 {
+		t.Errorf("contentHeight()=%d, want 27", got)
+	}
+	card := m.renderCard("Title", "line1\nline2", true, 20, 5)
+	lines := strings.Split(card, "\n")
+	// title bar + h=5 content lines, no frame
+	if len(lines) != 6 {
+		t.Fatalf("card line count=%d, want 6", len(lines))
+	}
+	if !strings.Contains(lines[0], "Title") {
+		t.Errorf("first line should be the title bar, got %q", lines[0])
+	}
+	for _, l := range lines {
+		if w := lipgloss.Width(l); w != 20 {
+			t.Errorf("line %q is %d wide, want 20", l, w)
+		}
+		if strings.ContainsAny(l, "╭╮╰╯│") {
+			t.Errorf("borderless card has frame chars: %q", l)
+		}
+	}
+}
+
+func TestComputeFileListWidth(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		ratio float64
+		width int
+		want  int
+	}{
+		{"zero_uses_default", 0, 200, defaultFileListWidth},
+		{"negative_uses_default", -0.5, 200, defaultFileListWidth},
+		{"one_uses_default", 1, 200, defaultFileListWidth},
+		{"quarter", 0.25, 200, 50},
+		{"clamped_min", 0.1, 100, minFileListWidth},
+		{"clamped_max", 0.9, 100, 100 - 5 - minFileListWidth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := computeFileListWidth(tt.ratio, tt.width); got != tt.want {
+				t.Errorf("computeFileListWidth(%v, %d)=%d, want %d", tt.ratio, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleResize_AppliesFileListRatio(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.cfg.FileListRatio = 0.25
+	result, _ := m.handleResize(tea.WindowSizeMsg{Width: 240, Height: 40})
+	rm := result.(Model)
+	if rm.fileListWidth() != 60 {
+		t.Errorf("fileListWidth()=%d, want 60", rm.fileListWidth())
+	}
+	if rm.diffWidth() != 240-60-5 {
+		t.Errorf("diffWidth()=%d, want %d", rm.diffWidth(), 240-60-5)
+	}
+}
+
 func TestRenderCard_Dimensions(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, nil)
@@ -196,20 +483,21 @@ func newTestModel(t *testing.T, files []fileItem) Model {
 	bf := textinput.New()
 	bf.Placeholder = "filter..."
 	bf.CharLimit = 100
-	bf.Width = fileListWidth - 8
+	bf.Width = defaultFileListWidth - 8
 	bi := textinput.New()
 	bi.Placeholder = "branch name..."
 	bi.CharLimit = 100
 	return Model{
-		files:        files,
-		styles:       NewStyles(th),
-		theme:        th,
-		cfg:          config.Default(),
-		width:        120,
-		height:       30,
-		commitInput:  textinput.New(),
-		branchFilter: bf,
-		branchInput:  bi,
+		files:         files,
+		styles:        NewStyles(th),
+		theme:         th,
+		cfg:           config.Default(),
+		width:         120,
+		height:        30,
+		commitInput:   textinput.New(),
+		branchFilter:  bf,
+		branchInput:   bi,
+		settingsInput: textinput.New(),
 	}
 }
 
@@ -246,6 +534,71 @@ func TestRenderStatusBar_StatusMsg(t *testing.T) {
 	}
 }
 
+func TestRenderStatusBar_ConfiguredItems(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	m.splitDiff = true
+	m.upstream = git.UpstreamInfo{Upstream: "origin/main", URL: "git@example.com:x/y.git", Ahead: 2}
+	m.cfg.StatusBarItems = []string{"upstream_url", "files", "bogus"}
+
+	bar := m.renderStatusBar()
+	if strings.Contains(bar, "staged") || strings.Contains(bar, "split") {
+		t.Errorf("unlisted items should be hidden: %q", bar)
+	}
+	url, files := strings.Index(bar, "git@example.com"), strings.Index(bar, "1 files")
+	if url < 0 || files < 0 || url > files {
+		t.Errorf("items should render in configured order: %q", bar)
+	}
+}
+
+func TestRenderStatusBar_Truncates(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.width = 20
+	m.statusMsg = "a very long status message that does not fit"
+	bar := m.renderStatusBar()
+	if w := lipgloss.Width(bar); w != 20 {
+		t.Errorf("bar width=%d, want 20", w)
+	}
+	if !strings.Contains(bar, "…") {
+		t.Errorf("overflowing bar should end with ellipsis: %q", bar)
+	}
+}
+
+func TestRenderStatusBar_NarrowKeepsMessage(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	m.splitDiff = true
+	m.upstream = git.UpstreamInfo{Upstream: "origin/main", Ahead: 2, Behind: 1}
+	m.statusMsg = "pushed!"
+
+	tests := []struct {
+		width int
+		shows []string
+		hides []string
+	}{
+		{30, []string{"pushed!", "1 files", "0 staged", "…"}, []string{"↑2", "fetched", "split"}},
+		{16, []string{"pushed!", "…"}, []string{"files", "staged"}},
+	}
+	for _, tt := range tests {
+		m.width = tt.width
+		bar := m.renderStatusBar()
+		if w := lipgloss.Width(bar); w != tt.width {
+			t.Errorf("width %d: bar width=%d", tt.width, w)
+		}
+		for _, s := range tt.shows {
+			if !strings.Contains(bar, s) {
+				t.Errorf("width %d: bar should contain %q: %q", tt.width, s, bar)
+			}
+		}
+		for _, s := range tt.hides {
+			if strings.Contains(bar, s) {
+				t.Errorf("width %d: bar should elide %q: %q", tt.width, s, bar)
+			}
+		}
+	}
+}
+
 func TestRenderHelpBar_FileListMode(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, nil)
@@ -270,6 +623,44 @@ func TestRenderHelpBar_DiffMode(t *testing.T) {
 	}
 }
 
+func TestRenderHelpBar_DiffModeOneLine(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeDiff
+	for _, width := range []int{minWidth, 80, 120, 160} {
+		m.width = width
+		bar := m.renderHelpBar()
+		if h, w := lipgloss.Height(bar), lipgloss.Width(bar); h != 1 || w != width {
+			t.Errorf("width %d: help bar is %d lines, %d wide; want 1 line", width, h, w)
+		}
+		for _, key := range []string{"j/k", "esc", "…", "q quit"} {
+			if !strings.Contains(bar, key) {
+				t.Errorf("width %d: bar should contain %q: %q", width, key, bar)
+			}
+		}
+	}
+	m.width = 300
+	if bar := m.renderHelpBar(); strings.Contains(bar, "…") || !strings.Contains(bar, "copy path") {
+		t.Errorf("a wide bar should show every pair: %q", bar)
+	}
+}
+
+func TestRenderHelpBar_FileListOneLine(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeFileList
+	m.width = 80
+	bar := m.renderHelpBar()
+	if h := lipgloss.Height(bar); h != 1 {
+		t.Fatalf("help bar at 80 columns is %d lines, want 1: %q", h, bar)
+	}
+	for _, key := range []string{"j/k", "tab", "c commit", "q quit"} {
+		if !strings.Contains(bar, key) {
+			t.Errorf("bar should contain %q: %q", key, bar)
+		}
+	}
+}
+
 func TestRenderHelpBar_BranchMode(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, nil)
@@ -328,6 +719,38 @@ func TestRenderBranchItem_Current(t *testing.T) {
 	}
 }
 
+func TestRenderBranchItem_Merged(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mergedBranches = map[string]bool{"old-feature": true, "main": true}
+	if item := m.renderBranchItem("old-feature", false, false); !strings.Contains(item, "✓merged") {
+		t.Errorf("merged branch should be tagged, got %q", item)
+	}
+	if item := m.renderBranchItem("main", false, true); strings.Contains(item, "✓merged") {
+		t.Error("current branch should not be tagged as merged")
+	}
+	if item := m.renderBranchItem("wip", false, false); strings.Contains(item, "✓merged") {
+		t.Error("unmerged branch should not be tagged")
+	}
+}
+
+func TestRenderBranchItem_Details(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.branchDetails = map[string]git.BranchInfo{"feat": {Name: "feat", Subject: "add login", Date: "2 days ago"}}
+	if item := m.renderBranchItem("feat", false, false); strings.Contains(item, "add login") {
+		t.Error("narrow panel should hide the detail column")
+	}
+	m.fileListW = 70
+	item := m.renderBranchItem("feat", false, false)
+	if !strings.Contains(item, "add login · 2 days ago") {
+		t.Errorf("wide panel should show last commit, got %q", item)
+	}
+	if lipgloss.Width(item) > m.fileListWidth() {
+		t.Errorf("item width=%d exceeds panel %d", lipgloss.Width(item), m.fileListWidth())
+	}
+}
+
 func TestRenderFileItem_ShowsStats(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, nil)
@@ -338,6 +761,46 @@ func TestRenderFileItem_ShowsStats(t *testing.T) {
 	}
 }
 
+func TestStatsBarCells(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		added, deleted   int
+		wantAdd, wantDel int
+	}{
+		{"none", 0, 0, 0, 0},
+		{"small_added", 2, 0, 2, 0},
+		{"small_mixed", 1, 1, 1, 1},
+		{"all_added", 100, 0, 5, 0},
+		{"mostly_added", 90, 10, 4, 1},
+		{"tiny_deleted_keeps_cell", 1000, 1, 4, 1},
+		{"tiny_added_keeps_cell", 1, 1000, 1, 4},
+		{"even", 50, 50, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a, d := statsBarCells(tt.added, tt.deleted)
+			if a != tt.wantAdd || d != tt.wantDel {
+				t.Errorf("statsBarCells(%d, %d)=(%d, %d), want (%d, %d)", tt.added, tt.deleted, a, d, tt.wantAdd, tt.wantDel)
+			}
+		})
+	}
+}
+
+func TestRenderFileItem_StatsBar(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	changed := fileItem{change: git.FileChange{Path: "a.go", Status: git.StatusModified, AddedLines: 3, DeletedLines: 2}}
+	if out := m.renderFileItem(changed, false); strings.Count(out, "■") != 5 {
+		t.Errorf("expected 5 bar cells, got %q", out)
+	}
+	binary := fileItem{change: git.FileChange{Path: "a.png", Status: git.StatusModified}}
+	if out := m.renderFileItem(binary, false); strings.Contains(out, "■") {
+		t.Errorf("0/0 change should render no bar, got %q", out)
+	}
+}
+
 func TestUpdateBranchMode_Navigation(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, nil)
@@ -406,7 +869,7 @@ func TestHandleResize_ClearsDiffCache(t *testing.T) {
 	}
 
 	// handleDiffLoaded with same content should apply (not skip) after resize
-	result2, _ := rm.handleDiffLoaded(diffLoadedMsg{content: "old diff", index: 0})
+	result2, _ := rm.handleDiffLoaded(diffLoadedMsg{content: "old diff", index: 0, width: rm.diffContentWidth()})
 	rm2 := result2.(Model)
 	if rm2.lastDiffContent != "old diff" {
 		t.Error("handleDiffLoaded should apply content after resize cleared cache")
@@ -416,6 +879,36 @@ func TestHandleResize_ClearsDiffCache(t *testing.T) {
 	}
 }
 
+func TestHandleResize_RecoversFromTooSmall(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	stale := diffLoadedMsg{content: "narrow diff", index: 0, width: m.diffContentWidth()}
+
+	result, cmd := m.handleResize(tea.WindowSizeMsg{Width: 40, Height: 8})
+	rm := result.(Model)
+	if cmd != nil {
+		t.Error("no diff should be rendered below the minimum size")
+	}
+	if !strings.Contains(rm.View(), "Terminal too small") {
+		t.Errorf("View = %q, want size warning", rm.View())
+	}
+
+	result, cmd = rm.handleResize(tea.WindowSizeMsg{Width: 140, Height: 30})
+	rm = result.(Model)
+	if cmd == nil {
+		t.Fatal("growing past the minimum should reload the diff")
+	}
+	if rm.viewport.Width != rm.diffContentWidth() || rm.viewport.Height != rm.contentHeight() {
+		t.Errorf("viewport %dx%d, want %dx%d", rm.viewport.Width, rm.viewport.Height, rm.diffContentWidth(), rm.contentHeight())
+	}
+
+	// A diff rendered for the earlier width must not overwrite the reload.
+	result, _ = rm.handleDiffLoaded(stale)
+	if rm = result.(Model); rm.lastDiffContent != "" {
+		t.Error("stale diff from another width should be ignored")
+	}
+}
+
 func TestHandleDiffLoaded_SkipsDuplicate(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, []fileItem{
@@ -432,6 +925,38 @@ func TestHandleDiffLoaded_SkipsDuplicate(t *testing.T) {
 	}
 }
 
+func TestHandleDiffLoaded_PollKeepsScrollUnlessContentChanged(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{
+		{change: git.FileChange{Path: "a.go", Status: git.StatusModified}},
+	})
+	m.viewport.Width, m.viewport.Height = 40, 5
+	w := m.diffContentWidth()
+	body := strings.Repeat("line\n", 50)
+	result, _ := m.handleDiffLoaded(diffLoadedMsg{content: body, width: w, hash: 1, resetScroll: true})
+	m = result.(Model)
+	m.viewport.SetYOffset(10)
+
+	// Same source, re-rendered differently (e.g. a tick): scroll stays.
+	result, _ = m.handleDiffLoaded(diffLoadedMsg{content: body + "x", width: w, hash: 1, poll: true})
+	if rm := result.(Model); rm.viewport.YOffset != 10 {
+		t.Errorf("unchanged source: YOffset=%d, want 10", rm.viewport.YOffset)
+	}
+	// Same stats but different content: start from the top.
+	result, _ = m.handleDiffLoaded(diffLoadedMsg{content: body + "y", width: w, hash: 2, poll: true})
+	if rm := result.(Model); rm.viewport.YOffset != 0 || rm.diffHash != 2 {
+		t.Errorf("changed source: YOffset=%d hash=%d, want 0 and 2", rm.viewport.YOffset, rm.diffHash)
+	}
+	// Other reloads (toggles, resizes) never jump on their own.
+	result, _ = m.handleDiffLoaded(diffLoadedMsg{content: body + "z", width: w, hash: 3})
+	if rm := result.(Model); rm.viewport.YOffset != 10 {
+		t.Errorf("non-poll reload: YOffset=%d, want 10", rm.viewport.YOffset)
+	}
+	if contentHash("a.go", "x") == contentHash("b.go", "x") || contentHash("a.go", "x") != contentHash("a.go", "x") {
+		t.Error("contentHash should depend on path and be stable")
+	}
+}
+
 func TestBranchListScroll(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, nil)
@@ -638,23 +1163,86 @@ func TestUpdateBranchMode_CtrlN_EntersCreateMode(t *testing.T) {
 	}
 }
 
-func TestUpdateBranchMode_CreateMode_RoutesToInput(t *testing.T) {
+func TestDirtyTreeGuard(t *testing.T) {
 	t.Parallel()
-	m := newTestModel(t, nil)
+	untracked := fileItem{change: git.FileChange{Path: "new.go"}, untracked: true}
+	modified := fileItem{change: git.FileChange{Path: "a.go"}}
+
+	m := newTestModel(t, []fileItem{untracked})
 	m.mode = modeBranchPicker
-	m.branchCreating = true
-	m.branchInput.Focus()
-	m.branches = []string{"main"}
+	m.branches = []string{"main", "dev"}
+	m.branchCursor = 1
+	if _, cmd := m.updateBranchMode(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Error("untracked files only: switch should run right away")
+	}
 
-	// Typing 'j' should go to text input, not move branch cursor
-	result, _ := m.updateBranchMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	m.files = []fileItem{untracked, modified}
+	result, cmd := m.updateBranchMode(tea.KeyMsg{Type: tea.KeyEnter})
 	rm := result.(Model)
-	if rm.branchInput.Value() != "j" {
-		t.Errorf("input=%q, want %q", rm.branchInput.Value(), "j")
+	if cmd != nil || rm.stashSwitch != "dev" || !strings.Contains(rm.statusMsg, "stash and switch to dev") {
+		t.Fatalf("dirty switch: cmd=%v stashSwitch=%q status=%q", cmd != nil, rm.stashSwitch, rm.statusMsg)
+	}
+	if _, cmd := rm.updateBranchMode(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Error("second enter should stash and switch")
+	}
+	result, _ = rm.updateBranchMode(tea.KeyMsg{Type: tea.KeyUp})
+	if rm = result.(Model); rm.stashSwitch != "" {
+		t.Error("moving away should drop the stash confirmation")
 	}
-}
 
-func TestUpdateBranchMode_CreateMode_Esc_Cancels(t *testing.T) {
+	m.mode = modeFileList
+	m.upstream.Upstream = "origin/main"
+	result, cmd = m.updateFileListMode(runeKey('F'))
+	rm = result.(Model)
+	if cmd != nil || !rm.stashPull || !strings.Contains(rm.statusMsg, "F again to stash and pull") {
+		t.Fatalf("dirty pull: cmd=%v stashPull=%v status=%q", cmd != nil, rm.stashPull, rm.statusMsg)
+	}
+	result, cmd = rm.updateFileListMode(runeKey('F'))
+	if rm = result.(Model); cmd == nil || rm.stashPull || rm.statusMsg != "stashing and pulling..." {
+		t.Errorf("second F: cmd=%v stashPull=%v status=%q", cmd != nil, rm.stashPull, rm.statusMsg)
+	}
+}
+
+func TestUpdateBranchMode_CtrlD_EntersDetachMode(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeBranchPicker
+	m.branches = []string{"main"}
+	m.width = 80
+	m.branchFilter.Focus()
+
+	result, _ := m.updateBranchMode(tea.KeyMsg{Type: tea.KeyCtrlT})
+	rm := result.(Model)
+	if !rm.branchCreating || !rm.branchDetaching {
+		t.Fatal("ctrl+t should open the input in detach mode")
+	}
+	if bar := rm.renderBranchCreateBar(); !strings.Contains(bar, "detach at") || !strings.Contains(bar, "detaches HEAD") {
+		t.Errorf("bar should warn about detaching: %q", bar)
+	}
+
+	result, _ = rm.updateBranchMode(tea.KeyMsg{Type: tea.KeyEscape})
+	if rm = result.(Model); rm.branchCreating || rm.branchDetaching {
+		t.Error("esc should leave detach mode")
+	}
+}
+
+func TestUpdateBranchMode_CreateMode_RoutesToInput(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeBranchPicker
+	m.branchCreating = true
+	m.branchInput.Focus()
+	m.branches = []string{"main"}
+
+	// Typing 'j' should go to text input, not move branch cursor
+	result, _ := m.updateBranchMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	rm := result.(Model)
+	if rm.branchInput.Value() != "j" {
+		t.Errorf("input=%q, want %q", rm.branchInput.Value(), "j")
+	}
+}
+
+func TestUpdateBranchMode_CreateMode_Esc_Cancels(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, nil)
 	m.mode = modeBranchPicker
@@ -874,6 +1462,122 @@ func TestEnterCommitMode_NoStaged_SetsStatus(t *testing.T) {
 	}
 }
 
+func TestLastFetchItem(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		upstream git.UpstreamInfo
+		want     string
+	}{
+		{"no upstream", git.UpstreamInfo{}, ""},
+		{"never fetched", git.UpstreamInfo{Upstream: "origin/main"}, "never fetched"},
+		{"recent", git.UpstreamInfo{Upstream: "origin/main", LastFetch: now.Add(-3 * time.Hour)}, "fetched 3h ago"},
+		{"stale", git.UpstreamInfo{Upstream: "origin/main", LastFetch: now.Add(-72 * time.Hour)}, "fetched 3d ago"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := newTestModel(t, nil)
+			m.upstream = tt.upstream
+			if got := m.lastFetchItem(now); got != tt.want {
+				t.Errorf("lastFetchItem = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToggleStageStatus(t *testing.T) {
+	t.Parallel()
+	files := []fileItem{
+		{change: git.FileChange{Path: "a.go", Status: git.StatusModified}},
+		{change: git.FileChange{Path: "b.go", Status: git.StatusModified}},
+		{change: git.FileChange{Path: "c.go", Status: git.StatusModified, Staged: true}},
+		{change: git.FileChange{Path: "n.go", Status: git.StatusUntracked}, untracked: true},
+	}
+	tests := []struct {
+		name   string
+		cursor int
+		want   string
+	}{
+		{"unstaged modified", 0, "staged 2 modified files"},
+		{"staged modified", 2, "unstaged 1 modified file"},
+		{"untracked", 3, "staged 1 untracked file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := newTestModel(t, files)
+			m.cursor = tt.cursor
+			result, cmd := m.updateFileListMode(runeKey('A'))
+			if got := result.(Model).statusMsg; got != tt.want || cmd == nil {
+				t.Errorf("statusMsg = %q (cmd %v), want %q", got, cmd != nil, tt.want)
+			}
+		})
+	}
+}
+
+func TestToggleUncapped(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "big.go"}}})
+	m.mode = modeDiff
+
+	result, _ := m.updateDiffMode(runeKey('X'))
+	rm := result.(Model)
+	if rm.uncappedPath != "big.go" {
+		t.Fatalf("uncappedPath = %q, want big.go", rm.uncappedPath)
+	}
+	result, _ = rm.updateDiffMode(runeKey('X'))
+	if got := result.(Model).uncappedPath; got != "" {
+		t.Errorf("second X should restore the cap, uncappedPath = %q", got)
+	}
+}
+
+func TestEnterCommitMode_AutoStage(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		files     []fileItem
+		wantCmd   bool
+		wantInMsg string
+	}{
+		{"tracked change", []fileItem{{change: git.FileChange{Path: "a.go"}}}, true, "auto-staging"},
+		{"untracked only", []fileItem{{change: git.FileChange{Path: "n.go"}, untracked: true}}, false, "no staged files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := newTestModel(t, tt.files)
+			m.cfg.AutoStageOnCommit = true
+
+			result, cmd := m.enterCommitMode()
+			rm := result.(Model)
+			if (cmd != nil) != tt.wantCmd {
+				t.Errorf("cmd = %v, want cmd %v", cmd != nil, tt.wantCmd)
+			}
+			if !strings.Contains(rm.statusMsg, tt.wantInMsg) {
+				t.Errorf("statusMsg = %q, want %q", rm.statusMsg, tt.wantInMsg)
+			}
+		})
+	}
+}
+
+func TestHandleAutoStaged_EntersCommitMode(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	m.cfg.AutoStageOnCommit = true
+
+	staged := []fileItem{{change: git.FileChange{Path: "a.go", Staged: true}}}
+	result, _ := m.Update(autoStagedMsg{files: staged, count: 1})
+	rm := result.(Model)
+	if rm.mode != modeCommit {
+		t.Errorf("mode = %v, want commit", rm.mode)
+	}
+	if rm.statusMsg != "auto-staged 1 tracked files" {
+		t.Errorf("statusMsg = %q", rm.statusMsg)
+	}
+}
+
 func TestEnterCommitMode_WithStaged_EntersCommitMode(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go", Staged: true}}})
@@ -908,6 +1612,204 @@ func TestUpdateCommitMode_EnterEmpty_ShowsError(t *testing.T) {
 	}
 }
 
+func TestUpdateCommitMode_EnterShowsSpinner(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeCommit
+	m.commitInput.SetValue("feat: x")
+
+	result, cmd := m.updateCommitMode(tea.KeyMsg{Type: tea.KeyEnter})
+	rm := result.(Model)
+	if !rm.committing || cmd == nil {
+		t.Fatalf("committing=%v cmd=%v", rm.committing, cmd != nil)
+	}
+	if !strings.Contains(rm.renderCommitBar(), "running commit hooks") {
+		t.Error("commit bar should show hook indicator")
+	}
+	if _, cmd := rm.updateCommitMode(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Error("enter while committing should be ignored")
+	}
+}
+
+func TestHandleCommitDone_ShowsHash(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeCommit
+	result, _ := m.handleCommitDone(commitDoneMsg{hash: "abc1234"})
+	rm := result.(Model)
+	if rm.statusMsg != "committed abc1234" || rm.mode != modeFileList {
+		t.Errorf("statusMsg=%q mode=%v", rm.statusMsg, rm.mode)
+	}
+	// The hash couldn't be read back, but the commit was made.
+	result, _ = m.handleCommitDone(commitDoneMsg{})
+	if rm = result.(Model); rm.statusMsg != "committed" || rm.mode != modeFileList {
+		t.Errorf("no hash: statusMsg=%q mode=%v", rm.statusMsg, rm.mode)
+	}
+}
+
+func TestHandleCommitDone_SubsetShowsRemaining(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{
+		{change: git.FileChange{Path: "a.go", Staged: true}},
+		{change: git.FileChange{Path: "a.go"}},
+		{change: git.FileChange{Path: "b.go", Staged: true}},
+		{change: git.FileChange{Path: "c.go"}, untracked: true},
+	})
+	m.mode = modeCommit
+	m.cursor = 3
+	result, _ := m.handleCommitDone(commitDoneMsg{hash: "abc1234"})
+	rm := result.(Model)
+	if rm.statusMsg != "committed abc1234 · 2 files remaining" {
+		t.Errorf("statusMsg=%q", rm.statusMsg)
+	}
+	if rm.mode != modeFileList || rm.cursor != 0 {
+		t.Errorf("mode=%v cursor=%d, want file list at the top", rm.mode, rm.cursor)
+	}
+}
+
+func TestHandleCommitDone_ShowsHookOutput(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeCommit
+	m.committing = true
+	m.commitInput.SetValue("feat: x")
+	m.viewport.Width, m.viewport.Height = 60, 10
+
+	result, _ := m.handleCommitDone(commitDoneMsg{err: errors.New("gofmt: main.go needs formatting\nrun make fmt")})
+	rm := result.(Model)
+	if rm.committing || rm.mode != modeHookOutput {
+		t.Fatalf("committing=%v mode=%v", rm.committing, rm.mode)
+	}
+	if view := rm.viewport.View(); !strings.Contains(view, "run make fmt") {
+		t.Errorf("hook output not shown: %q", view)
+	}
+
+	result, _ = rm.updateHookOutputMode(tea.KeyMsg{Type: tea.KeyEsc})
+	rm = result.(Model)
+	if rm.mode != modeCommit || rm.commitInput.Value() != "feat: x" {
+		t.Errorf("esc should return to commit mode keeping message, mode=%v msg=%q", rm.mode, rm.commitInput.Value())
+	}
+}
+
+func TestOpenPR_RequiresUpstream(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	result, cmd := m.updateFileListMode(runeKey('o'))
+	rm := result.(Model)
+	if cmd != nil || !strings.Contains(rm.statusMsg, "push first") {
+		t.Errorf("no upstream: status=%q cmd=%v", rm.statusMsg, cmd != nil)
+	}
+
+	m.upstream = git.UpstreamInfo{Upstream: "origin/feat"}
+	result, cmd = m.updateFileListMode(runeKey('o'))
+	if cmd == nil {
+		t.Error("expected open cmd with upstream")
+	}
+	result, _ = result.(Model).Update(urlOpenedMsg{url: "https://github.com/o/r/compare/feat?expand=1"})
+	if rm := result.(Model); !strings.HasPrefix(rm.statusMsg, "opened https://github.com") {
+		t.Errorf("statusMsg=%q", rm.statusMsg)
+	}
+}
+
+func TestFencedDiff(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"plain", "@@ -1 +1 @@\n-a\n+b\n", "```diff\n@@ -1 +1 @@\n-a\n+b\n```\n"},
+		{"contains_fence", "+```go\n", "````diff\n+```go\n````\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := fencedDiff(tt.raw); got != tt.want {
+				t.Errorf("fencedDiff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	want := "--- /dev/null\n+++ b/new.go\n@@ -0,0 +1,2 @@\n+package x\n+\n"
+	if got := newFilePatch("new.go", "package x\n\n"); got != want {
+		t.Errorf("newFilePatch = %q, want %q", got, want)
+	}
+}
+
+func TestSelectedFilePath(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	if got := m.selectedFilePath(false); got != "" {
+		t.Errorf("empty list path=%q", got)
+	}
+	m.files = []fileItem{{change: git.FileChange{Path: "pkg/a.go"}}}
+	if got := m.selectedFilePath(false); got != "pkg/a.go" {
+		t.Errorf("relative=%q", got)
+	}
+
+	result, _ := m.Update(clipboardDoneMsg{label: "copied path"})
+	if rm := result.(Model); rm.statusMsg != "copied path" {
+		t.Errorf("statusMsg=%q", rm.statusMsg)
+	}
+}
+
+func TestClipboardDone_OSC52GoesOutWithTheView(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	// Too small for panels: the view is just the size warning.
+	result, _ := m.Update(tea.WindowSizeMsg{Width: 20, Height: 5})
+	m = result.(Model)
+	result, cmd := m.Update(clipboardDoneMsg{label: "copied path", osc52: "pkg/a.go"})
+	m = result.(Model)
+	// "pkg/a.go" in base64.
+	if !strings.HasPrefix(m.View(), "\x1b]52;c;cGtnL2EuZ28=") || cmd == nil {
+		t.Fatalf("view should start with the OSC 52 copy, cmd=%v", cmd != nil)
+	}
+	result, _ = m.Update(osc52ClearedMsg{text: "pkg/a.go"})
+	if view := result.(Model).View(); strings.Contains(view, "\x1b]52;") {
+		t.Error("the copy should be dropped from later frames")
+	}
+}
+
+func TestSingleLine(t *testing.T) {
+	t.Parallel()
+	tests := []struct{ in, want string }{
+		{"fix: typo", "fix: typo"},
+		{"fix: typo\n", "fix: typo"},
+		{"subject\n\n  body line\r\nmore\n", "subject body line more"},
+		{"\n\n", ""},
+	}
+	for _, tt := range tests {
+		if got := singleLine(tt.in); got != tt.want {
+			t.Errorf("singleLine(%q)=%q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCommitMode_Paste(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeCommit
+	m.commitInput.SetValue("feat: ")
+	m.commitInput.CursorEnd()
+
+	if _, cmd := m.updateCommitMode(tea.KeyMsg{Type: tea.KeyCtrlV}); cmd == nil {
+		t.Fatal("ctrl+v should read the clipboard")
+	}
+	result, _ := m.Update(clipboardPastedMsg{text: "add x\n\nlong body\n"})
+	rm := result.(Model)
+	if got := rm.commitInput.Value(); got != "feat: add x long body" {
+		t.Errorf("value=%q", got)
+	}
+	if rm.statusMsg != "pasted" {
+		t.Errorf("statusMsg=%q", rm.statusMsg)
+	}
+
+	result, _ = m.Update(clipboardPastedMsg{err: errors.New("no clipboard tool found")})
+	if rm := result.(Model); rm.commitInput.Value() != "feat: " || !strings.HasPrefix(rm.statusMsg, "paste failed") {
+		t.Errorf("failed paste: value=%q statusMsg=%q", rm.commitInput.Value(), rm.statusMsg)
+	}
+}
+
 func TestToggleStage_DisabledInStagedOnlyOrRef(t *testing.T) {
 	t.Parallel()
 	files := []fileItem{{change: git.FileChange{Path: "a.go", Staged: false}}}
@@ -957,30 +1859,1168 @@ func TestHandleFilesRefreshed_EmptyClearsViewport(t *testing.T) {
 	}
 }
 
-func TestHandleTick_SkipsPollingDuringCommit(t *testing.T) {
+func TestHandleFilesRefreshed_ForceBypassesEqual(t *testing.T) {
 	t.Parallel()
-	m := newTestModel(t, nil)
-	m.mode = modeCommit
+	files := []fileItem{{change: git.FileChange{Path: "a.go"}}}
+	m := newTestModel(t, files)
+	m.lastDiffContent = "cached"
+	m.prevCurs = 0
 
-	_, cmd := m.handleTick()
-	if cmd == nil {
-		t.Fatal("expected tick cmd")
+	result, _ := m.handleFilesRefreshed(filesRefreshedMsg{files: files})
+	if rm := result.(Model); rm.lastDiffContent != "cached" {
+		t.Error("normal refresh of equal files should keep cached diff")
 	}
-	msg := cmd()
-	if _, ok := msg.(tickMsg); !ok {
-		t.Errorf("msg type=%T, want tickMsg", msg)
+	result, _ = m.handleFilesRefreshed(filesRefreshedMsg{files: files, force: true})
+	if rm := result.(Model); rm.lastDiffContent != "" || rm.prevCurs != -1 {
+		t.Errorf("force refresh should clear cache, lastDiffContent=%q prevCurs=%d", rm.lastDiffContent, rm.prevCurs)
 	}
 }
 
-func TestPushConfirm_ResetOnNonPKey(t *testing.T) {
+func TestHandleFilesRefreshed_ShowsStageError(t *testing.T) {
+	t.Parallel()
+	files := []fileItem{{change: git.FileChange{Path: "a.go"}}}
+	m := newTestModel(t, files)
+	result, _ := m.handleFilesRefreshed(filesRefreshedMsg{files: files, err: errors.New("fatal: Unable to create index.lock\nhint")})
+	rm := result.(Model)
+	if rm.statusMsg != "stage failed: fatal: Unable to create index.lock" {
+		t.Errorf("statusMsg=%q", rm.statusMsg)
+	}
+}
+
+func TestHandleFilesRefreshed_FollowsStagedFile(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{
+		{change: git.FileChange{Path: "a.go"}},
+		{change: git.FileChange{Path: "b.go"}},
+		{change: git.FileChange{Path: "c.go"}},
+	})
+	m.cursor = 1
+	// Staging b.go moves it into the Staged group at the top.
+	staged := []fileItem{
+		{change: git.FileChange{Path: "b.go", Staged: true}},
+		{change: git.FileChange{Path: "a.go"}},
+		{change: git.FileChange{Path: "c.go"}},
+	}
+	result, _ := m.handleFilesRefreshed(filesRefreshedMsg{files: staged, follow: "b.go"})
+	if rm := result.(Model); rm.cursor != 0 {
+		t.Errorf("cursor=%d, want 0 (b.go)", rm.cursor)
+	}
+
+	result, _ = m.handleFilesRefreshed(filesRefreshedMsg{files: staged[1:], follow: "b.go"})
+	if rm := result.(Model); rm.cursor != 1 {
+		t.Errorf("missing follow path: cursor=%d, want 1 (kept)", rm.cursor)
+	}
+}
+
+func TestHandleFetchDone(t *testing.T) {
 	t.Parallel()
 	m := newTestModel(t, nil)
-	m.mode = modeFileList
-	m.pushConfirm = true
+	m.fetching = true
+	if bar := m.renderStatusBar(); !strings.Contains(bar, "fetching origin") {
+		t.Errorf("status bar should show the running fetch: %q", bar)
+	}
+	tests := []struct {
+		err     error
+		status  string
+		refresh bool
+	}{
+		{nil, "fetched origin", true},
+		{git.ErrNoRemote, "no origin remote, skipped fetch", false},
+		{errors.New("fatal: unable to access 'https://x/'\nmore"), "fetch skipped: fatal: unable to access 'https://x/'", false},
+	}
+	for _, tt := range tests {
+		result, cmd := m.handleFetchDone(fetchDoneMsg{err: tt.err})
+		rm := result.(Model)
+		if rm.fetching || rm.statusMsg != tt.status || (cmd != nil) != tt.refresh {
+			t.Errorf("err=%v: fetching=%v status=%q refresh=%v", tt.err, rm.fetching, rm.statusMsg, cmd != nil)
+		}
+	}
+}
 
-	result, _ := m.updateFileListMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+func TestExternalToolKeys(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "new.go"}, untracked: true}})
+	result, cmd := m.updateFileListMode(runeKey('T'))
+	if rm := result.(Model); cmd != nil || rm.statusMsg != "untracked file: nothing to compare" {
+		t.Errorf("T on untracked: cmd=%v status=%q", cmd != nil, rm.statusMsg)
+	}
+	m.reviewOnly = true
+	if _, cmd := m.updateFileListMode(runeKey('M')); cmd != nil {
+		t.Error("M should not launch mergetool in review-only mode")
+	}
+
+	m.lastDiffContent = "cached"
+	result, cmd = m.handleExternalToolDone(externalToolDoneMsg{tool: "mergetool"})
 	rm := result.(Model)
-	if rm.pushConfirm {
-		t.Error("pushConfirm should reset on non-P key")
+	if rm.statusMsg != "mergetool done" || rm.lastDiffContent != "" || cmd == nil {
+		t.Errorf("done: status=%q cache=%q reload=%v", rm.statusMsg, rm.lastDiffContent, cmd != nil)
+	}
+	result, _ = m.handleExternalToolDone(externalToolDoneMsg{tool: "difftool", err: errors.New("exit status 1")})
+	if rm := result.(Model); rm.statusMsg != "difftool failed: exit status 1" {
+		t.Errorf("failed: status=%q", rm.statusMsg)
+	}
+}
+
+func TestFileActions(t *testing.T) {
+	t.Parallel()
+	keys := func(m Model) string {
+		var out []string
+		for _, a := range m.fileActions() {
+			out = append(out, a.key+"="+a.label)
+		}
+		return strings.Join(out, " ")
+	}
+	tests := []struct {
+		name  string
+		file  fileItem
+		setup func(m *Model)
+		want  string
+	}{
+		{"unstaged", fileItem{change: git.FileChange{Path: "a.go", Status: git.StatusModified}}, nil,
+			"enter=view diff tab=stage e=open in editor y=copy path Y=copy absolute path C=copy diff as markdown i=explain with AI T=open in diff tool M=open in merge tool"},
+		{"staged", fileItem{change: git.FileChange{Path: "a.go", Status: git.StatusModified, Staged: true}}, nil,
+			"enter=view diff tab=unstage e=open in editor y=copy path Y=copy absolute path C=copy diff as markdown i=explain with AI T=open in diff tool M=open in merge tool"},
+		{"untracked", fileItem{change: git.FileChange{Path: "n.go", Status: git.StatusUntracked}, untracked: true}, nil,
+			"enter=view diff tab=stage e=open in editor y=copy path Y=copy absolute path C=copy diff as markdown i=explain with AI"},
+		{"deleted_review", fileItem{change: git.FileChange{Path: "d.go", Status: git.StatusDeleted}}, func(m *Model) { m.reviewOnly = true },
+			"enter=view diff y=copy path Y=copy absolute path C=copy diff as markdown i=explain with AI T=open in diff tool"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := newTestModel(t, []fileItem{tt.file})
+			if tt.setup != nil {
+				tt.setup(&m)
+			}
+			if got := keys(m); got != tt.want {
+				t.Errorf("actions:\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionsMode_Mnemonics(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	result, _ := m.updateFileListMode(runeKey(' '))
+	m = result.(Model)
+	if m.mode != modeActions {
+		t.Fatalf("space: mode=%v, want actions", m.mode)
+	}
+	result, _ = m.updateActionsMode(runeKey('j'))
+	if rm := result.(Model); rm.actionsCursor != 1 || rm.mode != modeActions {
+		t.Errorf("j: cursor=%d mode=%v", rm.actionsCursor, rm.mode)
+	}
+	result, cmd := m.updateActionsMode(runeKey('e'))
+	if rm := result.(Model); rm.SelectedFile != "a.go" || rm.mode != modeFileList || cmd == nil {
+		t.Errorf("e: selected=%q mode=%v quit=%v", rm.SelectedFile, rm.mode, cmd != nil)
+	}
+	result, _ = m.updateActionsMode(tea.KeyMsg{Type: tea.KeyEsc})
+	if rm := result.(Model); rm.mode != modeFileList {
+		t.Errorf("esc: mode=%v, want file list", rm.mode)
+	}
+}
+
+func TestRefreshKeys(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.lastDiffContent = "cached"
+	result, cmd := m.updateFileListMode(runeKey('r'))
+	rm := result.(Model)
+	if cmd == nil || rm.statusMsg != "refreshed" || rm.lastDiffContent != "cached" {
+		t.Errorf("r: status=%q cache=%q", rm.statusMsg, rm.lastDiffContent)
+	}
+	result, _ = m.updateFileListMode(runeKey('R'))
+	rm = result.(Model)
+	if rm.statusMsg != "reloaded" || rm.lastDiffContent != "" {
+		t.Errorf("R: status=%q cache=%q", rm.statusMsg, rm.lastDiffContent)
+	}
+}
+
+func TestHandleTick_SkipsPollingDuringCommit(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeCommit
+
+	_, cmd := m.handleTick()
+	if cmd == nil {
+		t.Fatal("expected tick cmd")
+	}
+	msg := cmd()
+	if _, ok := msg.(tickMsg); !ok {
+		t.Errorf("msg type=%T, want tickMsg", msg)
+	}
+}
+
+func TestPushConfirm_ResetOnNonPKey(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeFileList
+	m.pushConfirm = true
+
+	result, _ := m.updateFileListMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	rm := result.(Model)
+	if rm.pushConfirm {
+		t.Error("pushConfirm should reset on non-P key")
+	}
+}
+
+func TestDiffMode_RelativeLineNums_MovesCursor(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeDiff
+	m.cfg.RelativeLineNums = true
+	m.lastDiffContent = "one\ntwo\nthree\n"
+
+	result, _ := m.updateDiffMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	rm := result.(Model)
+	if rm.diffCursor != 1 {
+		t.Errorf("diffCursor=%d after j, want 1", rm.diffCursor)
+	}
+	for i := 0; i < 5; i++ {
+		result, _ = rm.updateDiffMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+		rm = result.(Model)
+	}
+	if rm.diffCursor != 2 {
+		t.Errorf("diffCursor=%d, want clamped to last line 2", rm.diffCursor)
+	}
+	result, _ = rm.updateDiffMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}})
+	if rm = result.(Model); rm.diffCursor != 1 {
+		t.Errorf("diffCursor=%d after k, want 1", rm.diffCursor)
+	}
+}
+
+func TestDiffMode_RelativeLineNums_RerendersWithoutGit(t *testing.T) {
+	t.Parallel()
+	parsed := ParseDiff("@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n")
+	// No repo: running git here would panic.
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "f.txt"}}})
+	m.mode = modeDiff
+	m.cfg.RelativeLineNums = true
+	m.diffRender = &diffRender{parsed: parsed}
+	m.lastDiffContent = RenderDiffRelative(parsed, "f.txt", m.styles, m.theme, m.diffContentWidth(), 0, RenderOptions{})
+
+	result, cmd := m.updateDiffMode(runeKey('j'))
+	msg, ok := cmd().(diffLoadedMsg)
+	if !ok {
+		t.Fatalf("j should re-render the cached diff, got %T", cmd())
+	}
+	if rm := result.(Model); msg.content == rm.lastDiffContent || msg.render != m.diffRender {
+		t.Error("re-render should move the cursor and keep the cached parse")
+	}
+}
+
+func TestDiffMode_AbsoluteLineNums_LeavesCursor(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeDiff
+	m.lastDiffContent = "one\ntwo\n"
+
+	result, _ := m.updateDiffMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	if rm := result.(Model); rm.diffCursor != 0 {
+		t.Errorf("diffCursor=%d, want 0 when relative numbers are off", rm.diffCursor)
+	}
+}
+
+func TestParseSuggestions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		out  string
+		n    int
+		want []string
+	}{
+		{"numbered", "1. feat: add x\n2. fix: y\n3) chore: z", 3, []string{"feat: add x", "fix: y", "chore: z"}},
+		{"fewer_than_requested", "1. feat: only one\n", 3, []string{"feat: only one"}},
+		{"caps_at_n", "1. a\n2. b\n3. c", 2, []string{"a", "b"}},
+		{"bullets_and_quotes", "- \"fix: quoted\"\n\n* `refactor: ticks`", 5, []string{"fix: quoted", "refactor: ticks"}},
+		{"empty", "\n  \n", 3, nil},
+		{"leading_digits_kept", "1. 2fa: add totp\n2) 3d: fix shading\n- 404 page", 3, []string{"2fa: add totp", "3d: fix shading", "404 page"}},
+		{"unnumbered", "2fa: add totp\n-x flag", 2, []string{"2fa: add totp", "-x flag"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := parseSuggestions(tt.out, tt.n)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("parseSuggestions()=%q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstLines(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		content string
+		limit   int
+		want    string
+		cut     bool
+	}{
+		{"uncapped", "a\nb\nc", 0, "a\nb\nc", false},
+		{"under_limit", "a\nb", 3, "a\nb", false},
+		{"at_limit", "a\nb\nc", 3, "a\nb\nc", false},
+		{"over_limit", "a\nb\nc\nd", 2, "a\nb", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, cut := firstLines(tt.content, tt.limit)
+			if got != tt.want || cut != tt.cut {
+				t.Errorf("firstLines()=%q,%v, want %q,%v", got, cut, tt.want, tt.cut)
+			}
+		})
+	}
+}
+
+func TestBuildCommitMsgPrompt_RequestsList(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	if p := buildCommitMsgPrompt(cfg, "diff"); strings.Contains(p, "numbered list") {
+		t.Error("single suggestion prompt should not ask for a list")
+	}
+	cfg.CommitMsgCount = 3
+	if p := buildCommitMsgPrompt(cfg, "diff"); !strings.Contains(p, "3 alternative") {
+		t.Errorf("prompt should ask for 3 alternatives, got %q", p)
+	}
+}
+
+func TestHandleCommitMsgGenerated_Suggestions(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeCommit
+	m.generatingMsg = true
+
+	result, _ := m.handleCommitMsgGenerated(commitMsgGeneratedMsg{message: "a", suggestions: []string{"a", "b"}})
+	rm := result.(Model)
+	if len(rm.suggestions) != 2 || rm.commitInput.Value() != "a" {
+		t.Fatalf("suggestions=%v input=%q", rm.suggestions, rm.commitInput.Value())
+	}
+	if rm.viewport.Height != rm.contentHeight()-2 {
+		t.Errorf("viewport height = %d, want %d to leave room for the suggestions", rm.viewport.Height, rm.contentHeight()-2)
+	}
+
+	result, _ = rm.updateCommitMode(tea.KeyMsg{Type: tea.KeyDown})
+	rm = result.(Model)
+	if rm.suggestionIdx != 1 || rm.commitInput.Value() != "b" {
+		t.Errorf("down should pick second suggestion, idx=%d input=%q", rm.suggestionIdx, rm.commitInput.Value())
+	}
+	if !strings.Contains(rm.renderSuggestions(), "2. b") {
+		t.Error("suggestion list should render numbered entries")
+	}
+
+	result, _ = rm.updateCommitMode(tea.KeyMsg{Type: tea.KeyEsc})
+	if rm = result.(Model); rm.viewport.Height != rm.contentHeight() {
+		t.Errorf("esc: viewport height = %d, want %d", rm.viewport.Height, rm.contentHeight())
+	}
+}
+
+func TestRunAICmd_Timeout(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	// The script forks sleep, so only a process-group kill ends it in time.
+	script := filepath.Join(t.TempDir(), "slow.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsleep 10\necho late\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := runAICmd(ctx, script, "prompt")
+	if !errors.Is(err, errAITimeout) {
+		t.Fatalf("err = %v, want errAITimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("timeout took %v", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := runAICmd(ctx, script, "prompt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: err = %v, want context.Canceled", err)
+	}
+}
+
+func TestUpdateCommitMode_EscCancelsGeneration(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeCommit
+	m.generatingMsg = true
+	cancelled := false
+	m.cancelGenerate = func() { cancelled = true }
+
+	result, _ := m.updateCommitMode(tea.KeyMsg{Type: tea.KeyEscape})
+	rm := result.(Model)
+	if !cancelled || rm.generatingMsg || rm.statusMsg != "generation cancelled" {
+		t.Fatalf("cancelled=%v generating=%v status=%q", cancelled, rm.generatingMsg, rm.statusMsg)
+	}
+	// The cancelled command's result must not touch the UI.
+	result, _ = rm.handleCommitMsgGenerated(commitMsgGeneratedMsg{err: context.Canceled})
+	if rm = result.(Model); rm.statusMsg != "generation cancelled" {
+		t.Errorf("status after late result = %q", rm.statusMsg)
+	}
+
+	m.generatingMsg = true
+	result, _ = m.handleCommitMsgGenerated(commitMsgGeneratedMsg{err: errAITimeout})
+	if rm = result.(Model); rm.generatingMsg || rm.statusMsg != "AI generation timed out" {
+		t.Errorf("timeout: generating=%v status=%q", rm.generatingMsg, rm.statusMsg)
+	}
+}
+
+func TestUpdateCommitMode_CtrlR_Regenerates(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeCommit
+
+	result, cmd := m.updateCommitMode(tea.KeyMsg{Type: tea.KeyCtrlR})
+	rm := result.(Model)
+	if !rm.generatingMsg || cmd == nil {
+		t.Error("ctrl+r should start regenerating the commit message")
+	}
+}
+
+func TestHandleCleanPreview_Empty(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	result, _ := m.handleCleanPreview(cleanPreviewMsg{})
+	rm := result.(Model)
+	if rm.mode != modeFileList {
+		t.Errorf("mode=%v, want file list", rm.mode)
+	}
+	if rm.statusMsg != "nothing to clean" {
+		t.Errorf("statusMsg=%q", rm.statusMsg)
+	}
+}
+
+func TestHandleCleanPreview_ListsFiles(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.viewport.Width, m.viewport.Height = 60, 10
+	result, _ := m.handleCleanPreview(cleanPreviewMsg{files: []string{"junk.txt", "out/"}})
+	rm := result.(Model)
+	if rm.mode != modeClean {
+		t.Fatalf("mode=%v, want clean", rm.mode)
+	}
+	if !strings.Contains(rm.viewport.View(), "junk.txt") || !strings.Contains(rm.viewport.View(), "out/") {
+		t.Errorf("preview should list files, got %q", rm.viewport.View())
+	}
+	if !strings.Contains(rm.diffCardTitle(), "2 to delete") {
+		t.Errorf("title=%q", rm.diffCardTitle())
+	}
+}
+
+func TestUpdateCleanMode_Keys(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeClean
+	m.cleanFiles = []string{"junk.txt"}
+
+	result, cmd := m.updateCleanMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	rm := result.(Model)
+	if !rm.cleanIgnored || cmd == nil {
+		t.Error("i should toggle ignored and re-run the preview")
+	}
+	if _, cmd = rm.updateCleanMode(runeKey('y')); cmd != nil {
+		t.Error("y before the new preview arrives should do nothing")
+	}
+	result, _ = rm.handleCleanPreview(cleanPreviewMsg{files: []string{"stale.txt"}})
+	if rm = result.(Model); rm.cleanFiles != nil {
+		t.Errorf("preview for the old ignored setting applied: %v", rm.cleanFiles)
+	}
+
+	result, cmd = m.updateCleanMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	if rm = result.(Model); cmd == nil || rm.statusMsg != "cleaning..." {
+		t.Errorf("y should start cleaning, statusMsg=%q", rm.statusMsg)
+	}
+	if _, cmd = rm.updateCleanMode(runeKey('y')); cmd != nil {
+		t.Error("a second y while cleaning should be ignored")
+	}
+
+	result, _ = m.updateCleanMode(tea.KeyMsg{Type: tea.KeyEscape})
+	if rm = result.(Model); rm.mode != modeFileList || rm.cleanFiles != nil {
+		t.Error("esc should cancel clean mode")
+	}
+}
+
+func TestUpdateCleanMode_NothingToDelete(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeClean
+
+	_, cmd := m.updateCleanMode(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	if cmd != nil {
+		t.Error("y with no files should do nothing")
+	}
+}
+
+func TestRender_NoColor_NoEscapes(t *testing.T) {
+	prev := lipgloss.ColorProfile()
+	t.Cleanup(func() { lipgloss.SetColorProfile(prev) })
+	files := []fileItem{
+		{change: git.FileChange{Path: "a.go", Status: git.StatusModified, Staged: true, AddedLines: 2, DeletedLines: 1}},
+		{change: git.FileChange{Path: "b.go", Status: git.StatusAdded, AddedLines: 1}},
+	}
+	m := newTestModel(t, files)
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	if !strings.Contains(m.renderFileList(10), "\x1b[") {
+		t.Fatal("colored file list should contain escape sequences")
+	}
+
+	lipgloss.SetColorProfile(termenv.Ascii)
+	m.styles.Monochrome = true
+
+	list := m.renderFileList(10)
+	parsed := ParsedDiff{Lines: []DiffLine{
+		{Type: LineHunkHeader, Content: "func main()", OldNum: -1, NewNum: -1},
+		{Type: LineRemoved, Content: "old := 1", OldNum: 1, NewNum: -1},
+		{Type: LineAdded, Content: "new := 2", OldNum: -1, NewNum: 1},
+	}}
+	diff := RenderDiff(parsed, "a.go", m.styles, m.theme, 80)
+	for name, out := range map[string]string{"file list": list, "diff": diff, "status": m.renderStatusBar()} {
+		if strings.Contains(out, "\x1b[") {
+			t.Errorf("%s contains escape sequences: %q", name, out)
+		}
+	}
+	if !strings.Contains(list, ">● M a.go") {
+		t.Errorf("selected row should carry a > marker, got %q", list)
+	}
+	if !strings.Contains(diff, "- old := 1") || !strings.Contains(diff, "+ new := 2") {
+		t.Errorf("diff lines should keep +/- indicators, got %q", diff)
+	}
+}
+
+func runeKey(r rune) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}} }
+
+func TestPendingKeys_Feed(t *testing.T) {
+	t.Parallel()
+	var p pendingKeys
+	var ok bool
+	if _, ok = p.feed("0"); ok {
+		t.Error("leading 0 should not start a count")
+	}
+	for _, k := range []string{"1", "2"} {
+		if p, ok = p.feed(k); !ok {
+			t.Fatalf("digit %q should be buffered", k)
+		}
+	}
+	if p.count != 12 || p.repeat() != 12 {
+		t.Errorf("count=%d, want 12", p.count)
+	}
+	if _, ok = p.feed("g"); ok {
+		t.Error("g should act at once, not wait for a second key")
+	}
+	if got := (pendingKeys{}).jumpTarget(9, 4); got != 4 {
+		t.Errorf("jumpTarget without count=%d, want fallback 4", got)
+	}
+	if got := (pendingKeys{count: 50}).jumpTarget(9, 0); got != 9 {
+		t.Errorf("jumpTarget past end=%d, want clamped 9", got)
+	}
+}
+
+func TestFileList_CountAndG(t *testing.T) {
+	t.Parallel()
+	var files []fileItem
+	for i := 0; i < 10; i++ {
+		files = append(files, fileItem{change: git.FileChange{Path: fmt.Sprintf("f%d.go", i)}})
+	}
+	m := newTestModel(t, files)
+	press := func(keys string) {
+		t.Helper()
+		for _, r := range keys {
+			result, _ := m.updateFileListMode(runeKey(r))
+			m = result.(Model)
+		}
+	}
+
+	press("3j")
+	if m.cursor != 3 {
+		t.Errorf("cursor=%d after 3j, want 3", m.cursor)
+	}
+	press("20j")
+	if m.cursor != 9 {
+		t.Errorf("cursor=%d after 20j, want clamped 9", m.cursor)
+	}
+	press("2k")
+	if m.cursor != 7 {
+		t.Errorf("cursor=%d after 2k, want 7", m.cursor)
+	}
+	press("g")
+	if m.cursor != 0 {
+		t.Errorf("cursor=%d after g, want 0", m.cursor)
+	}
+	press("3g")
+	if m.cursor != 2 {
+		t.Errorf("cursor=%d after 3g, want 2", m.cursor)
+	}
+	press("5G")
+	if m.cursor != 4 {
+		t.Errorf("cursor=%d after 5G, want 4", m.cursor)
+	}
+	press("G")
+	if m.cursor != 9 {
+		t.Errorf("cursor=%d after G, want 9", m.cursor)
+	}
+}
+
+func TestDiffMode_CountScrollsViewport(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeDiff
+	m.viewport.Width, m.viewport.Height = 40, 5
+	m.viewport.SetContent(strings.Repeat("line\n", 50))
+
+	for _, r := range "10j" {
+		result, _ := m.updateDiffMode(runeKey(r))
+		m = result.(Model)
+	}
+	if m.viewport.YOffset != 10 {
+		t.Errorf("YOffset=%d after 10j, want 10", m.viewport.YOffset)
+	}
+	for _, r := range "gg" {
+		result, _ := m.updateDiffMode(runeKey(r))
+		m = result.(Model)
+	}
+	if m.viewport.YOffset != 0 {
+		t.Errorf("YOffset=%d after gg, want 0", m.viewport.YOffset)
+	}
+	result, _ := m.updateDiffMode(runeKey('G'))
+	m = result.(Model)
+	if !m.viewport.AtBottom() {
+		t.Errorf("YOffset=%d after G, want the bottom", m.viewport.YOffset)
+	}
+	for _, r := range "20G" {
+		result, _ := m.updateDiffMode(runeKey(r))
+		m = result.(Model)
+	}
+	if m.viewport.YOffset != 19 {
+		t.Errorf("YOffset=%d after 20G, want 19", m.viewport.YOffset)
+	}
+	result, _ = m.updateDiffMode(runeKey('g'))
+	if m = result.(Model); m.viewport.YOffset != 0 {
+		t.Errorf("YOffset=%d after a single g, want 0", m.viewport.YOffset)
+	}
+}
+
+func summaryTestFiles() []fileItem {
+	return []fileItem{
+		{change: git.FileChange{Path: "big.go", Status: git.StatusModified, AddedLines: 40, DeletedLines: 10}},
+		{change: git.FileChange{Path: "small.go", Status: git.StatusModified, AddedLines: 1, DeletedLines: 1}},
+		{change: git.FileChange{Path: "logo.png", Status: git.StatusAdded}},
+	}
+}
+
+func TestRenderSummary(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, summaryTestFiles())
+	out := m.renderSummary()
+	lines := strings.Split(out, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 3 file rows + footer, got %d: %q", len(lines), out)
+	}
+	if !strings.Contains(lines[0], "big.go") || !strings.Contains(lines[0], "50 +") {
+		t.Errorf("big.go row=%q", lines[0])
+	}
+	if strings.Count(lines[0], "+") <= strings.Count(lines[1], "+") {
+		t.Error("bigger change should get a longer bar")
+	}
+	if !strings.Contains(lines[2], "Bin") {
+		t.Errorf("0/0 row should show Bin, got %q", lines[2])
+	}
+	if !strings.Contains(lines[3], "3 files changed, 41 insertions(+), 11 deletions(-)") {
+		t.Errorf("footer=%q", lines[3])
+	}
+}
+
+func TestSummaryMode_EnterOpensDiff(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, summaryTestFiles())
+	m.viewport.Width, m.viewport.Height = 60, 10
+
+	result, _ := m.updateFileListMode(runeKey('S'))
+	rm := result.(Model)
+	if rm.mode != modeSummary {
+		t.Fatalf("mode=%v, want summary", rm.mode)
+	}
+	result, _ = rm.updateSummaryMode(tea.KeyMsg{Type: tea.KeyDown})
+	rm = result.(Model)
+	if rm.cursor != 1 {
+		t.Errorf("cursor=%d, want 1", rm.cursor)
+	}
+	result, _ = rm.updateSummaryMode(tea.KeyMsg{Type: tea.KeyEnter})
+	if rm = result.(Model); rm.mode != modeDiff {
+		t.Errorf("mode=%v after enter, want diff", rm.mode)
+	}
+}
+
+func TestHandleDiffLoaded_IgnoredInSummary(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, summaryTestFiles())
+	m.mode = modeSummary
+	result, _ := m.handleDiffLoaded(diffLoadedMsg{content: "diff", index: 0})
+	if rm := result.(Model); rm.lastDiffContent == "diff" {
+		t.Error("diff loads should not replace the summary panel")
+	}
+}
+
+func TestCycleChoice(t *testing.T) {
+	t.Parallel()
+	choices := []string{"a", "b", "c"}
+	if got := cycleChoice(choices, "c", 1); got != "a" {
+		t.Errorf("forward wrap=%q, want a", got)
+	}
+	if got := cycleChoice(choices, "a", -1); got != "c" {
+		t.Errorf("backward wrap=%q, want c", got)
+	}
+	if got := cycleChoice(choices, "unknown", 1); got != "b" {
+		t.Errorf("unknown current=%q, want b", got)
+	}
+}
+
+func TestSettings_ToggleSplitAppliesLive(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	result, _ := m.updateFileListMode(runeKey(','))
+	rm := result.(Model)
+	if rm.mode != modeSettings {
+		t.Fatalf("mode=%v, want settings", rm.mode)
+	}
+	rm.settingsCursor = 1 // Split diff
+	result, cmd := rm.updateSettingsMode(tea.KeyMsg{Type: tea.KeyEnter})
+	rm = result.(Model)
+	if !rm.splitDiff || !rm.cfg.SplitDiff {
+		t.Error("toggling split should update model and config")
+	}
+	if cmd == nil {
+		t.Error("expected save cmd")
+	}
+	if !strings.Contains(rm.renderSettings(), "on") {
+		t.Error("settings should render the new value")
+	}
+}
+
+func TestSettings_ThemeSwapRebuildsStyles(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeSettings
+	m.styles.Monochrome = true
+
+	result, _ := m.updateSettingsMode(tea.KeyMsg{Type: tea.KeyRight})
+	rm := result.(Model)
+	if rm.cfg.Theme == "dark" {
+		t.Fatal("theme should change")
+	}
+	if rm.theme.Bg != theme.Themes[rm.cfg.Theme].Bg {
+		t.Error("theme should be swapped")
+	}
+	if !rm.styles.Monochrome {
+		t.Error("theme swap should keep monochrome mode")
+	}
+}
+
+func TestReloadConfig_ReadsFileAndRebuildsStyles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfg := config.Default()
+	cfg.Theme = "light"
+	cfg.SplitDiff = true
+	if err := config.SaveTo(cfg, filepath.Join(home, ".config", "differ", "config.json")); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestModel(t, nil)
+	m.mode = modeDiff
+
+	result, _ := m.updateDiffMode(tea.KeyMsg{Type: tea.KeyCtrlR})
+	rm := result.(Model)
+	if rm.cfg.Theme != "light" || rm.theme.Bg != theme.LightTheme().Bg {
+		t.Errorf("theme = %q, want light", rm.cfg.Theme)
+	}
+	if !rm.splitDiff {
+		t.Error("split setting should be re-applied")
+	}
+	if rm.statusMsg != "config reloaded" {
+		t.Errorf("statusMsg = %q", rm.statusMsg)
+	}
+}
+
+func TestSettings_EditText(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeSettings
+	fields := settingFields()
+	for i, f := range fields {
+		if f.label == "Editor command" {
+			m.settingsCursor = i
+		}
+	}
+
+	result, _ := m.updateSettingsMode(tea.KeyMsg{Type: tea.KeyEnter})
+	rm := result.(Model)
+	if !rm.settingsEditing {
+		t.Fatal("enter on a text field should start editing")
+	}
+	rm.settingsInput.SetValue("nvim {file}")
+	result, _ = rm.updateSettingsMode(tea.KeyMsg{Type: tea.KeyEnter})
+	rm = result.(Model)
+	if rm.settingsEditing || rm.cfg.EditorCmd != "nvim {file}" {
+		t.Errorf("editing=%v EditorCmd=%q", rm.settingsEditing, rm.cfg.EditorCmd)
+	}
+}
+
+func TestSettings_NumberBounds(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	for _, f := range settingFields() {
+		switch f.label {
+		case "Tab width":
+			cfg.TabWidth = 1
+			f.adjust(&cfg, -1)
+			if cfg.TabWidth != 1 {
+				t.Errorf("TabWidth=%d, want clamped 1", cfg.TabWidth)
+			}
+		case "File list ratio":
+			f.adjust(&cfg, 1)
+			if cfg.FileListRatio != 0.05 {
+				t.Errorf("FileListRatio=%v, want 0.05", cfg.FileListRatio)
+			}
+			f.adjust(&cfg, -2)
+			if cfg.FileListRatio != 0 {
+				t.Errorf("FileListRatio=%v, want clamped 0", cfg.FileListRatio)
+			}
+		}
+	}
+}
+
+func TestSubjectCounter(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.cfg.CommitSubjectMax = 5
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", m.styles.HelpDesc.Render("0/5")},
+		{"fix", m.styles.HelpDesc.Render("3/5")},
+		{"fixed", m.styles.HelpDesc.Render("5/5")},
+		{"fixed it", m.styles.SubjectOverLimit.Render("8/5")},
+		{"héllo", m.styles.HelpDesc.Render("5/5")},
+	}
+	for _, tt := range tests {
+		m.commitInput.SetValue(tt.value)
+		if got := m.subjectCounter(); got != tt.want {
+			t.Errorf("%q: counter = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+	m.cfg.CommitSubjectMax = 0
+	if got := m.subjectCounter(); got != "" {
+		t.Errorf("limit 0: counter = %q, want none", got)
+	}
+}
+
+func TestExplainCmdLine_FallsBack(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	if got := explainCmdLine(cfg); got != defaultCommitMsgCmd {
+		t.Errorf("default = %q, want %q", got, defaultCommitMsgCmd)
+	}
+	cfg.CommitMsgCmd = "llm"
+	if got := explainCmdLine(cfg); got != "llm" {
+		t.Errorf("should fall back to commit_msg_cmd, got %q", got)
+	}
+	cfg.ExplainCmd = "explainer"
+	if got := explainCmdLine(cfg); got != "explainer" {
+		t.Errorf("explain_cmd should win, got %q", got)
+	}
+}
+
+func TestHandleExplainDone(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	m.mode = modeDiff
+	m.explaining = true
+	m.viewport.Width, m.viewport.Height = 60, 10
+
+	result, _ := m.handleExplainDone(explainDoneMsg{path: "a.go", from: modeDiff, text: "Renames the helper."})
+	rm := result.(Model)
+	if rm.explaining || rm.mode != modeExplain {
+		t.Fatalf("explaining=%v mode=%v", rm.explaining, rm.mode)
+	}
+	if view := rm.viewport.View(); !strings.Contains(view, "Renames the helper.") {
+		t.Errorf("explanation not shown: %q", view)
+	}
+
+	result, _ = rm.updateExplainMode(tea.KeyMsg{Type: tea.KeyEsc})
+	if rm = result.(Model); rm.mode != modeDiff {
+		t.Errorf("esc should return to diff, mode=%v", rm.mode)
+	}
+
+	m.explaining = true
+	result, _ = m.handleExplainDone(explainDoneMsg{err: errors.New("not found")})
+	if rm = result.(Model); rm.mode != modeDiff || !strings.Contains(rm.statusMsg, "explain failed") {
+		t.Errorf("mode=%v statusMsg=%q", rm.mode, rm.statusMsg)
+	}
+
+	// Left the diff while waiting: the explanation doesn't take over.
+	m.mode = modeFileList
+	result, _ = m.handleExplainDone(explainDoneMsg{path: "a.go", from: modeDiff, text: "late"})
+	if rm = result.(Model); rm.mode != modeFileList || rm.statusMsg != "explanation of a.go dropped: moved on before it arrived" {
+		t.Errorf("left the diff: mode=%v statusMsg=%q", rm.mode, rm.statusMsg)
+	}
+	m.mode = modeDiff
+	result, _ = m.handleExplainDone(explainDoneMsg{path: "b.go", from: modeDiff, text: "other file"})
+	if rm = result.(Model); rm.mode != modeDiff {
+		t.Errorf("another file's explanation should not show, mode=%v", rm.mode)
+	}
+}
+
+func TestRunAICmd_BlankCommand(t *testing.T) {
+	t.Parallel()
+	if _, err := runAICmd(context.Background(), "  ", "prompt"); err == nil {
+		t.Error("a blank command should be an error, not a panic")
+	}
+}
+
+func TestStagingOnly_BlocksCommitAndRemoteKeys(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go", Staged: true}}})
+	m.SetStagingOnly()
+	m.width = 200
+
+	for _, key := range []rune{'c', 'b', 'P', 'F', 'o', 'X'} {
+		result, cmd := m.updateFileListMode(runeKey(key))
+		rm := result.(Model)
+		if cmd != nil || rm.mode != modeFileList || rm.statusMsg != "" {
+			t.Errorf("%c should be ignored, mode=%v status=%q", key, rm.mode, rm.statusMsg)
+		}
+	}
+	help := m.renderHelpBar()
+	if strings.Contains(help, "commit") || strings.Contains(help, "push") {
+		t.Errorf("help bar should drop commit/push: %q", help)
+	}
+	if !strings.Contains(help, "stage/unstage") {
+		t.Errorf("help bar should keep staging keys: %q", help)
+	}
+}
+
+func TestHandleDivergenceLoaded(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.viewport.Width, m.viewport.Height = 80, 12
+	d := git.Divergence{
+		Upstream:  "origin/main",
+		MergeBase: "abc1234",
+		Ahead:     []git.Commit{{Short: "1111111", Subject: "local fix", Author: "me", Date: "1 hour ago"}},
+		// The list was cut: only one of the 150 commits is loaded.
+		AheadCount: 150,
+	}
+
+	m.mode = modeDiff
+	result, _ := m.handleDivergenceLoaded(divergenceLoadedMsg{divergence: d, from: modeFileList})
+	if rm := result.(Model); rm.mode != modeDiff {
+		t.Errorf("left the file list: mode = %v, want the diff kept", rm.mode)
+	}
+	m.mode = modeFileList
+	result, _ = m.handleDivergenceLoaded(divergenceLoadedMsg{divergence: d, from: modeFileList})
+	rm := result.(Model)
+	if rm.mode != modeDivergence {
+		t.Fatalf("mode = %v, want modeDivergence", rm.mode)
+	}
+	view := rm.viewport.View()
+	for _, want := range []string{"↑150 ↓0 origin/main", "merge-base abc1234", "1111111 local fix", "… and 149 more", "none"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	result, _ = rm.updateDivergenceMode(tea.KeyMsg{Type: tea.KeyEsc})
+	if rm = result.(Model); rm.mode != modeFileList {
+		t.Errorf("esc should return to the file list, mode=%v", rm.mode)
+	}
+}
+
+func TestShowDivergence_RequiresUpstream(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	result, cmd := m.updateFileListMode(runeKey('U'))
+	if rm := result.(Model); cmd != nil || rm.statusMsg != "no upstream configured" {
+		t.Errorf("cmd=%v statusMsg=%q", cmd != nil, rm.statusMsg)
+	}
+}
+
+func TestFileListScroll(t *testing.T) {
+	t.Parallel()
+	files := make([]fileItem, 40)
+	for i := range files {
+		files[i] = fileItem{change: git.FileChange{Path: fmt.Sprintf("file-%02d.go", i)}}
+	}
+	m := newTestModel(t, files)
+	h := m.contentHeight()
+
+	// Walk down past the bottom: the list scrolls to keep the cursor visible.
+	for range 35 {
+		result, _ := m.updateFileListMode(runeKey('j'))
+		m = result.(Model)
+	}
+	out := m.renderFileList(h)
+	if !strings.Contains(out, "file-35.go") {
+		t.Error("file list should show the cursor file when scrolled")
+	}
+	if strings.Contains(out, "file-00.go") {
+		t.Error("file list should not show the first file when scrolled down")
+	}
+
+	// Moving up a little keeps the scroll position instead of snapping back.
+	offset := m.fileOffset
+	result, _ := m.updateFileListMode(runeKey('k'))
+	if m = result.(Model); m.fileOffset != offset {
+		t.Errorf("fileOffset = %d, want %d", m.fileOffset, offset)
+	}
+
+	// Back at the top the group header is visible again.
+	result, _ = m.updateFileListMode(runeKey('g'))
+	result, _ = result.(Model).updateFileListMode(runeKey('g'))
+	if m = result.(Model); m.fileOffset != 0 || !strings.Contains(m.renderFileList(h), "Changes (40)") {
+		t.Errorf("fileOffset = %d, header should be visible", m.fileOffset)
+	}
+}
+
+func TestHandleFilesRefreshed_NotesAddedAndRemoved(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{
+		{change: git.FileChange{Path: "a.go"}},
+		{change: git.FileChange{Path: "b.go"}},
+	})
+
+	// Staging changes the list but not the set of paths: no note.
+	result, _ := m.handleFilesRefreshed(filesRefreshedMsg{files: []fileItem{
+		{change: git.FileChange{Path: "a.go", Staged: true}},
+		{change: git.FileChange{Path: "b.go"}},
+	}})
+	rm := result.(Model)
+	if rm.statusMsg != "" || rm.newFiles != nil {
+		t.Fatalf("statusMsg=%q newFiles=%v", rm.statusMsg, rm.newFiles)
+	}
+
+	result, _ = rm.handleFilesRefreshed(filesRefreshedMsg{files: []fileItem{
+		{change: git.FileChange{Path: "a.go", Staged: true}},
+		{change: git.FileChange{Path: "gen.go"}, untracked: true},
+		{change: git.FileChange{Path: "gen_test.go"}, untracked: true},
+	}})
+	rm = result.(Model)
+	if rm.statusMsg != "+2 -1 files" {
+		t.Errorf("statusMsg = %q, want %q", rm.statusMsg, "+2 -1 files")
+	}
+	if !rm.newFiles["gen.go"] || !rm.newFiles["gen_test.go"] || rm.newFiles["a.go"] {
+		t.Errorf("newFiles = %v", rm.newFiles)
+	}
+
+	next, _ := rm.Update(runeKey('j'))
+	if rm = next.(Model); rm.newFiles != nil {
+		t.Error("a keypress should clear the new-file marks")
+	}
+}
+
+func TestReviewOnly_LocksOutMutations(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{
+		{change: git.FileChange{Path: "a.go", Staged: true}},
+		{change: git.FileChange{Path: "b.go"}},
+	})
+	m.SetReviewOnly()
+	m.upstream.Upstream = "origin/main"
+	m.width = 200
+
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyTab}, runeKey('a'), {Type: tea.KeyCtrlA}, runeKey('A'), runeKey('c'), runeKey('b'), runeKey('P'), runeKey('F'), runeKey('X')} {
+		result, cmd := m.updateFileListMode(key)
+		rm := result.(Model)
+		if cmd != nil || rm.mode != modeFileList || rm.pushConfirm || rm.statusMsg != "" {
+			t.Errorf("%q should be ignored, mode=%v status=%q", key.String(), rm.mode, rm.statusMsg)
+		}
+	}
+	if help := m.renderHelpBar(); strings.Contains(help, "stage") || strings.Contains(help, "commit") {
+		t.Errorf("help bar should list navigation only: %q", help)
+	}
+}
+
+func TestCycleDiffAlgorithm(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.mode = modeDiff
+	result, cmd := m.updateDiffMode(runeKey('D'))
+	rm := result.(Model)
+	if rm.cfg.DiffAlgorithm != "patience" || rm.statusMsg != "diff algorithm: patience" {
+		t.Errorf("DiffAlgorithm=%q statusMsg=%q", rm.cfg.DiffAlgorithm, rm.statusMsg)
+	}
+	if cmd == nil {
+		t.Error("expected reload and save cmds")
+	}
+}
+
+func TestToggleChangesOnly(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	m.mode = modeDiff
+	m.lastDiffContent = "cached"
+
+	result, cmd := m.updateDiffMode(runeKey('z'))
+	rm := result.(Model)
+	if !rm.cfg.ContextOnly || cmd == nil || rm.lastDiffContent != "" {
+		t.Fatalf("z should hide context and reload, ContextOnly=%v", rm.cfg.ContextOnly)
+	}
+	if !strings.HasSuffix(rm.diffCardTitle(), "[changes only]") {
+		t.Errorf("title = %q", rm.diffCardTitle())
+	}
+}
+
+func TestToggleFullFile(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	m.mode = modeDiff
+	m.cfg.RelativeLineNums = true
+
+	result, cmd := m.updateDiffMode(runeKey('f'))
+	rm := result.(Model)
+	if !rm.fullFile || cmd == nil {
+		t.Fatalf("f should enable full file compare and reload, fullFile=%v", rm.fullFile)
+	}
+	if !strings.HasSuffix(rm.diffCardTitle(), "[full file]") {
+		t.Errorf("title = %q", rm.diffCardTitle())
+	}
+	if rm.relativeGutterActive() {
+		t.Error("full file compare is side by side, so the relative gutter is off")
+	}
+}
+
+func TestFailedSwitchOrPull_ReportsStash(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	const hint = "(changes stashed; git stash pop restores them)"
+	result, _ := m.handleBranchSwitched(branchSwitchedMsg{err: errors.New("conflict"), stashed: true})
+	if rm := result.(Model); !strings.HasPrefix(rm.statusMsg, "switch failed: conflict") || !strings.HasSuffix(rm.statusMsg, hint) {
+		t.Errorf("switch: status = %q", rm.statusMsg)
+	}
+	result, _ = m.handlePullDone(pullDoneMsg{err: errors.New("not ff"), stashed: true})
+	if rm := result.(Model); !strings.HasPrefix(rm.statusMsg, "pull failed: not ff") || !strings.HasSuffix(rm.statusMsg, hint) {
+		t.Errorf("pull: status = %q", rm.statusMsg)
+	}
+	result, _ = m.handlePullDone(pullDoneMsg{err: errors.New("not ff")})
+	if rm := result.(Model); strings.Contains(rm.statusMsg, "stash") {
+		t.Errorf("pull without stash: status = %q", rm.statusMsg)
+	}
+}
+
+func TestTreeDirty_StagedOnlyUsesRefresh(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, nil)
+	m.stagedOnly = true
+	result, _ := m.handleFilesRefreshed(filesRefreshedMsg{dirty: true})
+	if rm := result.(Model); !rm.treeDirty() {
+		t.Error("staged-only view should take the dirty state from the refresh")
+	}
+	result, _ = result.(Model).handleFilesRefreshed(filesRefreshedMsg{})
+	if rm := result.(Model); rm.treeDirty() {
+		t.Error("a clean refresh should clear it")
 	}
 }
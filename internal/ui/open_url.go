@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+// openURL opens url in the default browser.
+func openURL(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// openPRCmd opens the hosting provider's "create PR" page for the upstream
+// branch.
+func (m Model) openPRCmd() tea.Cmd {
+	repo := m.repo
+	upstream := m.upstream.Upstream
+	return func() tea.Msg {
+		web, err := repo.RemoteWebURL()
+		if err != nil {
+			return urlOpenedMsg{err: err}
+		}
+		_, branch, _ := strings.Cut(upstream, "/")
+		url, err := git.CompareURL(web, branch)
+		if err != nil {
+			return urlOpenedMsg{err: err}
+		}
+		if err := openURL(url); err != nil {
+			return urlOpenedMsg{err: fmt.Errorf("open %s: %w", url, err)}
+		}
+		return urlOpenedMsg{url: url}
+	}
+}
+
+func (m Model) openPR() (tea.Model, tea.Cmd) {
+	if m.upstream.Upstream == "" {
+		m.statusMsg = "no upstream — push first (P)"
+		return m, nil
+	}
+	m.statusMsg = "opening PR page..."
+	return m, m.openPRCmd()
+}
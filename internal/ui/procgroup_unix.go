@@ -0,0 +1,18 @@
+//go:build !windows
+
+package ui
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killGroupOnCancel starts cmd in its own process group and makes context
+// cancellation kill the whole group, so wrappers like `sh -c` or a CLI that
+// forks a helper don't outlive the timeout.
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package ui
+
+import "os/exec"
+
+// killGroupOnCancel is a no-op on Windows: exec.CommandContext already
+// kills the process itself on cancellation.
+func killGroupOnCancel(cmd *exec.Cmd) {}
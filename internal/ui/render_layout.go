@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jansmrcka/differ/internal/git"
@@ -13,23 +15,40 @@ import (
 // View composition and all rendering helpers.
 
 func (m Model) View() string {
+	return withOSC52(m.osc52, m.renderView())
+}
+
+func (m Model) renderView() string {
 	if m.width == 0 || !m.ready {
 		return ""
 	}
-	if m.width < minWidth || m.height < minHeight {
+	if m.tooSmall() {
 		return fmt.Sprintf("Terminal too small (%dx%d). Minimum: %dx%d", m.width, m.height, minWidth, minHeight)
 	}
-	contentH := m.contentHeight()
+	contentH := m.contentHeight() - m.suggestionRows()
 	var fileContent string
 	if m.mode == modeBranchPicker {
 		fileContent = m.renderBranchList(contentH)
 	} else {
 		fileContent = m.renderFileList(contentH)
 	}
-	fileCard := m.renderCard(m.fileCardTitle(), fileContent, m.mode == modeFileList || m.mode == modeBranchPicker, fileListWidth, contentH)
-	diffCard := m.renderCard(m.diffCardTitle(), m.viewport.View(), m.mode == modeDiff, m.diffWidth(), contentH)
+	fileCard := m.renderCard(m.fileCardTitle(), fileContent, m.mode == modeFileList || m.mode == modeBranchPicker, m.fileListWidth(), contentH)
+	diffContent := lipgloss.JoinHorizontal(lipgloss.Top, m.diffViewportView(), m.renderMinimap(contentH))
+	if len(m.files) == 0 && !m.panelOverlay() {
+		diffContent = m.styles.HelpDesc.Render(m.noChangesText())
+	}
+	if m.mode == modeSettings {
+		diffContent = m.renderSettings()
+	}
+	if m.mode == modeActions {
+		diffContent = m.renderActions()
+	}
+	diffCard := m.renderCard(m.diffCardTitle(), diffContent, m.mode == modeDiff || m.mode == modeSettings || m.mode == modeActions || m.panelOverlay(), m.diffWidth(), contentH)
 	main := lipgloss.JoinHorizontal(lipgloss.Top, fileCard, " ", diffCard)
 	statusBar := m.renderStatusBar()
+	if m.mode == modeCommit && m.suggestionRows() > 0 {
+		return lipgloss.JoinVertical(lipgloss.Left, main, statusBar, m.renderSuggestions(), m.renderCommitBar())
+	}
 	if m.mode == modeCommit {
 		return lipgloss.JoinVertical(lipgloss.Left, main, statusBar, m.renderCommitBar())
 	}
@@ -39,7 +58,23 @@ func (m Model) View() string {
 	return lipgloss.JoinVertical(lipgloss.Left, main, statusBar, m.renderHelpBar())
 }
 
+// noChangesText fills the diff panel when there is no file to show.
+func (m Model) noChangesText() string {
+	switch {
+	case len(m.hiddenFiles) > 0:
+		return "  every change matches hide_patterns (H shows them)"
+	case m.stagedOnly:
+		return "  nothing staged"
+	case m.ref != "":
+		return "  no changes"
+	}
+	return "  working tree clean"
+}
+
 func (m Model) renderCard(title, content string, focused bool, w, h int) string {
+	if !m.cfg.Borders {
+		return renderFlatCard(m.theme, title, content, focused, w, h)
+	}
 	return renderCard(m.theme, title, content, focused, w, h)
 }
 
@@ -76,20 +111,86 @@ func renderCard(t theme.Theme, title, content string, focused bool, w, h int) st
 	return lipgloss.JoinVertical(lipgloss.Left, top, strings.Join(rows, "\n"), bottom)
 }
 
+// renderFlatCard is renderCard without the frame: a title bar colored by
+// focus above h content rows, all exactly w wide.
+func renderFlatCard(t theme.Theme, title, content string, focused bool, w, h int) string {
+	titleColor := lipgloss.Color(t.BorderFg)
+	if focused {
+		titleColor = lipgloss.Color(t.AccentFg)
+	}
+	cardBg := lipgloss.Color(t.CardBg)
+	top := lipgloss.NewStyle().Foreground(titleColor).Background(cardBg).Bold(true).
+		Width(w).MaxWidth(w).Render(" " + title)
+	lines := strings.Split(content, "\n")
+	for len(lines) < h {
+		lines = append(lines, "")
+	}
+	fill := lipgloss.NewStyle().Background(cardBg)
+	rows := make([]string, h)
+	for i := range rows {
+		line := lines[i]
+		if pad := w - lipgloss.Width(line); pad > 0 {
+			line += fill.Render(strings.Repeat(" ", pad))
+		}
+		rows[i] = line
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, top, strings.Join(rows, "\n"))
+}
+
 func (m Model) fileCardTitle() string {
 	if m.mode == modeBranchPicker {
 		return "Branches"
 	}
-	title := m.repo.BranchName()
-	if m.ref != "" {
-		title += " ref:" + m.ref
-	} else if m.stagedOnly {
+	title, detached := m.repo.HeadName()
+	if m.ref == "" && m.treeDirty() {
+		title += "*"
+	}
+	if detached {
+		title += " (detached)"
+	}
+	if m.stagedOnly {
 		title += " staged"
 	}
+	if m.reviewOnly {
+		title += " review"
+	}
+	if m.compareBase != "" {
+		title += " base:" + m.compareBase + " (read-only)"
+	} else if m.commit != "" {
+		title += " commit:" + m.commit[:min(7, len(m.commit))]
+	} else if tip, ok := strings.CutSuffix(m.ref, "..."); ok {
+		title += " ref:" + tip + " (merge-base)"
+	} else if m.ref != "" {
+		title += " ref:" + m.ref
+	}
 	return title
 }
 
 func (m Model) diffCardTitle() string {
+	if m.mode == modeClean {
+		return m.cleanCardTitle()
+	}
+	if m.mode == modeSummary {
+		return "Summary"
+	}
+	if m.mode == modeSettings {
+		return "Settings"
+	}
+	if m.mode == modeActions && m.cursor < len(m.files) {
+		return "actions: " + m.files[m.cursor].change.Path
+	}
+	if m.mode == modeHookOutput {
+		return "commit failed"
+	}
+	if m.mode == modeDivergence {
+		return "Upstream"
+	}
+	if m.mode == modeExplain {
+		return "explain: " + m.explainPath
+	}
+	if m.mode == modeStagePreview && m.stagePlan != nil {
+		return m.stagePlan.what + ": " + m.stagePlan.path
+	}
 	if len(m.files) == 0 || m.cursor >= len(m.files) {
 		return ""
 	}
@@ -98,48 +199,119 @@ func (m Model) diffCardTitle() string {
 	if f.change.Staged {
 		name += " [staged]"
 	}
+	if m.fullFile && !f.untracked {
+		name += " [full file]"
+	} else if m.cfg.ContextOnly && !f.untracked {
+		name += " [changes only]"
+	}
 	return name
 }
 
+// renderFileList renders the files, grouped under "Staged" and "Changes"
+// headers when both index and working tree are shown. Header rows are
+// display-only; the cursor indexes m.files. The window scrolls to keep the
+// cursor visible.
 func (m Model) renderFileList(height int) string {
-	var b strings.Builder
+	var rows []string
+	cursorRow := 0
 	for i, f := range m.files {
-		if i >= height {
-			break
+		if m.startsGroup(i) {
+			rows = append(rows, m.renderGroupHeader(f.change.Staged))
 		}
-		b.WriteString(m.renderFileItem(f, i == m.cursor))
-		if i < len(m.files)-1 {
-			b.WriteByte('\n')
+		if i == m.cursor {
+			cursorRow = len(rows)
 		}
+		rows = append(rows, m.renderFileItem(f, i == m.cursor))
 	}
-	return b.String()
+	// fileOffset is kept in range by clampFileScroll; re-check so the
+	// cursor stays visible even before the first clamp.
+	start := min(m.fileOffset, cursorRow)
+	if cursorRow >= start+height {
+		start = cursorRow - height + 1
+	}
+	end := min(len(rows), start+height)
+	return strings.Join(rows[start:end], "\n")
+}
+
+func (m Model) renderGroupHeader(staged bool) string {
+	label, n := "Changes", 0
+	if staged {
+		label = "Staged"
+	}
+	for _, f := range m.files {
+		if f.change.Staged == staged {
+			n++
+		}
+	}
+	return m.styles.HelpKey.Render(fmt.Sprintf(" %s (%d)", label, n))
 }
 
 func (m Model) renderFileItem(f fileItem, selected bool) string {
-	status := string(f.change.Status)
+	status := statusIcon(m.cfg.Icons, f.change.Status)
+	icon := fileIcon(m.cfg.Icons, f.change.Path)
 	stagedRaw := "  "
 	if f.change.Staged {
 		stagedRaw = "● "
 	}
 	stats := fmt.Sprintf("+%d -%d", f.change.AddedLines, f.change.DeletedLines)
-	name := filepath.Base(f.change.Path)
-	if f.change.OldPath != "" {
-		name = filepath.Base(f.change.OldPath) + " → " + filepath.Base(f.change.Path)
+	if bar := m.renderStatsBar(f.change.AddedLines, f.change.DeletedLines); bar != "" {
+		stats += " " + bar
+	}
+	nameMaxW := m.fileListWidth() - lipgloss.Width(stagedRaw) - lipgloss.Width(status) - 1 - lipgloss.Width(icon) - lipgloss.Width(stats) - 1
+	if m.cfg.MaxNameWidth > 0 {
+		nameMaxW = min(nameMaxW, m.cfg.MaxNameWidth)
 	}
-	nameMaxW := fileListWidth - lipgloss.Width(stagedRaw) - lipgloss.Width(status) - 1 - lipgloss.Width(stats) - 1
 	if nameMaxW < 1 {
 		nameMaxW = 1
 	}
-	name = truncatePath(name, nameMaxW)
+	name := icon + m.fileItemName(f, nameMaxW)
 	if selected {
-		return m.styles.FileSelected.Width(fileListWidth).Render(fmt.Sprintf("%s%s %s %s", stagedRaw, status, name, stats))
+		return renderSelectedRow(m.styles, fmt.Sprintf("%s%s %s %s", stagedRaw, status, name, stats), m.fileListWidth())
+	}
+	if m.newFiles[f.change.Path] {
+		name = m.styles.NewFile.Render(name)
 	}
 	staged := stagedRaw
 	if f.change.Staged {
 		staged = m.styles.StagedIcon.Render("● ")
 	}
 	line := fmt.Sprintf("%s%s %s %s", staged, m.styleStatus(status, f.change.Status), name, stats)
-	return m.styles.FileItem.Width(fileListWidth).Render(line)
+	return m.styles.FileItem.Width(m.fileListWidth()).Render(line)
+}
+
+const statsBarWidth = 5
+
+// renderStatsBar draws a GitHub-style added/deleted ratio bar.
+// Returns "" for files without line changes (including binaries).
+func (m Model) renderStatsBar(added, deleted int) string {
+	a, d := statsBarCells(added, deleted)
+	if a+d == 0 {
+		return ""
+	}
+	return m.styles.DiffAdded.Render(strings.Repeat("■", a)) + m.styles.DiffRemoved.Render(strings.Repeat("■", d))
+}
+
+// statsBarCells splits up to statsBarWidth cells between added and deleted
+// lines. Changes smaller than the bar get one cell per line.
+func statsBarCells(added, deleted int) (int, int) {
+	return splitCells(added, deleted, min(added+deleted, statsBarWidth))
+}
+
+// splitCells divides cells proportionally between added and deleted lines,
+// keeping at least one cell for each non-zero side.
+func splitCells(added, deleted, cells int) (int, int) {
+	total := added + deleted
+	if total == 0 || cells <= 0 {
+		return 0, 0
+	}
+	a := (added*cells + total/2) / total
+	if added > 0 && a == 0 {
+		a = 1
+	}
+	if deleted > 0 && a == cells && cells > 1 {
+		a = cells - 1
+	}
+	return a, cells - a
 }
 
 func (m Model) renderBranchList(height int) string {
@@ -149,7 +321,7 @@ func (m Model) renderBranchList(height int) string {
 	list := m.activeBranches()
 	itemH := height - 1
 	if len(list) == 0 {
-		b.WriteString(m.styles.FileItem.Width(fileListWidth).Render(m.styles.HelpDesc.Render("  no matches")))
+		b.WriteString(m.styles.FileItem.Width(m.fileListWidth()).Render(m.styles.HelpDesc.Render("  no matches")))
 		return b.String()
 	}
 	end := m.branchOffset + itemH
@@ -169,11 +341,11 @@ func (m Model) renderBranchFilterBar() string {
 	list := m.activeBranches()
 	countStyled := m.styles.HelpDesc.Render(fmt.Sprintf("%d/%d", len(list), len(m.branches)))
 	input := m.branchFilter.View()
-	gap := fileListWidth - lipgloss.Width(input) - lipgloss.Width(countStyled) - 1
+	gap := m.fileListWidth() - lipgloss.Width(input) - lipgloss.Width(countStyled) - 1
 	if gap < 0 {
 		gap = 0
 	}
-	return lipgloss.NewStyle().Width(fileListWidth).Render(input + strings.Repeat(" ", gap) + countStyled)
+	return lipgloss.NewStyle().Width(m.fileListWidth()).Render(input + strings.Repeat(" ", gap) + countStyled)
 }
 
 func (m Model) renderBranchItem(name string, selected, current bool) string {
@@ -181,13 +353,117 @@ func (m Model) renderBranchItem(name string, selected, current bool) string {
 	if current {
 		prefix = m.styles.StagedIcon.Render("* ")
 	}
-	line := prefix + truncatePath(name, fileListWidth-4)
+	tag := ""
+	if !current && m.mergedBranches[name] {
+		tag = " " + m.styles.HelpDesc.Render("✓merged")
+	}
+	line := prefix + truncatePath(name, m.fileListWidth()-4-lipgloss.Width(tag)) + tag
+	if detail := m.branchDetail(name, m.fileListWidth()-3-lipgloss.Width(line)); detail != "" {
+		line += "  " + m.styles.HelpDesc.Render(detail)
+	}
 	if selected {
-		return m.styles.FileSelected.Width(fileListWidth).Render(line)
+		return renderSelectedRow(m.styles, line, m.fileListWidth())
+	}
+	return m.styles.FileItem.Width(m.fileListWidth()).Render(line)
+}
+
+// branchDetailMinWidth is the file panel width needed for the commit column.
+const branchDetailMinWidth = 50
+
+// branchDetail returns "subject · date" for the branch's last commit, sized to
+// avail cells, or "" when the panel is too narrow or details are unavailable.
+func (m Model) branchDetail(name string, avail int) string {
+	info, ok := m.branchDetails[name]
+	if !ok || m.fileListWidth() < branchDetailMinWidth || avail < 10 {
+		return ""
+	}
+	detail := info.Subject
+	if info.Date != "" {
+		detail += " · " + info.Date
+	}
+	return truncateEnd(detail, avail)
+}
+
+// fileItemName returns the display name: the basename, or the full path
+// shortened by truncateSmart when ShowFullPath is on. Renames show
+// old → new with each side shortened on its own.
+func (m Model) fileItemName(f fileItem, maxW int) string {
+	oldName, name := filepath.Base(f.change.OldPath), filepath.Base(f.change.Path)
+	if m.cfg.ShowFullPath {
+		oldName, name = f.change.OldPath, f.change.Path
+	}
+	if f.change.OldPath == "" && !m.cfg.ShowFullPath {
+		return truncatePath(name, maxW)
+	}
+	if f.change.OldPath == "" {
+		return truncateSmart(name, maxW)
+	}
+	if lipgloss.Width(oldName)+3+lipgloss.Width(name) <= maxW {
+		return oldName + " → " + name
 	}
-	return m.styles.FileItem.Width(fileListWidth).Render(line)
+	half := max((maxW-3)/2, 1)
+	return truncateSmart(oldName, half) + " → " + truncateSmart(name, maxW-3-half)
 }
 
+// truncateSmart shortens a path to maxW cells keeping its top-level
+// directory and filename, and as many trailing directories as fit:
+// "src/…/api/handler.go". Paths too long even for "top/…/name" fall back
+// to "…/name", then to truncateMiddle.
+func truncateSmart(path string, maxW int) string {
+	if lipgloss.Width(path) <= maxW {
+		return path
+	}
+	parts := strings.Split(path, "/")
+	last := len(parts) - 1
+	if last < 2 {
+		return truncateMiddle(path, maxW)
+	}
+	tail := parts[last]
+	if lipgloss.Width(parts[0]+"/…/"+tail) > maxW {
+		if short := "…/" + tail; lipgloss.Width(short) <= maxW {
+			return short
+		}
+		return truncateMiddle(tail, maxW)
+	}
+	for i := last - 1; i > 1; i-- {
+		next := parts[i] + "/" + tail
+		if lipgloss.Width(parts[0]+"/…/"+next) > maxW {
+			break
+		}
+		tail = next
+	}
+	return parts[0] + "/…/" + tail
+}
+
+// truncateMiddle shortens s to maxW cells by replacing its middle with an
+// ellipsis, favoring the tail so the filename stays visible. Widths are in
+// terminal cells, so wide runes never overflow.
+func truncateMiddle(s string, maxW int) string {
+	if lipgloss.Width(s) <= maxW {
+		return s
+	}
+	if maxW < 3 {
+		return truncatePath(s, maxW)
+	}
+	r := []rune(s)
+	tailW := (maxW - 1) * 2 / 3
+	headW := maxW - 1 - tailW
+	head, w := 0, 0
+	for head < len(r) && w+runeWidth(r[head]) <= headW {
+		w += runeWidth(r[head])
+		head++
+	}
+	tail, w := len(r), 0
+	for tail > head && w+runeWidth(r[tail-1]) <= tailW {
+		w += runeWidth(r[tail-1])
+		tail--
+	}
+	return string(r[:head]) + "…" + string(r[tail:])
+}
+
+// runeWidth is the terminal cell width of r.
+func runeWidth(r rune) int { return lipgloss.Width(string(r)) }
+
 func truncatePath(path string, maxW int) string {
 	if lipgloss.Width(path) <= maxW {
 		return path
@@ -198,6 +474,27 @@ func truncatePath(path string, maxW int) string {
 	return "…" + path
 }
 
+// renderSelectedRow renders the row under the cursor. Without colors the
+// highlight is invisible, so a ">" marker takes the place of the left padding.
+func renderSelectedRow(styles Styles, line string, w int) string {
+	if styles.Monochrome {
+		return lipgloss.NewStyle().Width(w).Render(">" + line)
+	}
+	return styles.FileSelected.Width(w).Render(line)
+}
+
+// truncateEnd shortens s to maxW cells, replacing the tail with an ellipsis.
+func truncateEnd(s string, maxW int) string {
+	if lipgloss.Width(s) <= maxW {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && lipgloss.Width(string(r)) > maxW-1 {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "…"
+}
+
 func (m Model) styleStatus(icon string, status git.FileStatus) string {
 	switch status {
 	case git.StatusModified:
@@ -215,41 +512,210 @@ func (m Model) styleStatus(icon string, status git.FileStatus) string {
 	}
 }
 
+// Status bar token priorities: when the bar is too narrow, extras are
+// elided first, then counts; the status message is kept whenever it fits.
+const (
+	statusPrioExtra = iota
+	statusPrioCount
+	statusPrioMessage
+)
+
+type statusToken struct {
+	text string
+	prio int
+}
+
+// renderStatusBar renders the configured status items in order, followed by
+// the transient status message, fitted to the terminal width.
 func (m Model) renderStatusBar() string {
-	stagedCount := 0
-	for _, f := range m.files {
-		if f.change.Staged {
-			stagedCount++
+	var tokens []statusToken
+	for _, item := range m.cfg.StatusBarItems {
+		if text := m.statusBarItem(item); text != "" {
+			prio := statusPrioExtra
+			if item == "staged" || item == "files" {
+				prio = statusPrioCount
+			}
+			tokens = append(tokens, statusToken{text, prio})
 		}
 	}
-	left := fmt.Sprintf(" %d staged  %d files", stagedCount, len(m.files))
-	if m.upstream.Upstream != "" && (m.upstream.Ahead > 0 || m.upstream.Behind > 0) {
-		left += fmt.Sprintf("  ↑%d ↓%d", m.upstream.Ahead, m.upstream.Behind)
+	if text := m.hiddenItem(); text != "" {
+		tokens = append(tokens, statusToken{text, statusPrioCount})
+	}
+	if m.fetching {
+		tokens = append(tokens, statusToken{"fetching origin...", statusPrioMessage})
 	}
-	if m.splitDiff {
-		left += "  split"
+	if m.explaining {
+		tokens = append(tokens, statusToken{m.spinner.View() + " explaining diff...", statusPrioMessage})
 	}
 	if m.statusMsg != "" {
-		left += "  " + m.statusMsg
+		tokens = append(tokens, statusToken{m.statusMsg, statusPrioMessage})
 	}
+	left := truncateEnd(fitStatusTokens(tokens, m.width), m.width)
 	return m.styles.StatusBar.Width(m.width).Render(left)
 }
 
+// fitStatusTokens joins tokens into a line of at most width cells, dropping
+// the lowest-priority tokens (rightmost first) until it fits. Each run of
+// dropped tokens is shown as "…". Message tokens are never dropped, so a
+// message too long on its own is left for the caller to truncate.
+func fitStatusTokens(tokens []statusToken, width int) string {
+	dropped := make([]bool, len(tokens))
+	line := joinStatusTokens(tokens, dropped)
+	for prio := statusPrioExtra; prio < statusPrioMessage; prio++ {
+		for i := len(tokens) - 1; i >= 0 && lipgloss.Width(line) > width; i-- {
+			if tokens[i].prio == prio {
+				dropped[i] = true
+				line = joinStatusTokens(tokens, dropped)
+			}
+		}
+	}
+	return line
+}
+
+func joinStatusTokens(tokens []statusToken, dropped []bool) string {
+	var parts []string
+	for i, tok := range tokens {
+		switch {
+		case !dropped[i]:
+			parts = append(parts, tok.text)
+		case i == 0 || !dropped[i-1]:
+			parts = append(parts, "…")
+		}
+	}
+	return " " + strings.Join(parts, "  ")
+}
+
+// statusBarItem renders one status bar token; unknown or empty items yield "".
+func (m Model) statusBarItem(item string) string {
+	switch item {
+	case "branch":
+		if m.repo != nil {
+			return m.repo.BranchName()
+		}
+	case "staged":
+		staged := 0
+		for _, f := range m.files {
+			if f.change.Staged {
+				staged++
+			}
+		}
+		return fmt.Sprintf("%d staged", staged)
+	case "files":
+		return fmt.Sprintf("%d files", len(m.files))
+	case "ahead_behind":
+		if m.upstream.Upstream != "" && m.upstream.Shallow {
+			return "shallow"
+		}
+		if m.upstream.Upstream != "" && (m.upstream.Ahead > 0 || m.upstream.Behind > 0) {
+			return fmt.Sprintf("↑%d ↓%d", m.upstream.Ahead, m.upstream.Behind)
+		}
+	case "split":
+		if m.splitDiff {
+			return "split"
+		}
+	case "last_fetch":
+		return m.lastFetchItem(time.Now())
+	case "upstream_url":
+		return m.upstream.URL
+	case "time":
+		return time.Now().Format("15:04")
+	}
+	return ""
+}
+
+// staleFetchAfter is when the last fetch is old enough that ahead/behind
+// counts deserve a warning.
+const staleFetchAfter = 24 * time.Hour
+
+// lastFetchItem shows how long ago the repo fetched, highlighted once stale.
+// Only shown with an upstream, where ahead/behind depends on it.
+func (m Model) lastFetchItem(now time.Time) string {
+	if m.upstream.Upstream == "" {
+		return ""
+	}
+	if m.upstream.LastFetch.IsZero() {
+		return m.styles.StaleFetch.Render("never fetched")
+	}
+	age := now.Sub(m.upstream.LastFetch)
+	text := "fetched " + relativeAge(age)
+	if age > staleFetchAfter {
+		return m.styles.StaleFetch.Render(text)
+	}
+	return text
+}
+
+// relativeAge formats d coarsely: "just now", "5m ago", "3h ago", "2d ago".
+func relativeAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
 func (m Model) renderHelpBar() string {
 	var pairs []struct{ key, desc string }
 	switch m.mode {
 	case modeDiff:
-		pairs = []struct{ key, desc string }{{"j/k", "scroll"}, {"d/u", "½ page"}, {"n/p", "next/prev"}, {"v", "split"}, {"tab", "stage"}, {"e", "edit"}, {"b", "branches"}, {"esc", "back"}, {"q", "quit"}}
+		// Most used first: a narrow bar keeps only the leading pairs.
+		pairs = []struct{ key, desc string }{{"j/k", "scroll"}, {"esc", "back"}, {"n/p", "next/prev"}, {"tab", "stage"}, {"s", "stage hunk"}, {"d/u", "½ page"}, {"g/G", "top/bottom"}, {"v", "split"}, {"f", "full file"}, {"z", "changes only"}, {"e", "edit"}, {"y/Y", "copy path"}, {"b", "branches"}, {"q", "quit"}}
+	case modeSettings:
+		pairs = []struct{ key, desc string }{{"j/k", "navigate"}, {"h/l", "change"}, {"enter", "toggle/edit"}, {"esc", "close"}}
+	case modeActions:
+		pairs = []struct{ key, desc string }{{"j/k", "navigate"}, {"enter", "run"}, {"key", "run that action"}, {"esc", "close"}}
+	case modeSummary:
+		pairs = []struct{ key, desc string }{{"j/k", "navigate"}, {"enter", "view diff"}, {"esc", "back"}, {"q", "quit"}}
+	case modeHookOutput:
+		pairs = []struct{ key, desc string }{{"j/k", "scroll"}, {"esc", "back to commit"}, {"q", "quit"}}
+	case modeDivergence:
+		pairs = []struct{ key, desc string }{{"j/k", "scroll"}, {"esc", "back"}, {"q", "quit"}}
+	case modeExplain:
+		pairs = []struct{ key, desc string }{{"j/k", "scroll"}, {"esc", "back to diff"}, {"q", "quit"}}
+	case modeStagePreview:
+		pairs = []struct{ key, desc string }{{"s/y", "apply patch"}, {"j/k", "scroll"}, {"esc", "cancel"}}
+	case modeClean:
+		pairs = []struct{ key, desc string }{{"y", "delete files"}, {"i", "toggle ignored"}, {"j/k", "scroll"}, {"esc", "cancel"}}
 	case modeBranchPicker:
-		pairs = []struct{ key, desc string }{{"type", "filter"}, {"↑/↓/^j/^k", "navigate"}, {"enter", "switch"}, {"^n", "new"}, {"esc", "clear/close"}}
+		pairs = []struct{ key, desc string }{{"type", "filter"}, {"↑/↓/^j/^k", "navigate"}, {"enter", "switch"}, {"^n", "new"}, {"^t", "detach at ref"}, {"esc", "clear/close"}}
 	default:
-		pairs = []struct{ key, desc string }{{"j/k", "navigate"}, {"enter", "view diff"}, {"v", "split"}, {"tab", "stage/unstage"}, {"a", "stage all"}, {"e", "edit"}, {"b", "branches"}, {"c", "commit"}, {"P", "push"}, {"F", "pull"}, {"q", "quit"}}
+		commit := "commit"
+		if m.cfg.AutoStageOnCommit {
+			commit = "commit -a"
+		}
+		// Most used first, as in the diff view.
+		pairs = []struct{ key, desc string }{{"j/k", "navigate"}, {"enter", "diff"}, {"tab", "stage/unstage"}, {"c", commit}, {"space", "actions"}, {"a", "stage all"}, {"b", "branches"}, {"P", "push"}, {"F", "pull"}, {"e", "edit"}, {"v", "split"}, {"S", "summary"}, {"X", "clean"}, {",", "settings"}, {"q", "quit"}}
+	}
+	if m.reviewOnly {
+		pairs = reviewHelpPairs(m.mode, pairs)
 	}
 	parts := make([]string, 0, len(pairs))
 	for _, p := range pairs {
+		if m.stagingOnly && stagingOnlyBlocks(m.mode, p.key) {
+			continue
+		}
 		parts = append(parts, m.styles.HelpKey.Render(p.key)+" "+m.styles.HelpDesc.Render(p.desc))
 	}
-	return lipgloss.NewStyle().Width(m.width).Render(" " + strings.Join(parts, "  ·  "))
+	return m.styles.HelpBar.Width(m.width).Render(fitHelpParts(parts, m.width))
+}
+
+// fitHelpParts joins rendered help pairs into one line of at most width
+// cells, as contentHeight budgets a single help row. Pairs that don't fit
+// are dropped from the end, keeping the last one (the way out), and a "…"
+// shows that some are hidden. The first and last pairs always fit in a
+// window of minWidth.
+func fitHelpParts(parts []string, width int) string {
+	const sep = "  ·  "
+	line := " " + strings.Join(parts, sep)
+	last := len(parts) - 1
+	for n := last - 1; n > 0 && lipgloss.Width(line) > width; n-- {
+		line = " " + strings.Join(parts[:n], sep) + sep + "…" + sep + parts[last]
+	}
+	return line
 }
 
 func (m Model) renderCommitBar() string {
@@ -257,10 +723,61 @@ func (m Model) renderCommitBar() string {
 	if m.generatingMsg {
 		return lipgloss.NewStyle().Width(m.width).Render(prompt + m.styles.HelpDesc.Render("generating...  esc cancel"))
 	}
-	return lipgloss.NewStyle().Width(m.width).Render(prompt + m.commitInput.View() + "  " + m.styles.HelpDesc.Render("esc cancel · enter commit"))
+	if m.committing {
+		return lipgloss.NewStyle().Width(m.width).Render(prompt + m.spinner.View() + " " + m.styles.HelpDesc.Render("running commit hooks..."))
+	}
+	hint := "esc cancel · enter commit · ^r regenerate"
+	if len(m.suggestions) > 0 {
+		hint = "↑/↓ pick · " + hint
+	}
+	if counter := m.subjectCounter(); counter != "" {
+		hint = counter + "  " + m.styles.HelpDesc.Render(hint)
+	} else {
+		hint = m.styles.HelpDesc.Render(hint)
+	}
+	return lipgloss.NewStyle().Width(m.width).Render(prompt + m.commitInput.View() + "  " + hint)
+}
+
+// subjectCounter shows the subject length against commit_subject_max
+// ("48/50"), highlighted once over. It only nudges; long subjects still
+// commit.
+func (m Model) subjectCounter() string {
+	limit := m.cfg.CommitSubjectMax
+	if limit <= 0 {
+		return ""
+	}
+	n := utf8.RuneCountInString(m.commitInput.Value())
+	text := fmt.Sprintf("%d/%d", n, limit)
+	if n > limit {
+		return m.styles.SubjectOverLimit.Render(text)
+	}
+	return m.styles.HelpDesc.Render(text)
+}
+
+func (m Model) suggestionRows() int {
+	if m.mode != modeCommit {
+		return 0
+	}
+	return len(m.suggestions)
+}
+
+func (m Model) renderSuggestions() string {
+	rows := make([]string, 0, len(m.suggestions))
+	for i, s := range m.suggestions {
+		line := truncateEnd(fmt.Sprintf("%d. %s", i+1, s), m.width-2)
+		if i == m.suggestionIdx {
+			rows = append(rows, renderSelectedRow(m.styles, line, m.width))
+			continue
+		}
+		rows = append(rows, m.styles.FileItem.Width(m.width).Render(m.styles.HelpDesc.Render(line)))
+	}
+	return strings.Join(rows, "\n")
 }
 
 func (m Model) renderBranchCreateBar() string {
-	prompt := m.styles.HelpKey.Render(" new branch: ")
-	return lipgloss.NewStyle().Width(m.width).Render(prompt + m.branchInput.View() + "  " + m.styles.HelpDesc.Render("esc cancel · enter create"))
+	prompt, hint := " new branch: ", "esc cancel · enter create"
+	if m.branchDetaching {
+		prompt, hint = " detach at: ", "esc cancel · enter checkout (detaches HEAD)"
+	}
+	return lipgloss.NewStyle().Width(m.width).Render(m.styles.HelpKey.Render(prompt) + m.branchInput.View() + "  " + m.styles.HelpDesc.Render(hint))
 }
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jansmrcka/differ/internal/theme"
+)
+
+// RepoPickerModel lists recently opened repositories when differ is started
+// outside a git repo. It filters and navigates like the branch picker.
+type RepoPickerModel struct {
+	styles   Styles
+	theme    theme.Theme
+	repos    []string
+	filtered []string // nil = no filter
+	filter   textinput.Model
+	cursor   int
+	offset   int
+	width    int
+	height   int
+	selected string
+}
+
+func NewRepoPickerModel(repos []string, styles Styles, t theme.Theme) RepoPickerModel {
+	fi := textinput.New()
+	fi.Placeholder = "filter..."
+	fi.CharLimit = 100
+	fi.Focus()
+	return RepoPickerModel{styles: styles, theme: t, repos: repos, filter: fi}
+}
+
+// Selected returns the chosen repo directory, or "" if the picker was
+// dismissed.
+func (m RepoPickerModel) Selected() string {
+	return m.selected
+}
+
+func (m RepoPickerModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m RepoPickerModel) active() []string {
+	if m.filtered != nil {
+		return m.filtered
+	}
+	return m.repos
+}
+
+func (m RepoPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.filter.Width = max(m.width-16, 10)
+		return m.clampScroll(), nil
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m RepoPickerModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		if m.filter.Value() != "" {
+			m.filter.Reset()
+			m.filtered = nil
+			m.cursor, m.offset = 0, 0
+			return m, nil
+		}
+		return m, tea.Quit
+	case "up", "ctrl+k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		return m.clampScroll(), nil
+	case "down", "ctrl+j":
+		if m.cursor < len(m.active())-1 {
+			m.cursor++
+		}
+		return m.clampScroll(), nil
+	case "enter":
+		list := m.active()
+		if m.cursor >= len(list) {
+			return m, nil
+		}
+		m.selected = list[m.cursor]
+		return m, tea.Quit
+	}
+	prevVal := m.filter.Value()
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	if m.filter.Value() != prevVal {
+		m.filtered = filterBranches(m.repos, m.filter.Value())
+		m.cursor, m.offset = 0, 0
+	}
+	return m, cmd
+}
+
+// listHeight is the number of rows left for repos below the filter bar.
+func (m RepoPickerModel) listHeight() int {
+	return m.height - 5 // card borders + filter + status + help
+}
+
+func (m RepoPickerModel) clampScroll() RepoPickerModel {
+	h := m.listHeight()
+	if h <= 0 {
+		return m
+	}
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+h {
+		m.offset = m.cursor - h + 1
+	}
+	return m
+}
+
+func (m RepoPickerModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	contentH := m.height - 4
+	cardW := m.width - 2
+	list := m.active()
+
+	var b strings.Builder
+	count := m.styles.HelpDesc.Render(fmt.Sprintf("%d/%d", len(list), len(m.repos)))
+	gap := max(cardW-lipgloss.Width(m.filter.View())-lipgloss.Width(count)-1, 0)
+	b.WriteString(m.filter.View() + strings.Repeat(" ", gap) + count)
+	if len(list) == 0 {
+		b.WriteString("\n" + m.styles.HelpDesc.Render("  no matches"))
+	}
+	end := min(m.offset+m.listHeight(), len(list))
+	for i := m.offset; i < end; i++ {
+		line := "  " + truncateMiddle(list[i], cardW-3)
+		b.WriteByte('\n')
+		if i == m.cursor {
+			b.WriteString(renderSelectedRow(m.styles, line, cardW))
+		} else {
+			b.WriteString(m.styles.PickerRow.Width(cardW).Render(line))
+		}
+	}
+
+	card := renderCard(m.theme, "Recent repositories", b.String(), true, cardW, contentH)
+	status := m.styles.StatusBar.Width(m.width).Render(" not a git repository — pick a recent one")
+	var parts []string
+	for _, p := range []struct{ key, desc string }{
+		{"↑/↓", "navigate"},
+		{"enter", "open"},
+		{"esc", "quit"},
+	} {
+		parts = append(parts, m.styles.HelpKey.Render(p.key)+" "+m.styles.HelpDesc.Render(p.desc))
+	}
+	help := m.styles.HelpBar.Width(m.width).Render(" " + strings.Join(parts, "  ·  "))
+	return lipgloss.JoinVertical(lipgloss.Left, card, status, help)
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRepoPicker(t *testing.T) {
+	t.Parallel()
+	styles, th := testStyles()
+	repos := []string{"/src/differ", "/src/api", "/work/site"}
+	send := func(m RepoPickerModel, msg tea.Msg) RepoPickerModel {
+		t.Helper()
+		result, _ := m.Update(msg)
+		return result.(RepoPickerModel)
+	}
+	m := NewRepoPickerModel(repos, styles, th)
+	m = send(m, tea.WindowSizeMsg{Width: 80, Height: 20})
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyDown})
+	m = send(m, tea.KeyMsg{Type: tea.KeyDown})
+	m = send(m, tea.KeyMsg{Type: tea.KeyDown})
+	if m.cursor != 2 {
+		t.Errorf("cursor=%d, want 2 (clamped)", m.cursor)
+	}
+
+	for _, r := range "api" {
+		m = send(m, runeKey(r))
+	}
+	if got := m.active(); len(got) != 1 || got[0] != "/src/api" || m.cursor != 0 {
+		t.Fatalf("filtered=%v cursor=%d, want [/src/api] at 0", got, m.cursor)
+	}
+	result, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := result.(RepoPickerModel).Selected(); got != "/src/api" || cmd == nil {
+		t.Errorf("Selected()=%q quit=%v, want /src/api and quit", got, cmd != nil)
+	}
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.filtered != nil || m.filter.Value() != "" {
+		t.Error("first esc should clear the filter")
+	}
+	result, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if result.(RepoPickerModel).Selected() != "" || cmd == nil {
+		t.Error("esc without a filter should quit with nothing selected")
+	}
+}
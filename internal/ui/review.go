@@ -0,0 +1,22 @@
+package ui
+
+// Review mode (--review): a read-only session for pair review or demos.
+// Staging, committing, push/pull, branch switching and clean are disabled
+// by guards in their handlers; this file holds the setup and help bar.
+
+// SetReviewOnly locks out every action that changes the repository.
+func (m *Model) SetReviewOnly() {
+	m.reviewOnly = true
+}
+
+// reviewHelpPairs trims the file list and diff help bars to navigation
+// keys; other modes keep theirs.
+func reviewHelpPairs(mode viewMode, pairs []struct{ key, desc string }) []struct{ key, desc string } {
+	switch mode {
+	case modeFileList:
+		return []struct{ key, desc string }{{"j/k", "navigate"}, {"enter", "view diff"}, {"v", "split"}, {"S", "summary"}, {"space", "actions"}, {"q", "quit"}}
+	case modeDiff:
+		return []struct{ key, desc string }{{"j/k", "scroll"}, {"d/u", "½ page"}, {"n/p", "next/prev"}, {"v", "split"}, {"esc", "back"}, {"q", "quit"}}
+	}
+	return pairs
+}
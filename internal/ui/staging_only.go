@@ -0,0 +1,23 @@
+package ui
+
+// Staging-only configuration used by `differ add`.
+
+// SetStagingOnly turns the model into the focused `differ add` UI: the file
+// list and diff keep staging, while commit, branch, push/pull, PR and clean
+// keys are disabled and dropped from the help bar.
+func (m *Model) SetStagingOnly() {
+	m.stagingOnly = true
+}
+
+// stagingOnlyBlocks reports whether key is disabled in staging-only mode.
+// The diff view reuses X for uncapping, so only its branch key is blocked.
+func stagingOnlyBlocks(mode viewMode, key string) bool {
+	if mode == modeDiff {
+		return key == "b"
+	}
+	switch key {
+	case "c", "b", "P", "F", "o", "X":
+		return true
+	}
+	return false
+}
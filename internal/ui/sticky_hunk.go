@@ -0,0 +1,80 @@
+package ui
+
+import "strings"
+
+// hunkHeader is a hunk header's rendered line and its text.
+type hunkHeader struct {
+	line int
+	text string
+}
+
+// hunkHeaders finds the hunk headers of a diff rendered one line per parsed
+// line (unified or compact). changesOnly gap markers are not hunks.
+func hunkHeaders(lines []DiffLine) []hunkHeader {
+	var hunks []hunkHeader
+	for i, dl := range lines {
+		if dl.Type == LineHunkHeader && dl.Content != gapMarker {
+			hunks = append(hunks, hunkHeader{line: i, text: dl.Content})
+		}
+	}
+	return hunks
+}
+
+// splitHunkHeaders is hunkHeaders for the split view, which renders one
+// line per left/right pair.
+func splitHunkHeaders(parsed ParsedDiff) []hunkHeader {
+	if parsed.Binary {
+		return nil
+	}
+	var hunks []hunkHeader
+	for i, sl := range PairLines(parsed.Lines) {
+		if sl.Left != nil && sl.Left.Type == LineHunkHeader && sl.Left.Content != gapMarker {
+			hunks = append(hunks, hunkHeader{line: i, text: sl.Left.Content})
+		}
+	}
+	return hunks
+}
+
+// activeHunk returns the hunk containing line top: the last header at or
+// above it.
+func activeHunk(hunks []hunkHeader, top int) (hunkHeader, bool) {
+	var active hunkHeader
+	found := false
+	for _, h := range hunks {
+		if h.line > top {
+			break
+		}
+		active, found = h, true
+	}
+	return active, found
+}
+
+// diffViewportView renders the diff viewport, pinning the header of the
+// hunk being read over the first row once it has scrolled out of view. A
+// hunk picked with ]/[ has its header marked.
+func (m Model) diffViewportView() string {
+	view := m.viewport.View()
+	if m.panelOverlay() || m.mode == modeSettings || m.mode == modeActions {
+		return view
+	}
+	rows := strings.Split(view, "\n")
+	top := m.viewport.YOffset
+	if m.pickedHunkVisible() && !m.relativeGutterActive() {
+		h := m.diffHunks[m.hunkCursor]
+		if r := h.line - top; r < len(rows) {
+			rows[r] = m.stickyHunkRow("▸ @@ " + h.text)
+		}
+	}
+	h, ok := activeHunk(m.diffHunks, top)
+	if !ok || h.line == top || (m.relativeGutterActive() && m.diffCursor == top) {
+		return strings.Join(rows, "\n")
+	}
+	rows[0] = m.stickyHunkRow("@@ " + h.text)
+	return strings.Join(rows, "\n")
+}
+
+// stickyHunkRow renders text as a full-width hunk header row.
+func (m Model) stickyHunkRow(text string) string {
+	w := m.viewport.Width
+	return m.styles.DiffStickyHunk.Width(w).Render(" " + truncateEnd(text, max(w-2, 1)))
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+func TestHunkHeaders(t *testing.T) {
+	t.Parallel()
+	lines := []DiffLine{
+		{Type: LineHunkHeader, Content: "func a()"},
+		{Type: LineAdded, Content: "x"},
+		{Type: LineHunkHeader, Content: gapMarker},
+		{Type: LineRemoved, Content: "y"},
+		{Type: LineHunkHeader, Content: "func b()"},
+	}
+	got := hunkHeaders(lines)
+	if len(got) != 2 || got[0] != (hunkHeader{0, "func a()"}) || got[1] != (hunkHeader{4, "func b()"}) {
+		t.Errorf("hunkHeaders = %+v", got)
+	}
+
+	tests := []struct {
+		top  int
+		want string
+		ok   bool
+	}{
+		{0, "func a()", true},
+		{3, "func a()", true},
+		{4, "func b()", true},
+		{9, "func b()", true},
+	}
+	for _, tt := range tests {
+		h, ok := activeHunk(got, tt.top)
+		if ok != tt.ok || h.text != tt.want {
+			t.Errorf("activeHunk(top=%d) = %q %v, want %q", tt.top, h.text, ok, tt.want)
+		}
+	}
+	if _, ok := activeHunk([]hunkHeader{{line: 5}}, 2); ok {
+		t.Error("no hunk should be active above the first header")
+	}
+}
+
+func TestDiffViewportView_StickyHunk(t *testing.T) {
+	t.Parallel()
+	m := newTestModel(t, []fileItem{{change: git.FileChange{Path: "a.go"}}})
+	m.viewport = viewport.New(40, 3)
+	var rows []string
+	for i := range 10 {
+		rows = append(rows, "row"+string(rune('0'+i)))
+	}
+	m.viewport.SetContent(strings.Join(rows, "\n"))
+	m.diffHunks = []hunkHeader{{line: 0, text: "-1,4 +1,4 func first()"}, {line: 5, text: "-9,4 +9,4 func second()"}}
+
+	if view := m.diffViewportView(); !strings.HasPrefix(view, "row0") {
+		t.Errorf("header in view should not be pinned: %q", view)
+	}
+	m.viewport.SetYOffset(2)
+	view := m.diffViewportView()
+	first, rest, _ := strings.Cut(view, "\n")
+	if !strings.Contains(first, "func first()") || !strings.HasPrefix(rest, "row3") {
+		t.Errorf("scrolled into hunk 1: first=%q rest=%q", first, rest)
+	}
+	m.viewport.SetYOffset(6)
+	if view := m.diffViewportView(); !strings.Contains(view, "func second()") || strings.Contains(view, "func first()") {
+		t.Errorf("scrolled into hunk 2: %q", view)
+	}
+}
@@ -24,8 +24,10 @@ type Styles struct {
 	DiffRemoved         lipgloss.Style
 	DiffAddedBg         lipgloss.Style // bg-only, for padding highlighted lines
 	DiffRemovedBg       lipgloss.Style // bg-only, for padding highlighted lines
+	DiffSelectedBg      lipgloss.Style // bg-only, for lines in the line selection
 	DiffContext         lipgloss.Style
 	DiffHunkHeader      lipgloss.Style
+	DiffStickyHunk      lipgloss.Style // active hunk header pinned atop the diff
 	DiffLineNum         lipgloss.Style
 	DiffLineNumAdded    lipgloss.Style
 	DiffLineNumRemoved  lipgloss.Style
@@ -40,12 +42,55 @@ type Styles struct {
 	// Commit input
 	CommitInput lipgloss.Style
 
+	// CommitBody frames a commit message body above its diff in the log
+	CommitBody lipgloss.Style
+
 	// Accent
 	Accent lipgloss.Style
+
+	// TruncatedBanner flags a diff cut at the line limit
+	TruncatedBanner lipgloss.Style
+
+	// NewFile marks files that appeared in the last refresh
+	NewFile lipgloss.Style
+
+	// StaleFetch highlights an old last-fetch time inside the status bar
+	StaleFetch lipgloss.Style
+
+	// SubjectOverLimit flags a commit subject longer than commit_subject_max
+	SubjectOverLimit lipgloss.Style
+
+	// Log authors, one style per theme.AuthorPalette entry
+	Authors []lipgloss.Style
+
+	// WhitespaceError marks trailing whitespace and a space before a tab in
+	// the indentation of added lines
+	WhitespaceError lipgloss.Style
+
+	// Explanation wraps AI explain output to the diff panel's width
+	Explanation lipgloss.Style
+
+	// PickerRow pads an unselected repo picker row to the card width
+	PickerRow lipgloss.Style
+
+	// HelpBar pads the key hints line to the window width
+	HelpBar lipgloss.Style
+
+	// Gutter picks the diff line-number columns: "both", "old", "new" or
+	// "none" (empty means "both").
+	Gutter string
+
+	// Monochrome is set when colors are disabled; rows then get structural
+	// cues (a ">" cursor marker) instead of relying on highlight colors.
+	Monochrome bool
 }
 
 // NewStyles creates styles from a theme.
 func NewStyles(t theme.Theme) Styles {
+	authors := make([]lipgloss.Style, 0, len(t.AuthorPalette))
+	for _, c := range t.AuthorPalette {
+		authors = append(authors, lipgloss.NewStyle().Foreground(lipgloss.Color(c)))
+	}
 	return Styles{
 		FileItem: lipgloss.NewStyle().
 			Foreground(lipgloss.Color(t.Fg)).
@@ -79,10 +124,16 @@ func NewStyles(t theme.Theme) Styles {
 			Background(lipgloss.Color(t.AddedBg)),
 		DiffRemovedBg: lipgloss.NewStyle().
 			Background(lipgloss.Color(t.RemovedBg)),
+		DiffSelectedBg: lipgloss.NewStyle().
+			Background(lipgloss.Color(t.SelectedBg)),
 		DiffContext: lipgloss.NewStyle().
 			Foreground(lipgloss.Color(t.Fg)),
 		DiffHunkHeader: lipgloss.NewStyle().
 			Foreground(lipgloss.Color(t.HunkFg)),
+		DiffStickyHunk: lipgloss.NewStyle().
+			Foreground(lipgloss.Color(t.HeaderFg)).
+			Background(lipgloss.Color(t.HeaderBg)).
+			Bold(true),
 		DiffLineNum: lipgloss.NewStyle().
 			Foreground(lipgloss.Color(t.LineNumFg)),
 		DiffLineNumAdded: lipgloss.NewStyle().
@@ -112,8 +163,36 @@ func NewStyles(t theme.Theme) Styles {
 
 		CommitInput: lipgloss.NewStyle().
 			Foreground(lipgloss.Color(t.Fg)),
+		CommitBody: lipgloss.NewStyle().
+			Foreground(lipgloss.Color(t.Fg)).
+			BorderStyle(lipgloss.NormalBorder()).
+			BorderLeft(true).
+			BorderForeground(lipgloss.Color(t.AccentFg)).
+			PaddingLeft(1),
 
 		Accent: lipgloss.NewStyle().
 			Foreground(lipgloss.Color(t.AccentFg)),
+		NewFile: lipgloss.NewStyle().
+			Foreground(lipgloss.Color(t.AccentFg)).
+			Bold(true),
+		StaleFetch: lipgloss.NewStyle().
+			Background(lipgloss.Color(t.StatusBarBg)).
+			Foreground(lipgloss.Color(t.ModifiedFg)).
+			Bold(true),
+		SubjectOverLimit: lipgloss.NewStyle().
+			Foreground(lipgloss.Color(t.DeletedFg)).
+			Bold(true),
+		TruncatedBanner: lipgloss.NewStyle().
+			Background(lipgloss.Color(t.ModifiedFg)).
+			Foreground(lipgloss.Color(t.Bg)).
+			Bold(true),
+
+		WhitespaceError: lipgloss.NewStyle().
+			Background(lipgloss.Color(t.DeletedFg)),
+		Explanation: lipgloss.NewStyle(),
+		PickerRow:   lipgloss.NewStyle(),
+		HelpBar:     lipgloss.NewStyle(),
+
+		Authors: authors,
 	}
 }
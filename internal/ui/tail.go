@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newestChange returns the path whose working-tree file was modified last,
+// or "" when none exists on disk (e.g. only deletions).
+func newestChange(files []fileItem, absPath func(string) string) string {
+	var newest string
+	var newestTime time.Time
+	for _, f := range files {
+		info, err := os.Stat(absPath(f.change.Path))
+		if err != nil || !info.ModTime().After(newestTime) {
+			continue
+		}
+		newest, newestTime = f.change.Path, info.ModTime()
+	}
+	return newest
+}
+
+// indexOfPath returns the first file with path p, or -1.
+func indexOfPath(files []fileItem, p string) int {
+	if p == "" {
+		return -1
+	}
+	for i, f := range files {
+		if f.change.Path == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// toggleTail turns tail mode on or off. Turning it on jumps to the newest
+// change right away instead of waiting for the next one.
+func (m Model) toggleTail() (tea.Model, tea.Cmd) {
+	m.tail = !m.tail
+	if !m.tail {
+		m.statusMsg = "tail off"
+		return m, nil
+	}
+	m.statusMsg = "tail on: following the latest change"
+	return m, m.reloadFilesCmd(true)
+}
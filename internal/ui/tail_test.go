@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jansmrcka/differ/internal/git"
+)
+
+func TestNewestChange(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"old.go", "new.go", "mid.go"} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-time.Duration(3-i) * time.Minute)
+		if name == "new.go" {
+			mtime = now
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []fileItem{
+		{change: git.FileChange{Path: "old.go"}},
+		{change: git.FileChange{Path: "gone.go", Status: git.StatusDeleted}},
+		{change: git.FileChange{Path: "new.go"}},
+		{change: git.FileChange{Path: "mid.go"}},
+	}
+	abs := func(p string) string { return filepath.Join(dir, p) }
+	if got := newestChange(files, abs); got != "new.go" {
+		t.Errorf("newestChange = %q, want new.go", got)
+	}
+	if got := newestChange(files[1:2], abs); got != "" {
+		t.Errorf("newestChange of deletions only = %q, want empty", got)
+	}
+}
+
+func TestTailFollowsNewestOnRefresh(t *testing.T) {
+	t.Parallel()
+	files := []fileItem{
+		{change: git.FileChange{Path: "a.go"}},
+		{change: git.FileChange{Path: "b.go"}},
+	}
+	m := newTestModel(t, files)
+	changed := append(files, fileItem{change: git.FileChange{Path: "c.go"}, untracked: true})
+
+	result, _ := m.handleFilesRefreshed(filesRefreshedMsg{files: changed, newest: "b.go"})
+	if rm := result.(Model); rm.cursor != 0 {
+		t.Errorf("tail off: cursor=%d, want 0", rm.cursor)
+	}
+
+	m.tail = true
+	result, _ = m.handleFilesRefreshed(filesRefreshedMsg{files: changed, newest: "b.go"})
+	if rm := result.(Model); rm.cursor != 1 {
+		t.Errorf("tail on: cursor=%d, want 1 (b.go)", rm.cursor)
+	}
+}
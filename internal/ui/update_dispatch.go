@@ -1,6 +1,12 @@
 package ui
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -17,8 +23,21 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleDiffLoaded(msg)
 	case filesRefreshedMsg:
 		return m.handleFilesRefreshed(msg)
+	case autoStagedMsg:
+		return m.handleAutoStaged(msg)
 	case commitDoneMsg:
 		return m.handleCommitDone(msg)
+	case divergenceLoadedMsg:
+		return m.handleDivergenceLoaded(msg)
+	case explainDoneMsg:
+		return m.handleExplainDone(msg)
+	case spinner.TickMsg:
+		if !m.committing && !m.explaining {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
 	case commitMsgGeneratedMsg:
 		return m.handleCommitMsgGenerated(msg)
 	case branchesLoadedMsg:
@@ -34,12 +53,47 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handlePushDone(msg)
 	case pullDoneMsg:
 		return m.handlePullDone(msg)
+	case fetchDoneMsg:
+		return m.handleFetchDone(msg)
+	case externalToolDoneMsg:
+		return m.handleExternalToolDone(msg)
+	case blameLoadedMsg:
+		return m.handleBlameLoaded(msg)
+	case cleanPreviewMsg:
+		return m.handleCleanPreview(msg)
+	case stagePreviewMsg:
+		return m.handleStagePreview(msg)
+	case cleanDoneMsg:
+		return m.handleCleanDone(msg)
+	case urlOpenedMsg:
+		if msg.err != nil {
+			m.statusMsg = "open PR failed: " + msg.err.Error()
+		} else {
+			m.statusMsg = "opened " + msg.url
+		}
+		return m, nil
+	case clipboardDoneMsg:
+		if msg.err != nil {
+			m.statusMsg = "copy failed: " + msg.err.Error()
+		} else {
+			m.statusMsg = msg.label
+		}
+		m.osc52 = msg.osc52
+		return m, clearOSC52Cmd(msg.osc52)
+	case osc52ClearedMsg:
+		if m.osc52 == msg.text {
+			m.osc52 = ""
+		}
+		return m, nil
+	case clipboardPastedMsg:
+		return m.handlePaste(msg), nil
 	case savePrefDoneMsg:
 		if msg.err != nil {
 			m.statusMsg = "config save failed"
 		}
 		return m, nil
 	case tea.KeyMsg:
+		m.newFiles = nil
 		switch m.mode {
 		case modeFileList:
 			return m.updateFileListMode(msg)
@@ -49,6 +103,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateCommitMode(msg)
 		case modeBranchPicker:
 			return m.updateBranchMode(msg)
+		case modeClean:
+			return m.updateCleanMode(msg)
+		case modeSummary:
+			return m.updateSummaryMode(msg)
+		case modeSettings:
+			return m.updateSettingsMode(msg)
+		case modeHookOutput:
+			return m.updateHookOutputMode(msg)
+		case modeExplain:
+			return m.updateExplainMode(msg)
+		case modeDivergence:
+			return m.updateDivergenceMode(msg)
+		case modeActions:
+			return m.updateActionsMode(msg)
+		case modeStagePreview:
+			return m.updateStagePreviewMode(msg)
 		}
 	}
 	return m, nil
@@ -57,61 +127,211 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) handleResize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 	m.width = msg.Width
 	m.height = msg.Height
-	m.viewport = viewport.New(m.diffWidth(), m.contentHeight())
+	m.fileListW = computeFileListWidth(m.cfg.FileListRatio, msg.Width)
 	m.lastDiffContent = ""
 	m.ready = true
+	if m.tooSmall() {
+		// View only shows a warning; the viewport and diff are rebuilt at
+		// the real size once the terminal is large enough again.
+		return m, nil
+	}
+	m.branchFilter.Width = m.fileListWidth() - 8
+	m.viewport = viewport.New(m.diffContentWidth(), m.contentHeight())
+	m = m.fitViewport().clampFileScroll()
 	return m, m.loadDiffCmd(true)
 }
 
 func (m Model) handleDiffLoaded(msg diffLoadedMsg) (tea.Model, tea.Cmd) {
-	if msg.index != m.cursor || msg.content == m.lastDiffContent {
+	// A diff rendered before a resize has the wrong width; the resize
+	// already requested a fresh one.
+	if msg.index != m.cursor || msg.width != m.diffContentWidth() || msg.content == m.lastDiffContent || m.panelOverlay() {
 		return m, nil
 	}
 	m.lastDiffContent = msg.content
+	m.diffKinds = msg.kinds
+	m.diffOldNums = msg.oldNums
+	m.diffNewNums = msg.newNums
+	m.diffRender = msg.render
+	m.diffHunks = msg.hunks
+	changed := msg.hash != m.diffHash
+	m.diffHash = msg.hash
 	m.viewport.SetContent(msg.content)
-	if msg.resetScroll {
+	if msg.resetScroll || (msg.poll && changed) {
 		m.viewport.GotoTop()
+		m.diffCursor = 0
+		m.hunkPicked = false
+		m.selecting = false
+		if m.hunkRestore {
+			return m.restoreHunk()
+		}
 	}
 	return m, nil
 }
 
 func (m Model) handleFilesRefreshed(msg filesRefreshedMsg) (tea.Model, tea.Cmd) {
-	if filesEqual(m.files, msg.files) {
-		return m, m.loadDiffCmd(false)
+	if msg.err != nil {
+		m.statusMsg = "stage failed: " + firstLine(msg.err.Error())
+	}
+	files, hidden := msg.files, []fileItem(nil)
+	if !m.showHidden {
+		files, hidden = partitionHidden(msg.files, m.cfg.HidePatterns)
 	}
-	m.files = msg.files
+	m.hiddenFiles = hidden
+	m.dirty = msg.dirty
+	if !msg.force && filesEqual(m.files, files) {
+		return m, m.pollDiffCmd()
+	}
+	var note string
+	m.newFiles, note = compareFileSets(m.files, files)
+	if m.statusMsg == "" {
+		m.statusMsg = note
+	}
+	m.files = files
 	if m.cursor >= len(m.files) {
 		m.cursor = max(0, len(m.files)-1)
 	}
+	// Staging moves a file between the Staged and Changes groups; keep it
+	// selected instead of whatever slid into its old row.
+	if i := indexOfPath(m.files, msg.follow); i >= 0 {
+		m.cursor = i
+	}
+	// Staging part of a file leaves it on both sides; stay on the side its
+	// hunks are being taken from while it has any left.
+	m.hunkRestore = false
+	if i := indexOfChange(m.files, msg.hunkPath, msg.hunkStaged); i >= 0 {
+		m.cursor = i
+		m.hunkRestore = true
+	}
+	if i := indexOfPath(m.files, msg.newest); m.tail && i >= 0 {
+		m.cursor = i
+	}
 	m.prevCurs = -1
 	m.lastDiffContent = ""
+	m = m.clampFileScroll()
 	if len(m.files) == 0 {
+		m.diffKinds = nil
+		m.diffOldNums = nil
+		m.diffNewNums = nil
+		m.diffRender = nil
+		m.diffHunks = nil
 		m.viewport.SetContent("")
 		return m, nil
 	}
 	return m, m.loadDiffCmd(true)
 }
 
+// compareFileSets finds paths that appeared or disappeared between two file
+// lists. It returns the new paths and a note like "+2 -1 files", or "" when
+// the set of paths is unchanged (e.g. a file was only staged).
+func compareFileSets(before, after []fileItem) (map[string]bool, string) {
+	old := make(map[string]bool, len(before))
+	for _, f := range before {
+		old[f.change.Path] = true
+	}
+	added := make(map[string]bool)
+	cur := make(map[string]bool, len(after))
+	for _, f := range after {
+		cur[f.change.Path] = true
+		if !old[f.change.Path] {
+			added[f.change.Path] = true
+		}
+	}
+	removed := 0
+	for p := range old {
+		if !cur[p] {
+			removed++
+		}
+	}
+	var parts []string
+	if len(added) > 0 {
+		parts = append(parts, fmt.Sprintf("+%d", len(added)))
+	}
+	if removed > 0 {
+		parts = append(parts, fmt.Sprintf("-%d", removed))
+	}
+	if len(parts) == 0 {
+		return nil, ""
+	}
+	noun := "files"
+	if len(added)+removed == 1 {
+		noun = "file"
+	}
+	return added, strings.Join(parts, " ") + " " + noun
+}
+
+// handleAutoStaged applies the refreshed file list and, if staging worked,
+// continues into commit mode.
+func (m Model) handleAutoStaged(msg autoStagedMsg) (tea.Model, tea.Cmd) {
+	next, reload := m.handleFilesRefreshed(filesRefreshedMsg{files: msg.files, force: true, err: msg.err})
+	m = next.(Model)
+	if msg.err != nil {
+		return m, reload
+	}
+	next, commit := m.enterCommitMode()
+	m = next.(Model)
+	if m.mode == modeCommit {
+		m.statusMsg = fmt.Sprintf("auto-staged %d tracked files", msg.count)
+	}
+	return m, tea.Batch(reload, commit)
+}
+
 func (m Model) handleCommitDone(msg commitDoneMsg) (tea.Model, tea.Cmd) {
-	m.mode = modeFileList
+	m.committing = false
 	if msg.err != nil {
-		m.statusMsg = "commit failed: " + msg.err.Error()
-		return m, nil
+		return m.showHookOutput(msg.err.Error()), nil
+	}
+	m.mode = modeFileList
+	m.suggestions = nil
+	m = m.fitViewport()
+	m.statusMsg = strings.TrimSpace("committed " + msg.hash)
+	if n := remainingAfterCommit(m.files); n > 0 {
+		// A subset was committed: start the next round from the top of
+		// what is left.
+		noun := "files"
+		if n == 1 {
+			noun = "file"
+		}
+		m.statusMsg += fmt.Sprintf(" · %d %s remaining", n, noun)
+		m.cursor, m.fileOffset = 0, 0
 	}
-	m.statusMsg = "committed!"
 	m.commitInput.Reset()
 	return m, m.refreshFilesCmd()
 }
 
+// remainingAfterCommit counts the paths still changed once the staged
+// entries are committed: anything with an unstaged or untracked entry.
+func remainingAfterCommit(files []fileItem) int {
+	paths := make(map[string]bool)
+	for _, f := range files {
+		if !f.change.Staged {
+			paths[f.change.Path] = true
+		}
+	}
+	return len(paths)
+}
+
 func (m Model) handleCommitMsgGenerated(msg commitMsgGeneratedMsg) (tea.Model, tea.Cmd) {
+	if errors.Is(msg.err, context.Canceled) {
+		return m, nil // esc already reset the UI
+	}
 	m.generatingMsg = false
+	if errors.Is(msg.err, errAITimeout) {
+		m.statusMsg = msg.err.Error()
+		return m, nil
+	}
 	if msg.err != nil {
 		m.statusMsg = "ai msg failed: " + msg.err.Error()
 		return m, nil
 	}
+	m.statusMsg = ""
+	m.suggestions = nil
+	m.suggestionIdx = 0
+	if len(msg.suggestions) > 1 {
+		m.suggestions = msg.suggestions
+	}
 	m.commitInput.SetValue(msg.message)
 	m.commitInput.CursorEnd()
-	return m, nil
+	return m.fitViewport(), nil
 }
 
 func (m Model) handleBranchesLoaded(msg branchesLoadedMsg) (tea.Model, tea.Cmd) {
@@ -126,6 +346,8 @@ func (m Model) handleBranchesLoaded(msg branchesLoadedMsg) (tea.Model, tea.Cmd)
 	m.mode = modeBranchPicker
 	m.branches = msg.branches
 	m.currentBranch = msg.current
+	m.mergedBranches = msg.merged
+	m.branchDetails = msg.details
 	m.branchCursor = 0
 	m.branchOffset = 0
 	for i, b := range m.branches {
@@ -145,14 +367,26 @@ func (m Model) handleBranchSwitched(msg branchSwitchedMsg) (tea.Model, tea.Cmd)
 	m.filteredBranches = nil
 	m.branchFilter.Reset()
 	m.branchFilter.Blur()
+	m.branchCreating = false
+	m.branchDetaching = false
+	m.branchInput.Reset()
 	if msg.err != nil {
-		m.statusMsg = "switch failed: " + msg.err.Error()
+		m.statusMsg = "switch failed: " + firstLine(msg.err.Error())
+		if msg.stashed {
+			m.statusMsg += " (changes stashed; git stash pop restores them)"
+		}
 		return m, nil
 	}
 	m.statusMsg = "switched to " + m.repo.BranchName()
+	if msg.stashed {
+		m.statusMsg += " (changes stashed; git stash pop restores them)"
+	}
+	if msg.detached {
+		m.statusMsg = "HEAD detached at " + m.repo.BranchName() + " (pick a branch with b to leave it)"
+	}
 	m.prevCurs = -1
-	m.cursor = 0
-	return m, m.refreshFilesCmd()
+	m.cursor, m.fileOffset = 0, 0
+	return m, tea.Batch(m.refreshFilesCmd(), m.windowTitleCmd())
 }
 
 func (m Model) handleBranchCreated(msg branchCreatedMsg) (tea.Model, tea.Cmd) {
@@ -165,6 +399,6 @@ func (m Model) handleBranchCreated(msg branchCreatedMsg) (tea.Model, tea.Cmd) {
 	m.mode = modeFileList
 	m.statusMsg = "created & switched to " + msg.name
 	m.prevCurs = -1
-	m.cursor = 0
-	return m, m.refreshFilesCmd()
+	m.cursor, m.fileOffset = 0, 0
+	return m, tea.Batch(m.refreshFilesCmd(), m.windowTitleCmd())
 }
@@ -0,0 +1,36 @@
+package ui
+
+// Vim-style count prefixes (5j, 5G), shared by list and diff modes. A bare g
+// goes to the top at once, as in the log view, so gg does too.
+
+const maxKeyCount = 9999
+
+// pendingKeys buffers a numeric count between key presses. Any key that
+// doesn't extend it completes (and clears) the sequence.
+type pendingKeys struct {
+	count int
+}
+
+// feed consumes key into the pending state. It returns true when the key
+// only extended a count and needs no further handling.
+func (p pendingKeys) feed(key string) (pendingKeys, bool) {
+	if len(key) == 1 && key[0] >= '0' && key[0] <= '9' && (key != "0" || p.count > 0) {
+		p.count = min(p.count*10+int(key[0]-'0'), maxKeyCount)
+		return p, true
+	}
+	return p, false
+}
+
+// repeat returns the count for motions like j/k, defaulting to 1.
+func (p pendingKeys) repeat() int {
+	return max(p.count, 1)
+}
+
+// jumpTarget returns the index for g/G: the counted line (1-based) clamped
+// to [0, last], or fallback when no count was typed.
+func (p pendingKeys) jumpTarget(last, fallback int) int {
+	if p.count == 0 {
+		return fallback
+	}
+	return min(p.count-1, max(last, 0))
+}
@@ -0,0 +1,191 @@
+package ui
+
+import "strings"
+
+// Word diffs: git diff --word-diff=plain marks changes inside a line with
+// [-removed-] and {+added+} instead of emitting whole -/+ lines.
+
+// WordSpan is a changed run inside a word-diff line: Content[Start:End]
+// was removed (LineRemoved) or added (LineAdded).
+type WordSpan struct {
+	Start, End int
+	Type       DiffLineType
+}
+
+// ParseWordDiff parses git's --word-diff=plain output, keeping at most limit
+// lines (limit <= 0 parses all). A line that only removes or only adds text
+// becomes a removed or added line; a line mixing changes with kept text is
+// a context line with its changes in Words.
+func ParseWordDiff(raw string, limit int) ParsedDiff {
+	if strings.Contains(raw, "Binary files") && strings.Contains(raw, "differ") {
+		return ParsedDiff{Binary: true}
+	}
+
+	var lines []DiffLine
+	oldNum, newNum := 0, 0
+	inHunk := false
+
+	// Word-diff lines have no +/-/space prefix, so an empty line is real
+	// content; only the final newline is dropped.
+	for _, line := range strings.Split(strings.TrimSuffix(raw, "\n"), "\n") {
+		if limit > 0 && len(lines) >= limit {
+			return ParsedDiff{Lines: lines, Truncated: true}
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git"):
+			inHunk = false
+			continue
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+			parseHunkHeader(line, &oldNum, &newNum)
+			lines = append(lines, DiffLine{Type: LineHunkHeader, Content: extractHunkContext(line), OldNum: -1, NewNum: -1})
+			continue
+		case !inHunk:
+			continue // file headers
+		}
+		dl := parseWordLine(line)
+		switch dl.Type {
+		case LineAdded:
+			dl.OldNum, dl.NewNum = -1, newNum
+			newNum++
+		case LineRemoved:
+			dl.OldNum, dl.NewNum = oldNum, -1
+			oldNum++
+		default:
+			dl.OldNum, dl.NewNum = oldNum, newNum
+			oldNum++
+			newNum++
+		}
+		lines = append(lines, dl)
+	}
+	return ParsedDiff{Lines: lines}
+}
+
+// parseWordLine strips the word-diff markers from line, recording each
+// marked run as a WordSpan. An unterminated marker is kept as text.
+func parseWordLine(line string) DiffLine {
+	var b strings.Builder
+	var words []WordSpan
+	kept := false
+	for line != "" {
+		i, typ, closer := nextWordMarker(line)
+		if i < 0 {
+			b.WriteString(line)
+			kept = true
+			break
+		}
+		end := strings.Index(line[i+2:], closer)
+		if end < 0 {
+			b.WriteString(line)
+			kept = true
+			break
+		}
+		if i > 0 {
+			b.WriteString(line[:i])
+			kept = true
+		}
+		start := b.Len()
+		b.WriteString(line[i+2 : i+2+end])
+		words = append(words, WordSpan{Start: start, End: b.Len(), Type: typ})
+		line = line[i+2+end+2:]
+	}
+
+	dl := DiffLine{Type: LineContext, Content: b.String(), Words: words}
+	if kept || len(words) == 0 {
+		return dl
+	}
+	for _, w := range words[1:] {
+		if w.Type != words[0].Type {
+			return dl
+		}
+	}
+	// Every run is the same kind of change: a plain removed or added line.
+	dl.Type = words[0].Type
+	dl.Words = nil
+	return dl
+}
+
+// nextWordMarker finds the first [- or {+ in s, returning its index, the
+// change it opens and the marker that closes it. The index is -1 if none.
+func nextWordMarker(s string) (int, DiffLineType, string) {
+	rm := strings.Index(s, "[-")
+	add := strings.Index(s, "{+")
+	switch {
+	case rm < 0 && add < 0:
+		return -1, LineContext, ""
+	case add < 0 || (rm >= 0 && rm < add):
+		return rm, LineRemoved, "-]"
+	default:
+		return add, LineAdded, "+}"
+	}
+}
+
+// renderWords renders a word-diff line: kept text in the context color,
+// removed and added runs in the diff colors. Monochrome output has no
+// colors to tell them apart, so the runs keep git's markers instead.
+func renderWords(dl DiffLine, styles Styles) string {
+	if styles.Monochrome {
+		return styles.DiffContext.Render(plainWords(dl).Content)
+	}
+	var b strings.Builder
+	pos := 0
+	for _, w := range dl.Words {
+		start, end := min(w.Start, len(dl.Content)), min(w.End, len(dl.Content))
+		b.WriteString(styles.DiffContext.Render(dl.Content[pos:start]))
+		style := styles.DiffAdded
+		if w.Type == LineRemoved {
+			style = styles.DiffRemoved
+		}
+		b.WriteString(style.Render(dl.Content[start:end]))
+		pos = end
+	}
+	b.WriteString(styles.DiffContext.Render(dl.Content[pos:]))
+	return b.String()
+}
+
+// plainWords puts git's markers back around dl's changed runs and clears
+// Words, so the markers count toward the line's width.
+func plainWords(dl DiffLine) DiffLine {
+	if len(dl.Words) == 0 {
+		return dl
+	}
+	var b strings.Builder
+	pos := 0
+	for _, w := range dl.Words {
+		open, closer := "{+", "+}"
+		if w.Type == LineRemoved {
+			open, closer = "[-", "-]"
+		}
+		b.WriteString(dl.Content[pos:w.Start] + open + dl.Content[w.Start:w.End] + closer)
+		pos = w.End
+	}
+	b.WriteString(dl.Content[pos:])
+	dl.Content = b.String()
+	dl.Words = nil
+	return dl
+}
+
+// wordSide is one side of a word-diff line for the split view: the old
+// text (kept and removed runs) on the left, the new text on the right.
+func wordSide(dl DiffLine, left bool) DiffLine {
+	drop := LineRemoved
+	if left {
+		drop = LineAdded
+	}
+	var b strings.Builder
+	var words []WordSpan
+	pos := 0
+	for _, w := range dl.Words {
+		b.WriteString(dl.Content[pos:w.Start])
+		if w.Type != drop {
+			start := b.Len()
+			b.WriteString(dl.Content[w.Start:w.End])
+			words = append(words, WordSpan{Start: start, End: b.Len(), Type: w.Type})
+		}
+		pos = w.End
+	}
+	b.WriteString(dl.Content[pos:])
+	dl.Content = b.String()
+	dl.Words = words
+	return dl
+}
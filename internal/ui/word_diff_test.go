@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWordDiff(t *testing.T) {
+	t.Parallel()
+	raw := "diff --git a/f b/f\n" +
+		"index 991d5c6..1310d48 100644\n" +
+		"--- a/f\n" +
+		"+++ b/f\n" +
+		"@@ -1,6 +1,6 @@ intro\n" +
+		"a\n" +
+		"\n" +
+		"one [-two-]{+2+} three {+four+}\n" +
+		"[-b c-]{+foo+}\n" +
+		"{+bar+}\n" +
+		"[-e-]\n" +
+		"- list [-item\n"
+	got := ParseWordDiff(raw, 0)
+	want := []DiffLine{
+		{Type: LineHunkHeader, Content: "intro", OldNum: -1, NewNum: -1},
+		{Type: LineContext, Content: "a", OldNum: 1, NewNum: 1},
+		{Type: LineContext, Content: "", OldNum: 2, NewNum: 2},
+		{Type: LineContext, Content: "one two2 three four", OldNum: 3, NewNum: 3, Words: []WordSpan{
+			{Start: 4, End: 7, Type: LineRemoved},
+			{Start: 7, End: 8, Type: LineAdded},
+			{Start: 15, End: 19, Type: LineAdded},
+		}},
+		{Type: LineContext, Content: "b cfoo", OldNum: 4, NewNum: 4, Words: []WordSpan{
+			{Start: 0, End: 3, Type: LineRemoved},
+			{Start: 3, End: 6, Type: LineAdded},
+		}},
+		{Type: LineAdded, Content: "bar", OldNum: -1, NewNum: 5},
+		{Type: LineRemoved, Content: "e", OldNum: 5, NewNum: -1},
+		// Text that only looks like a marker stays as written.
+		{Type: LineContext, Content: "- list [-item", OldNum: 6, NewNum: 6},
+	}
+	if !reflect.DeepEqual(got.Lines, want) {
+		t.Errorf("ParseWordDiff:\n got %+v\nwant %+v", got.Lines, want)
+	}
+
+	if got := ParseWordDiff(raw, 3); !got.Truncated || len(got.Lines) != 3 {
+		t.Errorf("limit 3: truncated=%v lines=%d", got.Truncated, len(got.Lines))
+	}
+	if got := ParseWordDiff("Binary files a/x and b/x differ\n", 0); !got.Binary {
+		t.Error("binary word diff should be marked Binary")
+	}
+}
+
+func TestWordSideAndMarkers(t *testing.T) {
+	t.Parallel()
+	dl := parseWordLine("one [-two-]{+2+} three")
+	if got := wordSide(dl, true); got.Content != "one two three" || !reflect.DeepEqual(got.Words, []WordSpan{{4, 7, LineRemoved}}) {
+		t.Errorf("left side = %q %+v", got.Content, got.Words)
+	}
+	if got := wordSide(dl, false); got.Content != "one 2 three" || !reflect.DeepEqual(got.Words, []WordSpan{{4, 5, LineAdded}}) {
+		t.Errorf("right side = %q %+v", got.Content, got.Words)
+	}
+	if got := plainWords(dl); got.Content != "one [-two-]{+2+} three" || got.Words != nil {
+		t.Errorf("plainWords = %q %+v", got.Content, got.Words)
+	}
+}
+
+func TestChangesOnly_KeepsWordDiffLines(t *testing.T) {
+	t.Parallel()
+	parsed := ParseWordDiff("@@ -1,3 +1,3 @@\nkeep\nsay [-hi-]{+hello+}\nkeep\n", 0)
+	out := changesOnly(parsed)
+	if len(out.Lines) != 2 || out.Lines[1].Content != "say hihello" {
+		t.Errorf("changesOnly = %+v, want the hunk and the changed line", out.Lines)
+	}
+}
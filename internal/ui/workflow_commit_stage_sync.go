@@ -1,7 +1,10 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"os/exec"
 	"strings"
 	"time"
@@ -9,6 +12,8 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jansmrcka/differ/internal/config"
+	"github.com/jansmrcka/differ/internal/git"
+	"github.com/jansmrcka/differ/internal/theme"
 )
 
 // Commit, staging, polling, sync, and async command workflows.
@@ -18,50 +23,182 @@ func (m Model) updateCommitMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "esc":
 		m.mode = modeFileList
 		m.commitInput.Reset()
+		m.suggestions = nil
+		m = m.fitViewport()
+		if m.generatingMsg {
+			if m.cancelGenerate != nil {
+				m.cancelGenerate()
+			}
+			m.generatingMsg = false
+			m.statusMsg = "generation cancelled"
+		}
 		return m, nil
 	case "enter":
 		message := m.commitInput.Value()
+		if m.committing {
+			return m, nil
+		}
 		if strings.TrimSpace(message) == "" {
 			m.statusMsg = "empty commit message"
 			return m, nil
 		}
-		return m, m.commitCmd(message)
+		m.committing = true
+		m.statusMsg = "committing..."
+		return m, tea.Batch(m.commitCmd(message), m.spinner.Tick)
+	case "up", "down":
+		if len(m.suggestions) > 0 {
+			return m.pickSuggestion(msg.String() == "down"), nil
+		}
+	case "ctrl+v", "ctrl+y":
+		return m, pasteCmd()
+	case "ctrl+r":
+		if m.generatingMsg {
+			return m, nil
+		}
+		return m.startGenerate()
 	}
 	var cmd tea.Cmd
 	m.commitInput, cmd = m.commitInput.Update(msg)
 	return m, cmd
 }
 
+// handlePaste inserts clipboard text at the commit input's cursor,
+// collapsed to one line.
+func (m Model) handlePaste(msg clipboardPastedMsg) Model {
+	if m.mode != modeCommit {
+		return m
+	}
+	if msg.err != nil {
+		m.statusMsg = "paste failed: " + msg.err.Error()
+		return m
+	}
+	text := []rune(singleLine(msg.text))
+	value := []rune(m.commitInput.Value())
+	pos := min(m.commitInput.Position(), len(value))
+	m.commitInput.SetValue(string(value[:pos]) + string(text) + string(value[pos:]))
+	m.commitInput.SetCursor(pos + len(text))
+	m.statusMsg = "pasted"
+	return m
+}
+
+// pickSuggestion moves through AI suggestions and fills the commit input.
+func (m Model) pickSuggestion(down bool) Model {
+	if down && m.suggestionIdx < len(m.suggestions)-1 {
+		m.suggestionIdx++
+	} else if !down && m.suggestionIdx > 0 {
+		m.suggestionIdx--
+	}
+	m.commitInput.SetValue(m.suggestions[m.suggestionIdx])
+	m.commitInput.CursorEnd()
+	return m
+}
+
 func (m Model) toggleStage() (tea.Model, tea.Cmd) {
-	if m.stagedOnly || m.ref != "" || len(m.files) == 0 {
+	if m.reviewOnly || m.stagedOnly || m.ref != "" || len(m.files) == 0 {
 		return m, nil
 	}
 	f := m.files[m.cursor]
 	repo := m.repo
 	path := f.change.Path
 	return m, func() tea.Msg {
+		var err error
 		if f.change.Staged {
-			_ = repo.UnstageFile(path)
+			err = repo.UnstageFile(path)
 		} else {
-			_ = repo.StageFile(path)
+			err = repo.StageFile(path)
 		}
-		return m.buildRefreshedFiles()
+		msg := m.buildRefreshedFiles()
+		msg.err = err
+		msg.follow = path
+		return msg
 	}
 }
 
+// toggleStageStatus stages every unstaged file sharing the selected file's
+// status, or unstages every such staged file when the selection is staged.
+func (m Model) toggleStageStatus() (tea.Model, tea.Cmd) {
+	if m.reviewOnly || m.stagedOnly || m.ref != "" || len(m.files) == 0 {
+		return m, nil
+	}
+	sel := m.files[m.cursor]
+	var paths []string
+	for _, f := range m.files {
+		if f.change.Staged == sel.change.Staged && f.change.Status == sel.change.Status {
+			paths = append(paths, f.change.Path)
+		}
+	}
+	verb := "staged"
+	if sel.change.Staged {
+		verb = "unstaged"
+	}
+	noun := "files"
+	if len(paths) == 1 {
+		noun = "file"
+	}
+	m.statusMsg = fmt.Sprintf("%s %d %s %s", verb, len(paths), statusName(sel.change.Status), noun)
+	repo := m.repo
+	unstage := sel.change.Staged
+	return m, func() tea.Msg {
+		var err error
+		if unstage {
+			err = repo.UnstageFiles(paths...)
+		} else {
+			err = repo.StageFiles(paths...)
+		}
+		msg := m.buildRefreshedFiles()
+		msg.err = err
+		msg.follow = sel.change.Path
+		return msg
+	}
+}
+
+// statusName is the lower-case word for a file status.
+func statusName(s git.FileStatus) string {
+	switch s {
+	case git.StatusModified:
+		return "modified"
+	case git.StatusAdded:
+		return "added"
+	case git.StatusDeleted:
+		return "deleted"
+	case git.StatusRenamed:
+		return "renamed"
+	case git.StatusCopied:
+		return "copied"
+	case git.StatusUntracked:
+		return "untracked"
+	}
+	return string(s)
+}
+
 func (m Model) stageAll() (tea.Model, tea.Cmd) {
-	if m.stagedOnly || m.ref != "" {
+	if m.reviewOnly || m.stagedOnly || m.ref != "" {
+		return m, nil
+	}
+	repo := m.repo
+	return m, func() tea.Msg {
+		err := repo.StageAll()
+		msg := m.buildRefreshedFiles()
+		msg.err = err
+		return msg
+	}
+}
+
+func (m Model) unstageAll() (tea.Model, tea.Cmd) {
+	if m.reviewOnly || m.stagedOnly || m.ref != "" {
 		return m, nil
 	}
 	repo := m.repo
 	return m, func() tea.Msg {
-		_ = repo.StageAll()
-		return m.buildRefreshedFiles()
+		err := repo.UnstageAll()
+		msg := m.buildRefreshedFiles()
+		msg.err = err
+		return msg
 	}
 }
 
 func (m Model) enterCommitMode() (tea.Model, tea.Cmd) {
-	if m.ref != "" {
+	if m.reviewOnly || m.ref != "" {
 		return m, nil
 	}
 	hasStaged := false
@@ -72,14 +209,59 @@ func (m Model) enterCommitMode() (tea.Model, tea.Cmd) {
 		}
 	}
 	if !hasStaged {
+		if n := m.unstagedTrackedCount(); m.cfg.AutoStageOnCommit && n > 0 {
+			m.statusMsg = "auto-staging..."
+			return m, m.autoStageCmd(n)
+		}
 		m.statusMsg = "no staged files"
 		return m, nil
 	}
 	m.mode = modeCommit
+	m.suggestions = nil
+	m.commitInput.Focus()
+	m, cmd := m.startGenerate()
+	return m, tea.Batch(textinput.Blink, cmd)
+}
+
+// startGenerate runs the commit message command under a context that esc
+// can cancel and that expires after commit_msg_timeout_sec.
+func (m Model) startGenerate() (Model, tea.Cmd) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if secs := m.cfg.CommitMsgTimeoutSec; secs > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(secs)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	m.cancelGenerate = cancel
 	m.generatingMsg = true
 	m.statusMsg = "generating commit message..."
-	m.commitInput.Focus()
-	return m, tea.Batch(textinput.Blink, m.generateCommitMsgCmd())
+	return m, m.generateCommitMsgCmd(ctx, cancel)
+}
+
+// unstagedTrackedCount counts modified tracked files that aren't staged,
+// i.e. what git commit -a would pick up.
+func (m Model) unstagedTrackedCount() int {
+	if m.stagedOnly {
+		return 0
+	}
+	n := 0
+	for _, f := range m.files {
+		if !f.change.Staged && !f.untracked {
+			n++
+		}
+	}
+	return n
+}
+
+func (m Model) autoStageCmd(count int) tea.Cmd {
+	repo := m.repo
+	return func() tea.Msg {
+		err := repo.StageTracked()
+		return autoStagedMsg{files: m.buildRefreshedFiles().files, count: count, err: err}
+	}
 }
 
 func (m Model) fetchUpstreamStatusCmd() tea.Cmd {
@@ -101,9 +283,56 @@ func (m Model) pushSetUpstreamCmd() tea.Cmd {
 	return func() tea.Msg { return pushDoneMsg{err: repo.PushSetUpstream("origin", branch)} }
 }
 
-func (m Model) pullCmd() tea.Cmd {
+// pullCmd pulls, first stashing tracked changes when stash is set.
+func (m Model) pullCmd(stash bool) tea.Cmd {
 	repo := m.repo
-	return func() tea.Msg { return pullDoneMsg{err: repo.Pull()} }
+	return func() tea.Msg {
+		if stash {
+			if err := repo.Stash("differ: before pull"); err != nil {
+				return pullDoneMsg{err: err}
+			}
+		}
+		return pullDoneMsg{err: repo.Pull(), stashed: stash}
+	}
+}
+
+// treeDirty reports whether tracked files have changes, which can make a
+// switch or pull refuse to run. The file list answers it in the working
+// tree view; staged-only and ref views don't show everything, so the file
+// refresh asks git. It is called from View, so it must not run git itself.
+func (m Model) treeDirty() bool {
+	if m.stagedOnly || m.ref != "" {
+		return m.dirty
+	}
+	for _, files := range [][]fileItem{m.files, m.hiddenFiles} {
+		for _, f := range files {
+			if !f.untracked {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m Model) fetchCmd() tea.Cmd {
+	repo := m.repo
+	return func() tea.Msg { return fetchDoneMsg{err: repo.Fetch("origin")} }
+}
+
+// handleFetchDone refreshes ahead/behind after the startup fetch. Failing
+// is expected offline or without an origin, so it only leaves a note.
+func (m Model) handleFetchDone(msg fetchDoneMsg) (tea.Model, tea.Cmd) {
+	m.fetching = false
+	switch {
+	case errors.Is(msg.err, git.ErrNoRemote):
+		m.statusMsg = "no origin remote, skipped fetch"
+		return m, nil
+	case msg.err != nil:
+		m.statusMsg = "fetch skipped: " + firstLine(msg.err.Error())
+		return m, nil
+	}
+	m.statusMsg = "fetched origin"
+	return m, m.fetchUpstreamStatusCmd()
 }
 
 func tickCmd() tea.Cmd {
@@ -111,7 +340,7 @@ func tickCmd() tea.Cmd {
 }
 
 func (m Model) handleTick() (tea.Model, tea.Cmd) {
-	if m.mode == modeCommit || m.mode == modeBranchPicker || m.generatingMsg {
+	if m.mode == modeCommit || m.mode == modeBranchPicker || m.panelOverlay() || m.generatingMsg || m.committing {
 		return m, tickCmd()
 	}
 	return m, tea.Batch(m.refreshFilesCmd(), m.fetchUpstreamStatusCmd(), tickCmd())
@@ -129,9 +358,15 @@ func (m Model) handlePushDone(msg pushDoneMsg) (tea.Model, tea.Cmd) {
 func (m Model) handlePullDone(msg pullDoneMsg) (tea.Model, tea.Cmd) {
 	if msg.err != nil {
 		m.statusMsg = "pull failed: " + msg.err.Error()
+		if msg.stashed {
+			m.statusMsg += " (changes stashed; git stash pop restores them)"
+		}
 		return m, nil
 	}
 	m.statusMsg = "pulled!"
+	if msg.stashed {
+		m.statusMsg = "pulled! (changes stashed; git stash pop restores them)"
+	}
 	return m, tea.Batch(m.refreshFilesCmd(), m.fetchUpstreamStatusCmd())
 }
 
@@ -143,52 +378,280 @@ func (m Model) loadDiffCmd(resetScroll bool) tea.Cmd {
 	f := m.files[idx]
 	repo := m.repo
 	styles := m.styles
+	styles.Gutter = m.cfg.Gutter
+	opts := m.renderOptions()
 	t := m.theme
 	staged := f.change.Staged
 	ref := m.ref
-	diffW := m.diffWidth()
+	diffW := m.diffContentWidth()
 	filename := f.change.Path
-	splitMode := m.splitDiff && diffW >= minSplitWidth
+	fullFile := m.fullFile
+	splitMode := (m.splitDiff || fullFile) && diffW >= minSplitWidth
+	compact := m.cfg.CompactDiff && !fullFile
+	hideContext := m.cfg.ContextOnly && !fullFile
+	hexView := m.hexView
+	hexMax := m.cfg.HexdumpMaxBytes
+	lineLimit := m.cfg.MaxDiffLines
+	if m.uncappedPath == filename {
+		lineLimit = 0
+	}
+	cursor := -1
+	if m.relativeGutterActive() {
+		cursor = m.diffCursor
+		if resetScroll {
+			cursor = 0
+		}
+	}
+	selLo, selHi := -1, -1
+	if m.selecting && !resetScroll {
+		selLo, selHi = m.selRange()
+	}
 	return func() tea.Msg {
 		var content string
+		var kinds []DiffLineType
+		var oldNums, newNums []int
+		var hunks []hunkHeader
+		var render *diffRender
+		var source string // what the diff was rendered from, hashed below
 		if f.untracked {
 			raw, err := repo.ReadFileContent(filename)
+			source = raw
+			analyseLexer(filename, fileHead(raw))
 			if err != nil {
 				content = styles.DiffHunkHeader.Render("Error: " + err.Error())
+			} else if isBinary([]byte(raw[:min(len(raw), binarySniffLen)])) {
+				content = RenderBinaryFile(styles, diffW)
+				if hexView {
+					content = loadHexDiff(repo, f, ref, hexMax, styles, diffW)
+				}
+			} else if compact {
+				content = RenderDiffCompact(newFileDiff(raw), filename, styles, t, diffW)
 			} else if splitMode {
-				content = RenderNewFileSplit(raw, filename, styles, t, diffW)
+				content = RenderNewFileSplit(raw, filename, styles, t, diffW, opts)
 			} else {
 				content = RenderNewFile(raw, filename, styles, t, diffW)
 			}
+		} else if old, ok := deletedContent(repo, f, ref); ok {
+			source = old
+			analyseLexer(filename, fileHead(old))
+			shown, cut := firstLines(old, lineLimit)
+			switch {
+			case compact:
+				content = RenderDiffCompact(deletedFileDiff(shown), filename, styles, t, diffW)
+			case splitMode:
+				content = RenderSplitDiff(deletedFileDiff(shown), filename, styles, t, diffW, opts)
+			default:
+				content = RenderDeletedFile(shown, filename, styles, t, diffW)
+			}
+			if cut {
+				content += RenderTruncationBanner(lineLimit, "press X for full view", styles, diffW)
+			}
 		} else {
-			raw, err := repo.DiffFile(filename, staged, ref)
+			diffFile := repo.DiffFile
+			if fullFile {
+				diffFile = repo.DiffFileFull
+			}
+			raw, err := diffFile(filename, staged, ref)
+			source = raw
 			if err != nil {
 				content = styles.DiffHunkHeader.Render("Error: " + err.Error())
 			} else {
-				parsed := ParseDiff(raw)
-				if splitMode {
-					content = RenderSplitDiff(parsed, filename, styles, t, diffW)
-				} else {
-					content = RenderDiff(parsed, filename, styles, t, diffW)
+				parse := ParseDiffLimit
+				if repo.WordDiff() {
+					parse = ParseWordDiff
+				}
+				parsed := parse(raw, lineLimit)
+				if head := diffHead(parsed); head != "" {
+					analyseLexer(filename, head)
+				} else if !lexerKnown(filename) {
+					// The diff doesn't show the top of the file.
+					if cur, err := repo.ReadFileContent(filename); err == nil {
+						analyseLexer(filename, fileHead(cur))
+					}
+				}
+				if hideContext {
+					parsed = changesOnly(parsed)
+				}
+				switch {
+				case parsed.Binary && hexView:
+					content = loadHexDiff(repo, f, ref, hexMax, styles, diffW)
+				case splitMode:
+					content = RenderSplitDiff(parsed, filename, styles, t, diffW, opts)
+					if parsed.Truncated {
+						content += RenderTruncationBanner(lineLimit, "press X for full view", styles, diffW)
+					}
+					hunks = splitHunkHeaders(parsed)
+				default:
+					render = &diffRender{parsed: parsed, compact: compact, lineLimit: lineLimit}
+					content = render.content(filename, styles, t, diffW, cursor, selLo, selHi, opts)
+					kinds = lineKinds(parsed)
+					oldNums = removedLineNums(parsed)
+					newNums = addedLineNums(parsed)
+					hunks = hunkHeaders(parsed.Lines)
 				}
 			}
 		}
-		return diffLoadedMsg{content: content, index: idx, resetScroll: resetScroll}
+		return diffLoadedMsg{content: content, kinds: kinds, oldNums: oldNums, newNums: newNums, hunks: hunks, render: render, index: idx, width: diffW, resetScroll: resetScroll, hash: contentHash(filename, source)}
 	}
 }
 
+// diffRender is the parse a unified or compact diff was rendered from, kept
+// so moving the line cursor or selection re-renders it without running git.
+type diffRender struct {
+	parsed    ParsedDiff
+	compact   bool
+	lineLimit int // for the truncation banner
+}
+
+// content renders the diff with the line cursor (-1 for none) and the
+// selected rows selLo through selHi (-1 for none).
+func (r *diffRender) content(filename string, styles Styles, t theme.Theme, width, cursor, selLo, selHi int, opts RenderOptions) string {
+	parsed := markSelected(r.parsed, selLo, selHi)
+	var content string
+	if r.compact {
+		content = RenderDiffCompact(parsed, filename, styles, t, width)
+	} else {
+		content = RenderDiffRelative(parsed, filename, styles, t, width, cursor, opts)
+	}
+	if parsed.Truncated {
+		content += RenderTruncationBanner(r.lineLimit, "press X for full view", styles, width)
+	}
+	return content
+}
+
+// renderOptions are the diff renderers' switches from the config.
+func (m Model) renderOptions() RenderOptions {
+	return RenderOptions{WhitespaceErrors: m.cfg.ShowWhitespaceErrors}
+}
+
+// rerenderDiffCmd redraws the shown diff for a moved line cursor or
+// selection from its cached parse, or reloads it when there is none.
+func (m Model) rerenderDiffCmd() tea.Cmd {
+	r := m.diffRender
+	if r == nil || m.cursor >= len(m.files) {
+		return m.loadDiffCmd(false)
+	}
+	styles := m.styles
+	styles.Gutter = m.cfg.Gutter
+	opts := m.renderOptions()
+	t := m.theme
+	filename := m.files[m.cursor].change.Path
+	diffW := m.diffContentWidth()
+	cursor := -1
+	if m.relativeGutterActive() {
+		cursor = m.diffCursor
+	}
+	selLo, selHi := -1, -1
+	if m.selecting {
+		selLo, selHi = m.selRange()
+	}
+	msg := diffLoadedMsg{kinds: m.diffKinds, oldNums: m.diffOldNums, newNums: m.diffNewNums, hunks: m.diffHunks, render: r, index: m.cursor, width: diffW, hash: m.diffHash}
+	return func() tea.Msg {
+		msg.content = r.content(filename, styles, t, diffW, cursor, selLo, selHi, opts)
+		return msg
+	}
+}
+
+// pollDiffCmd reloads the shown diff after a refresh that found the file
+// list unchanged. Scroll is kept unless the diff's content changed.
+func (m Model) pollDiffCmd() tea.Cmd {
+	load := m.loadDiffCmd(false)
+	if load == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		msg := load()
+		if dl, ok := msg.(diffLoadedMsg); ok {
+			dl.poll = true
+			return dl
+		}
+		return msg
+	}
+}
+
+// contentHash fingerprints a diff's source text for the file at path.
+func contentHash(path, source string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write([]byte(source))
+	return h.Sum64()
+}
+
+// deletedContent returns the last version of a deleted text file: ref,
+// HEAD (staged) or the index. ok is false for other files, binaries and
+// refs ShowBlob can't read (ranges), which keep the regular diff.
+func deletedContent(repo *git.Repo, f fileItem, ref string) (string, bool) {
+	if f.untracked || f.change.Status != git.StatusDeleted {
+		return "", false
+	}
+	oldRef := ref
+	if oldRef == "" && f.change.Staged {
+		oldRef = "HEAD"
+	}
+	data, err := repo.ShowBlob(oldRef, f.change.Path)
+	if err != nil || isBinary(data[:min(len(data), binarySniffLen)]) {
+		return "", false
+	}
+	return strings.TrimSuffix(string(data), "\n"), true
+}
+
+// firstLines cuts content to its first limit lines, as ParseDiffLimit cuts
+// a diff; limit <= 0 keeps it all. cut reports whether lines were dropped.
+func firstLines(content string, limit int) (_ string, cut bool) {
+	if limit <= 0 {
+		return content, false
+	}
+	i := 0
+	for n := 0; n < limit; n++ {
+		j := strings.IndexByte(content[i:], '\n')
+		if j < 0 {
+			return content, false
+		}
+		i += j + 1
+	}
+	return content[:i-1], true
+}
+
 func (m Model) refreshFilesCmd() tea.Cmd {
+	return m.reloadFilesCmd(false)
+}
+
+// reloadFilesCmd re-reads the file list; force makes the handler treat it as
+// changed even when it matches the current list.
+func (m Model) reloadFilesCmd(force bool) tea.Cmd {
 	repo := m.repo
 	stagedOnly := m.stagedOnly
 	ref := m.ref
+	tail := m.tail
 	return func() tea.Msg {
 		files, _ := repo.ChangedFiles(stagedOnly, ref)
 		var untracked []string
 		if !stagedOnly && ref == "" {
 			untracked, _ = repo.UntrackedFiles()
 		}
-		return filesRefreshedMsg{files: buildFileItems(repo, files, untracked)}
+		msg := filesRefreshedMsg{files: buildFileItems(repo, files, untracked), force: force}
+		if stagedOnly || ref != "" {
+			msg.dirty = repo.IsDirty()
+		}
+		if tail {
+			msg.newest = newestChange(msg.files, repo.AbsPath)
+		}
+		return msg
+	}
+}
+
+// refresh handles r (normal poll now) and R (force full reload, dropping
+// cached diff content).
+func (m Model) refresh(force bool) (tea.Model, tea.Cmd) {
+	if force {
+		m.lastDiffContent = ""
+		m.prevCurs = -1
+		m.statusMsg = "reloaded"
+	} else {
+		m.statusMsg = "refreshed"
 	}
+	return m, tea.Batch(m.reloadFilesCmd(force), m.fetchUpstreamStatusCmd())
 }
 
 func (m Model) buildRefreshedFiles() filesRefreshedMsg {
@@ -197,7 +660,11 @@ func (m Model) buildRefreshedFiles() filesRefreshedMsg {
 	if !m.stagedOnly && m.ref == "" {
 		untracked, _ = m.repo.UntrackedFiles()
 	}
-	return filesRefreshedMsg{files: buildFileItems(m.repo, files, untracked)}
+	msg := filesRefreshedMsg{files: buildFileItems(m.repo, files, untracked)}
+	if m.stagedOnly || m.ref != "" {
+		msg.dirty = m.repo.IsDirty()
+	}
+	return msg
 }
 
 func (m Model) saveSplitPrefCmd() tea.Cmd {
@@ -211,16 +678,20 @@ func (m Model) saveSplitPrefCmd() tea.Cmd {
 
 func (m Model) commitCmd(message string) tea.Cmd {
 	repo := m.repo
-	return func() tea.Msg { return commitDoneMsg{err: repo.Commit(message)} }
+	return func() tea.Msg {
+		hash, err := repo.Commit(message)
+		return commitDoneMsg{hash: hash, err: err}
+	}
 }
 
 const defaultCommitMsgCmd = "claude -p"
 const defaultCommitMsgPrompt = "Write a concise git commit message (one line, no quotes, use conventional commit prefixes like feat:, fix:, chore:, refactor: etc when appropriate) for this diff:"
 
-func (m Model) generateCommitMsgCmd() tea.Cmd {
+func (m Model) generateCommitMsgCmd(ctx context.Context, cancel context.CancelFunc) tea.Cmd {
 	repo := m.repo
 	cfg := m.cfg
 	return func() tea.Msg {
+		defer cancel()
 		diff, err := repo.StagedDiff()
 		if err != nil {
 			return commitMsgGeneratedMsg{err: fmt.Errorf("git diff: %w", err)}
@@ -228,26 +699,109 @@ func (m Model) generateCommitMsgCmd() tea.Cmd {
 		if strings.TrimSpace(diff) == "" {
 			return commitMsgGeneratedMsg{err: fmt.Errorf("empty staged diff")}
 		}
-		const maxDiff = 8000
-		if len(diff) > maxDiff {
-			diff = diff[:maxDiff] + "\n... (truncated)"
+		cmdStr := orDefault(cfg.CommitMsgCmd, defaultCommitMsgCmd)
+		out, err := runAICmd(ctx, cmdStr, buildCommitMsgPrompt(cfg, diff))
+		if err != nil {
+			return commitMsgGeneratedMsg{err: err}
 		}
-		promptPrefix := defaultCommitMsgPrompt
-		if cfg.CommitMsgPrompt != "" {
-			promptPrefix = cfg.CommitMsgPrompt
+		if cfg.CommitMsgCount <= 1 {
+			return commitMsgGeneratedMsg{message: strings.TrimSpace(out)}
 		}
-		prompt := promptPrefix + "\n\n" + diff
-		cmdStr := defaultCommitMsgCmd
-		if cfg.CommitMsgCmd != "" {
-			cmdStr = cfg.CommitMsgCmd
+		suggestions := parseSuggestions(out, cfg.CommitMsgCount)
+		if len(suggestions) == 0 {
+			return commitMsgGeneratedMsg{err: fmt.Errorf("%s: empty response", strings.Fields(cmdStr)[0])}
 		}
-		parts := strings.Fields(cmdStr)
-		args := append(parts[1:], prompt)
-		cmd := exec.Command(parts[0], args...)
-		out, err := cmd.Output()
-		if err != nil {
-			return commitMsgGeneratedMsg{err: fmt.Errorf("%s: %w", parts[0], err)}
+		return commitMsgGeneratedMsg{message: suggestions[0], suggestions: suggestions}
+	}
+}
+
+// errAITimeout reports that an AI command ran past its deadline.
+var errAITimeout = errors.New("AI generation timed out")
+
+// runAICmd runs an AI command line (e.g. "claude -p") with prompt as its
+// final argument and returns stdout. Ending ctx kills the command's whole
+// process group; a deadline comes back as errAITimeout and a cancel as
+// context.Canceled.
+func runAICmd(ctx context.Context, cmdStr, prompt string) (string, error) {
+	parts := strings.Fields(cmdStr)
+	if len(parts) == 0 {
+		return "", errors.New("no AI command configured")
+	}
+	args := append(parts[1:], prompt)
+	cmd := exec.CommandContext(ctx, parts[0], args...)
+	killGroupOnCancel(cmd)
+	cmd.WaitDelay = time.Second // don't wait on pipes held by killed children
+	out, err := cmd.Output()
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return "", errAITimeout
+		case context.Canceled:
+			return "", context.Canceled
+		}
+		return "", fmt.Errorf("%s: %w", parts[0], err)
+	}
+	return string(out), nil
+}
+
+// maxPromptDiff caps how much diff is sent to an AI command.
+const maxPromptDiff = 8000
+
+// truncateForPrompt cuts diff to maxPromptDiff bytes, marking the cut.
+func truncateForPrompt(diff string) string {
+	if len(diff) > maxPromptDiff {
+		return diff[:maxPromptDiff] + "\n... (truncated)"
+	}
+	return diff
+}
+
+// buildCommitMsgPrompt assembles the AI prompt, truncating large diffs and
+// asking for a numbered list when several suggestions are configured.
+func buildCommitMsgPrompt(cfg config.Config, diff string) string {
+	diff = truncateForPrompt(diff)
+	promptPrefix := defaultCommitMsgPrompt
+	if cfg.CommitMsgPrompt != "" {
+		promptPrefix = cfg.CommitMsgPrompt
+	}
+	if cfg.CommitMsgCount > 1 {
+		promptPrefix += fmt.Sprintf("\nGive %d alternative messages as a numbered list, one per line, nothing else.", cfg.CommitMsgCount)
+	}
+	return promptPrefix + "\n\n" + diff
+}
+
+// parseSuggestions extracts up to n messages from numbered/bulleted AI output.
+// Models may return fewer lines than asked; whatever is usable is kept.
+func parseSuggestions(out string, n int) []string {
+	var suggestions []string
+	for _, line := range strings.Split(out, "\n") {
+		line = trimListMarker(strings.TrimSpace(line))
+		line = strings.Trim(strings.TrimSpace(line), "\"`")
+		if line == "" {
+			continue
+		}
+		suggestions = append(suggestions, line)
+		if len(suggestions) == n {
+			break
 		}
-		return commitMsgGeneratedMsg{message: strings.TrimSpace(string(out))}
 	}
+	return suggestions
+}
+
+// trimListMarker drops a leading "1.", "1)", "-" or "*" list marker. The
+// marker must be followed by a space, so a message like "2fa: add totp"
+// keeps its digits.
+func trimListMarker(line string) string {
+	rest := strings.TrimLeft(line, "0123456789")
+	if len(rest) < len(line) {
+		if rest == "" || (rest[0] != '.' && rest[0] != ')') {
+			return line
+		}
+	} else if rest == "" || (rest[0] != '-' && rest[0] != '*') {
+		return line
+	}
+	rest = rest[1:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return line
+	}
+	return rest
 }